@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"time"
 
 	"github.com/artarts36/lowbot/engine/state"
 	"github.com/artarts36/lowbot/messenger/messengerapi"
@@ -19,10 +20,20 @@ type Action interface {
 	Run(ctx context.Context, req *Request) error
 }
 
+// Router is optionally implemented by Action with transitions, e.g. actions of Actions.
+type Router interface {
+	// Route returns name of state for transition after action run, defined by On or Switch.
+	// Returns empty string, when transition not defined.
+	Route(ctx context.Context, req *Request) (string, error)
+}
+
+var _ Router = &action{}
+
 type action struct {
-	stateName string
-	next      Action
-	action    func(ctx context.Context, req *Request) error
+	stateName   string
+	next        Action
+	action      func(ctx context.Context, req *Request) error
+	transitions transitions
 }
 
 func (a *action) State() string {
@@ -37,8 +48,18 @@ func (a *action) Run(ctx context.Context, req *Request) error {
 	return a.action(ctx, req)
 }
 
+func (a *action) Route(ctx context.Context, req *Request) (string, error) {
+	return a.transitions.route(ctx, req)
+}
+
 // Respond is wrapper for Responder.Respond with skip message.
 func (r *Request) Respond(answer *messengerapi.Answer) error {
 	_, err := r.Responder.Respond(answer)
 	return err
 }
+
+// Schedule is wrapper for State.Schedule: runs action for state {stateName} at time {at} without user input.
+// Action, scheduled by last action of dialog, is discarded, because dialog finished.
+func (r *Request) Schedule(at time.Time, stateName string, data map[string]string) {
+	r.State.Schedule(at, stateName, data)
+}
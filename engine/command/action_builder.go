@@ -25,7 +25,7 @@ func (b *ActionBuilder) do(fn ActionCallback) *ActionBuilder {
 		action:    fn,
 	}
 
-	b.actions.actionsMap[b.stateName] = act
+	b.actions.register(act)
 	b.next = act
 
 	return b
@@ -37,9 +37,44 @@ func (b *ActionBuilder) Then(stateName string, fn ActionCallback) *ActionBuilder
 		action:    fn,
 	}
 
-	b.actions.actionsMap[stateName] = act
+	b.actions.register(act)
 	b.next.next = act
 	b.next = act
 
 	return b
 }
+
+// Branch adds state without action, which only routes message by transitions: On, Switch.
+func (b *ActionBuilder) Branch(stateName string) *ActionBuilder {
+	return b.Then(stateName, passAction)
+}
+
+// On defines transition of last action: when message body equals value, machine forwards to targetState.
+func (b *ActionBuilder) On(value, targetState string) *ActionBuilder {
+	b.next.transitions.on(value, targetState)
+
+	return b
+}
+
+// Switch defines predicate-based transition of last action.
+// fn must return one of targets or empty string for default transition.
+func (b *ActionBuilder) Switch(fn SwitchFunc, targets ...string) *ActionBuilder {
+	b.next.transitions.setSwitch(fn, targets)
+
+	return b
+}
+
+// ForwardsTo declares states, to which last action forwards by State.Forward.
+func (b *ActionBuilder) ForwardsTo(states ...string) *ActionBuilder {
+	b.next.transitions.forwardsTo(states)
+
+	return b
+}
+
+// Calls declares sub-dialog, which last action starts by State.Call(commandName, returnState).
+// Command {commandName} must be registered before current command.
+func (b *ActionBuilder) Calls(commandName, returnState string) *ActionBuilder {
+	b.next.transitions.calledBy(commandName, returnState)
+
+	return b
+}
@@ -2,11 +2,17 @@ package command
 
 import (
 	"context"
+	"slices"
 )
 
 type Actions struct {
 	actions    []*action
 	actionsMap map[string]*action
+
+	// duplicates contains state names, which registered more than once.
+	duplicates []string
+	// reachable contains state names, which declared as reachable from outside of command.
+	reachable []string
 }
 
 type ActionCallback func(ctx context.Context, req *Request) error
@@ -40,11 +46,67 @@ func (a *Actions) Then(
 	}
 
 	a.actions = append(a.actions, act)
-	a.actionsMap[stateName] = act
+	a.register(act)
+
+	return a
+}
+
+// Branch adds state without action, which only routes message by transitions: On, Switch.
+func (a *Actions) Branch(stateName string) *Actions {
+	return a.Then(stateName, passAction)
+}
+
+// On defines transition of last action: when message body equals value, machine forwards to targetState.
+func (a *Actions) On(value, targetState string) *Actions {
+	a.last().transitions.on(value, targetState)
+
+	return a
+}
+
+// Switch defines predicate-based transition of last action.
+// fn must return one of targets or empty string for default transition.
+func (a *Actions) Switch(fn SwitchFunc, targets ...string) *Actions {
+	a.last().transitions.setSwitch(fn, targets)
+
+	return a
+}
+
+// ForwardsTo declares states, to which last action forwards by State.Forward.
+func (a *Actions) ForwardsTo(states ...string) *Actions {
+	a.last().transitions.forwardsTo(states)
+
+	return a
+}
+
+// Calls declares sub-dialog, which last action starts by State.Call(commandName, returnState).
+// Command {commandName} must be registered before current command.
+func (a *Actions) Calls(commandName, returnState string) *Actions {
+	a.last().transitions.calledBy(commandName, returnState)
 
 	return a
 }
 
+// Reachable declares states, which reachable from outside of command, e.g. by CommandButton.
+func (a *Actions) Reachable(states ...string) *Actions {
+	a.reachable = append(a.reachable, states...)
+
+	return a
+}
+
+// CalledCommands returns names of commands, which declared by Calls.
+func (a *Actions) CalledCommands() []string {
+	names := make([]string, 0)
+	for _, stateName := range a.stateNames() {
+		for _, c := range a.actionsMap[stateName].transitions.calls {
+			if !slices.Contains(names, c.commandName) {
+				names = append(names, c.commandName)
+			}
+		}
+	}
+
+	return names
+}
+
 func (a *Actions) Get(stateName string) (Action, bool) {
 	if act, ok := a.actionsMap[stateName]; ok {
 		return act, true
@@ -56,3 +118,23 @@ func (a *Actions) Get(stateName string) (Action, bool) {
 func (a *Actions) First() Action {
 	return a.actions[0]
 }
+
+func (a *Actions) register(act *action) {
+	if _, exists := a.actionsMap[act.stateName]; exists {
+		a.duplicates = append(a.duplicates, act.stateName)
+	}
+
+	a.actionsMap[act.stateName] = act
+}
+
+func (a *Actions) last() *action {
+	if len(a.actions) == 0 {
+		panic("lowbot: transition defined before any action")
+	}
+
+	return a.actions[len(a.actions)-1]
+}
+
+func passAction(context.Context, *Request) error {
+	return nil
+}
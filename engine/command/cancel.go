@@ -0,0 +1,29 @@
+package command
+
+import (
+	"context"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// CanceledCommandKey is key of state data, which contains name of canceled command.
+// Available in actions of DialogCanceler command.
+const CanceledCommandKey = "lowbot.canceled_command"
+
+// DialogCanceler is optional interface of Command, which aborts active dialog of any command.
+// Interrupt of current command is not called.
+type DialogCanceler interface {
+	CancelsDialog() bool
+}
+
+// CancelHandler is optional interface of Command, which cleans up dialog canceled by DialogCanceler.
+type CancelHandler interface {
+	// OnCancel is called before deletion of canceled state.
+	OnCancel(ctx context.Context, req *CancelRequest) error
+}
+
+type CancelRequest struct {
+	Message messengerapi.Message
+	State   *state.State
+}
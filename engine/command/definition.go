@@ -1,9 +1,25 @@
 package command
 
+import "time"
+
 type Definition struct {
 	// This field user for command routing.
 	Name string
 
 	// This field may be used in /start command.
 	Description string
+
+	// IdleTimeout is time of user inactivity, after which dialog expires.
+	// Deadline is kept in schedule store, so dialog expires with delay of schedule checking.
+	// Command may implement TimeoutHandler for notifying user.
+	// Zero value disables timeout.
+	IdleTimeout time.Duration
+
+	// ReplacePrompts enables replacing of last prompt with first answer after button press,
+	// so chat does not contain stale prompts and keyboards. Id of last prompt is stored by LastPromptIDKey.
+	ReplacePrompts bool
 }
+
+// LastPromptIDKey is key of state data, which contains id of last message sent to dialog.
+// Used with Definition.ReplacePrompts.
+const LastPromptIDKey = "lowbot.last_prompt_id"
@@ -1,5 +1,7 @@
 package command
 
+import "github.com/artarts36/lowbot/messenger/messengerapi"
+
 type CodeError interface {
 	Error() string
 	Code() string
@@ -8,6 +10,8 @@ type CodeError interface {
 
 type InvalidArgumentError struct {
 	Text string
+	// Answer is sent to user instead of Text, e.g. for asking again with buttons.
+	Answer *messengerapi.Answer
 }
 
 type PermissionDeniedError struct {
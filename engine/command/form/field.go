@@ -0,0 +1,196 @@
+package form
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// Validator validates parsed value of field.
+// Returned command.InvalidArgumentError text is sent to user with field prompt,
+// other errors abort handling as internal errors and are not shown to user.
+type Validator[V any] func(value V) error
+
+type field[T any] struct {
+	name   string
+	prompt *messengerapi.Answer
+
+	// extract returns raw value from message, which stored in state.
+	extract func(msg messengerapi.Message) (string, error)
+	// check parses and validates raw value.
+	check func(req *command.Request, raw string) error
+	// apply parses raw value, which checked by save action, and sets it to result without validation.
+	apply func(req *command.Request, result *T, raw string) error
+}
+
+// parseFunc parses raw value of field. Request is available for values, which depend on messenger.
+type parseFunc[V any] func(req *command.Request, raw string) (V, error)
+
+func newField[T, V any](
+	name string,
+	prompt *messengerapi.Answer,
+	parse parseFunc[V],
+	set func(result *T, value V),
+	validators []Validator[V],
+) *field[T] {
+	return &field[T]{
+		name:    name,
+		prompt:  prompt,
+		extract: extractBody,
+		check: func(req *command.Request, raw string) error {
+			value, err := parse(req, raw)
+			if err != nil {
+				return err
+			}
+
+			for _, validate := range validators {
+				if err = validate(value); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		apply: func(req *command.Request, result *T, raw string) error {
+			value, err := parse(req, raw)
+			if err != nil {
+				return err
+			}
+
+			set(result, value)
+
+			return nil
+		},
+	}
+}
+
+// plain adapts parser of raw value, which does not depend on request.
+func plain[V any](parse func(raw string) (V, error)) parseFunc[V] {
+	return func(_ *command.Request, raw string) (V, error) {
+		return parse(raw)
+	}
+}
+
+// invalid converts command.InvalidArgumentError to error, which re-asks field with prompt.
+// Other errors are returned as is.
+func (f *field[T]) invalid(err error) error {
+	var invalidErr *command.InvalidArgumentError
+	if !errors.As(err, &invalidErr) {
+		return err
+	}
+
+	answer := *f.prompt
+	answer.Text = fmt.Sprintf("%s\n%s", invalidErr.Text, f.prompt.Text)
+
+	return &command.InvalidArgumentError{
+		Text:   answer.Text,
+		Answer: &answer,
+	}
+}
+
+func newAttachmentField[T any](
+	name string,
+	prompt *messengerapi.Answer,
+	kind messengerapi.AttachmentKind,
+	set func(result *T, value *messengerapi.Attachment),
+	validators []Validator[*messengerapi.Attachment],
+) *field[T] {
+	fld := newField(name, prompt, parseAttachment, set, validators)
+	fld.extract = extractAttachment(kind)
+
+	return fld
+}
+
+func extractBody(msg messengerapi.Message) (string, error) {
+	return msg.GetBody(), nil
+}
+
+func extractAttachment(kind messengerapi.AttachmentKind) func(msg messengerapi.Message) (string, error) {
+	return func(msg messengerapi.Message) (string, error) {
+		attachment := messengerapi.FindAttachment(msg, kind)
+		if attachment == nil {
+			return "", command.NewInvalidArgumentError(fmt.Sprintf("Send %s.", kind))
+		}
+
+		raw, err := json.Marshal(attachment)
+		if err != nil {
+			return "", fmt.Errorf("marshal attachment: %w", err)
+		}
+
+		return string(raw), nil
+	}
+}
+
+// parseAttachment restores attachment from state.
+// Attachment can be downloaded, when responder of messenger implements messengerapi.FileFetcher.
+func parseAttachment(req *command.Request, raw string) (*messengerapi.Attachment, error) {
+	var attachment messengerapi.Attachment
+
+	if err := json.Unmarshal([]byte(raw), &attachment); err != nil {
+		return nil, fmt.Errorf("unmarshal attachment: %w", err)
+	}
+
+	if fetcher, ok := req.Responder.(messengerapi.FileFetcher); ok {
+		attachment.Fetcher = fetcher
+	}
+
+	return &attachment, nil
+}
+
+func parseText(raw string) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", command.NewInvalidArgumentError("Value must not be empty.")
+	}
+
+	return value, nil
+}
+
+func parseEmail(raw string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(raw))
+	if err != nil {
+		return "", command.NewInvalidArgumentError("Invalid email.")
+	}
+
+	return addr.Address, nil
+}
+
+func parseInt(raw string) (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, command.NewInvalidArgumentError("Value must be integer.")
+	}
+
+	return value, nil
+}
+
+func parseEnum(enum messengerapi.Enum) func(raw string) (string, error) {
+	return func(raw string) (string, error) {
+		if slices.ContainsFunc(enum.Values, func(item messengerapi.EnumItem) bool {
+			return item.Value == raw
+		}) {
+			return raw, nil
+		}
+
+		return "", command.NewInvalidArgumentError("Select one of values.")
+	}
+}
+
+func parseDate(layout string) func(raw string) (time.Time, error) {
+	return func(raw string) (time.Time, error) {
+		value, err := time.Parse(layout, strings.TrimSpace(raw))
+		if err != nil {
+			return time.Time{}, command.NewInvalidArgumentError(fmt.Sprintf("Date must be in format %s.", layout))
+		}
+
+		return value, nil
+	}
+}
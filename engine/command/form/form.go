@@ -0,0 +1,166 @@
+package form
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const DefaultDateLayout = "2006-01-02"
+
+// Attachment adds field, which requires attachment of kind, e.g. messengerapi.AttachmentPhoto.
+// Text of message is ignored. Attachment is downloaded lazily by Attachment.Open.
+func (f *Form[T]) Attachment(
+	name, prompt string,
+	kind messengerapi.AttachmentKind,
+	set func(result *T, value *messengerapi.Attachment),
+	validators ...Validator[*messengerapi.Attachment],
+) *Form[T] {
+	return f.add(newAttachmentField(name, &messengerapi.Answer{Text: prompt}, kind, set, validators))
+}
+
+// SubmitFunc receives result, which filled by all form fields.
+type SubmitFunc[T any] func(ctx context.Context, req *command.Request, result *T) error
+
+// Form generates actions, which collect validated user input into T.
+// For each field Form generates pair of actions: prompt and save.
+// When value is invalid, save action re-asks field without advancing state.
+type Form[T any] struct {
+	name   string
+	fields []*field[T]
+	submit SubmitFunc[T]
+}
+
+// New creates form. Name prefixes states and state data of form, so command can contain several forms.
+func New[T any](name string) *Form[T] {
+	return &Form[T]{
+		name:   name,
+		fields: make([]*field[T], 0),
+	}
+}
+
+// Text adds non-empty text field.
+func (f *Form[T]) Text(name, prompt string, set func(result *T, value string), validators ...Validator[string]) *Form[T] {
+	return f.add(newField(name, &messengerapi.Answer{Text: prompt}, plain(parseText), set, validators))
+}
+
+// Email adds email field.
+func (f *Form[T]) Email(name, prompt string, set func(result *T, value string), validators ...Validator[string]) *Form[T] {
+	return f.add(newField(name, &messengerapi.Answer{Text: prompt}, plain(parseEmail), set, validators))
+}
+
+// Int adds integer field.
+func (f *Form[T]) Int(name, prompt string, set func(result *T, value int), validators ...Validator[int]) *Form[T] {
+	return f.add(newField(name, &messengerapi.Answer{Text: prompt}, plain(parseInt), set, validators))
+}
+
+// Enum adds field with value from enum, user selects value by buttons.
+func (f *Form[T]) Enum(
+	name, prompt string,
+	enum messengerapi.Enum,
+	set func(result *T, value string),
+	validators ...Validator[string],
+) *Form[T] {
+	return f.add(newField(name, &messengerapi.Answer{Text: prompt, Enum: enum}, plain(parseEnum(enum)), set, validators))
+}
+
+// Date adds date field in DefaultDateLayout.
+func (f *Form[T]) Date(name, prompt string, set func(result *T, value time.Time), validators ...Validator[time.Time]) *Form[T] {
+	return f.DateWithLayout(name, prompt, DefaultDateLayout, set, validators...)
+}
+
+// DateWithLayout adds date field in layout.
+func (f *Form[T]) DateWithLayout(
+	name, prompt, layout string,
+	set func(result *T, value time.Time),
+	validators ...Validator[time.Time],
+) *Form[T] {
+	return f.add(newField(name, &messengerapi.Answer{Text: prompt}, plain(parseDate(layout)), set, validators))
+}
+
+// Submit sets callback, which receives filled result.
+func (f *Form[T]) Submit(fn SubmitFunc[T]) *Form[T] {
+	f.submit = fn
+
+	return f
+}
+
+// Actions returns actions of form.
+func (f *Form[T]) Actions() *command.Actions {
+	return f.AppendTo(command.NewActions())
+}
+
+// AppendTo appends actions of form to actions.
+func (f *Form[T]) AppendTo(actions *command.Actions) *command.Actions {
+	for _, fld := range f.fields {
+		actions.
+			Then(f.promptState(fld.name), f.promptAction(fld)).
+			Then(f.saveState(fld.name), f.saveAction(fld))
+	}
+
+	return actions.Then(f.submitState(), f.submitAction)
+}
+
+func (f *Form[T]) add(fld *field[T]) *Form[T] {
+	f.fields = append(f.fields, fld)
+
+	return f
+}
+
+func (f *Form[T]) promptAction(fld *field[T]) command.ActionCallback {
+	return func(_ context.Context, req *command.Request) error {
+		return req.Respond(fld.prompt)
+	}
+}
+
+func (f *Form[T]) saveAction(fld *field[T]) command.ActionCallback {
+	return func(_ context.Context, req *command.Request) error {
+		raw, err := fld.extract(req.Message)
+		if err != nil {
+			return fld.invalid(err)
+		}
+
+		if err = fld.check(req, raw); err != nil {
+			return fld.invalid(err)
+		}
+
+		req.State.Set(f.valueKey(fld.name), raw)
+
+		return req.State.Passthrough()
+	}
+}
+
+func (f *Form[T]) submitAction(ctx context.Context, req *command.Request) error {
+	var result T
+
+	for _, fld := range f.fields {
+		if err := fld.apply(req, &result, req.State.Get(f.valueKey(fld.name))); err != nil {
+			return command.NewInternalError(fmt.Errorf("apply field %q: %w", fld.name, err))
+		}
+	}
+
+	if f.submit == nil {
+		return nil
+	}
+
+	return f.submit(ctx, req, &result)
+}
+
+func (f *Form[T]) promptState(fieldName string) string {
+	return fmt.Sprintf("form.%s.%s.prompt", f.name, fieldName)
+}
+
+func (f *Form[T]) saveState(fieldName string) string {
+	return fmt.Sprintf("form.%s.%s.save", f.name, fieldName)
+}
+
+func (f *Form[T]) submitState() string {
+	return fmt.Sprintf("form.%s.submit", f.name)
+}
+
+func (f *Form[T]) valueKey(fieldName string) string {
+	return fmt.Sprintf("form.%s.%s", f.name, fieldName)
+}
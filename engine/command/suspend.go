@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// ResumeMode defines how suspended dialog is resumed, when interrupting command finished.
+type ResumeMode string
+
+const (
+	// ResumeNever discards dialog on interruption.
+	ResumeNever ResumeMode = ""
+	// ResumeAuto resumes suspended dialog immediately.
+	ResumeAuto ResumeMode = "auto"
+	// ResumeOffer sends button, which resumes suspended dialog.
+	ResumeOffer ResumeMode = "offer"
+)
+
+// DialogSuspender is optional interface of Command, which dialog is suspended instead of discarding on interruption.
+// SuspendOnInterrupt is called only when Interrupt allowed.
+type DialogSuspender interface {
+	// SuspendOnInterrupt returns ResumeMode of interrupted dialog. ResumeNever discards dialog.
+	SuspendOnInterrupt(ctx context.Context, req *InterruptRequest) (ResumeMode, error)
+}
+
+// ResumeHandler is optional interface of Command, which handles resuming of suspended dialog,
+// e.g. repeats question of current state.
+type ResumeHandler interface {
+	// OnResume is called after restoring of suspended state.
+	OnResume(ctx context.Context, req *ResumeRequest) error
+}
+
+type ResumeRequest struct {
+	Responder messengerapi.Responder
+	State     *state.State
+}
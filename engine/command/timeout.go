@@ -0,0 +1,19 @@
+package command
+
+import (
+	"context"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// TimeoutHandler is optional interface of Command, which handles dialog expiration by Definition.IdleTimeout.
+type TimeoutHandler interface {
+	// OnTimeout is called before deletion of expired state.
+	OnTimeout(ctx context.Context, req *TimeoutRequest) error
+}
+
+type TimeoutRequest struct {
+	Responder messengerapi.Responder
+	State     *state.State
+}
@@ -0,0 +1,103 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// SwitchFunc returns name of next state.
+// Returns empty string, when default transition must be used.
+type SwitchFunc func(ctx context.Context, req *Request) (string, error)
+
+// transitions of action, which evaluated after action run.
+type transitions struct {
+	values []valueTransition
+
+	switchFn      SwitchFunc
+	switchTargets []string
+
+	// forwards contains declared targets of State.Forward.
+	forwards []string
+	// calls contains declared sub-dialogs of State.Call.
+	calls []call
+}
+
+// call is declared sub-dialog of action: command {commandName} and state {returnState} of dialog after return.
+type call struct {
+	commandName string
+	returnState string
+}
+
+type valueTransition struct {
+	value       string
+	targetState string
+}
+
+func (t *transitions) on(value, targetState string) {
+	t.values = append(t.values, valueTransition{
+		value:       value,
+		targetState: targetState,
+	})
+}
+
+func (t *transitions) setSwitch(fn SwitchFunc, targets []string) {
+	t.switchFn = fn
+	t.switchTargets = targets
+}
+
+func (t *transitions) forwardsTo(states []string) {
+	t.forwards = append(t.forwards, states...)
+}
+
+func (t *transitions) calledBy(commandName, returnState string) {
+	t.calls = append(t.calls, call{
+		commandName: commandName,
+		returnState: returnState,
+	})
+}
+
+func (t *transitions) route(ctx context.Context, req *Request) (string, error) {
+	for _, tr := range t.values {
+		if tr.value == req.Message.GetBody() {
+			return tr.targetState, nil
+		}
+	}
+
+	if t.switchFn == nil {
+		return "", nil
+	}
+
+	target, err := t.switchFn(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if target != "" && !slices.Contains(t.switchTargets, target) {
+		return "", fmt.Errorf("switch returned undeclared state %q", target)
+	}
+
+	return target, nil
+}
+
+// targets returns all states, which can be reached by transitions immediately, without waiting for input.
+func (t *transitions) targets() []string {
+	targets := make([]string, 0, len(t.values)+len(t.switchTargets)+len(t.forwards))
+	for _, tr := range t.values {
+		targets = append(targets, tr.targetState)
+	}
+
+	targets = append(targets, t.switchTargets...)
+
+	return append(targets, t.forwards...)
+}
+
+// returns returns states, which reached after return from sub-dialogs.
+func (t *transitions) returns() []string {
+	states := make([]string, 0, len(t.calls))
+	for _, c := range t.calls {
+		states = append(states, c.returnState)
+	}
+
+	return states
+}
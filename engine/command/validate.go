@@ -0,0 +1,197 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/artarts36/lowbot/engine/state"
+)
+
+var (
+	ErrEmptyCommandName = errors.New("empty command name")
+	ErrNoActions        = errors.New("command has no actions")
+	ErrDuplicateState   = errors.New("duplicate state")
+	ErrReservedState    = errors.New("reserved state name")
+	ErrUnknownState     = errors.New("transition to unknown state")
+	ErrUnknownCommand   = errors.New("call of unknown command")
+	ErrUnreachableState = errors.New("unreachable state")
+	ErrInputlessCycle   = errors.New("cycle without input steps")
+)
+
+// Validate checks command definition and graph of actions.
+// Returns joined errors: ErrEmptyCommandName, ErrNoActions, ErrDuplicateState, ErrReservedState,
+// ErrUnknownState, ErrInputlessCycle.
+func Validate(cmd Command) error {
+	errs := make([]error, 0)
+
+	if cmd.Definition().Name == "" {
+		errs = append(errs, ErrEmptyCommandName)
+	}
+
+	if err := cmd.Actions().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Validate checks graph of actions.
+// Called commands are checked by router on registration, see ErrUnknownCommand.
+func (a *Actions) Validate() error {
+	if len(a.actionsMap) == 0 {
+		return ErrNoActions
+	}
+
+	errs := make([]error, 0)
+
+	for _, stateName := range a.duplicates {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrDuplicateState, stateName))
+	}
+
+	stateNames := a.stateNames()
+
+	for _, stateName := range stateNames {
+		if stateName == "" || stateName == state.Passthrough || stateName == state.Resume || stateName == state.Timeout {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrReservedState, stateName))
+		}
+
+		for _, target := range a.actionsMap[stateName].transitions.targets() {
+			if _, ok := a.actionsMap[target]; !ok {
+				errs = append(errs, fmt.Errorf("%w: from %q to %q", ErrUnknownState, stateName, target))
+			}
+		}
+
+		for _, target := range a.actionsMap[stateName].transitions.returns() {
+			if _, ok := a.actionsMap[target]; !ok {
+				errs = append(errs, fmt.Errorf("%w: return from sub-dialog of %q to %q", ErrUnknownState, stateName, target))
+			}
+		}
+	}
+
+	if cycle := a.findInputlessCycle(stateNames); cycle != nil {
+		errs = append(errs, fmt.Errorf("%w: %q", ErrInputlessCycle, cycle))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Warnings checks command for problems, which do not prevent running of command, see Actions.Warnings.
+func Warnings(cmd Command) []error {
+	return cmd.Actions().Warnings()
+}
+
+// Warnings returns ErrUnreachableState for states, which are not reached by declared transitions.
+// Transitions by On, Switch, ForwardsTo, Calls and Then are used for graph building,
+// so state, which reached only by State.Forward or State.Call, is unreachable until declared
+// by ForwardsTo, Calls or Reachable.
+func (a *Actions) Warnings() []error {
+	warnings := make([]error, 0)
+
+	reachable := a.reachableStates()
+	for _, stateName := range a.stateNames() {
+		if _, ok := reachable[stateName]; !ok {
+			warnings = append(warnings, fmt.Errorf("%w: %q", ErrUnreachableState, stateName))
+		}
+	}
+
+	return warnings
+}
+
+func (a *Actions) stateNames() []string {
+	stateNames := make([]string, 0, len(a.actionsMap))
+	for stateName := range a.actionsMap {
+		stateNames = append(stateNames, stateName)
+	}
+	sort.Strings(stateNames)
+
+	return stateNames
+}
+
+func (a *Actions) reachableStates() map[string]struct{} {
+	queue := make([]string, 0, len(a.actionsMap))
+	if len(a.actions) > 0 {
+		queue = append(queue, a.actions[0].stateName)
+	}
+	queue = append(queue, a.reachable...)
+
+	reachable := map[string]struct{}{}
+	for len(queue) > 0 {
+		stateName := queue[0]
+		queue = queue[1:]
+
+		act, ok := a.actionsMap[stateName]
+		if !ok {
+			continue
+		}
+		if _, visited := reachable[stateName]; visited {
+			continue
+		}
+		reachable[stateName] = struct{}{}
+
+		if act.next != nil {
+			queue = append(queue, act.next.State())
+		}
+		queue = append(queue, act.transitions.targets()...)
+		queue = append(queue, act.transitions.returns()...)
+	}
+
+	return reachable
+}
+
+// findInputlessCycle finds cycle by transitions, which run immediately without waiting for user input.
+// Returns states of cycle or nil.
+func (a *Actions) findInputlessCycle(stateNames []string) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	marks := make(map[string]int, len(stateNames))
+	path := make([]string, 0)
+
+	var visit func(stateName string) []string
+	visit = func(stateName string) []string {
+		act, ok := a.actionsMap[stateName]
+		if !ok {
+			return nil
+		}
+
+		switch marks[stateName] {
+		case visiting:
+			for i, s := range path {
+				if s == stateName {
+					return append(append([]string{}, path[i:]...), stateName)
+				}
+			}
+			return nil
+		case visited:
+			return nil
+		}
+
+		marks[stateName] = visiting
+		path = append(path, stateName)
+
+		for _, target := range act.transitions.targets() {
+			if cycle := visit(target); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		marks[stateName] = visited
+
+		return nil
+	}
+
+	for _, stateName := range stateNames {
+		if marks[stateName] == unvisited {
+			if cycle := visit(stateName); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
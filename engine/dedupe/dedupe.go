@@ -0,0 +1,66 @@
+package dedupe
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/artarts36/lowbot/metrics"
+)
+
+// Deduplicator skips redelivered updates, e.g. webhook updates, which telegram sent again after slow response.
+type Deduplicator struct {
+	store   Store
+	ttl     time.Duration
+	metrics *metrics.Updates
+	logger  logx.Logger
+}
+
+func New(store Store, ttl time.Duration, metrics *metrics.Updates, logger logx.Logger) *Deduplicator {
+	return &Deduplicator{
+		store:   store,
+		ttl:     ttl,
+		metrics: metrics,
+		logger:  logger,
+	}
+}
+
+// Duplicate returns true, when message already received, otherwise marks message as received.
+// Message is not considered duplicate, when store failed, so update is not lost.
+func (d *Deduplicator) Duplicate(ctx context.Context, msg messengerapi.Message) bool {
+	if msg.GetID() == "" {
+		return false
+	}
+
+	seen, err := d.store.Seen(ctx, key(msg), d.ttl)
+	if err != nil {
+		d.logger.ErrorContext(ctx, "[lowbot][dedupe] failed to check message", logx.Err(err))
+
+		return false
+	}
+
+	if seen {
+		d.logger.WarnContext(ctx, "[lowbot][dedupe] duplicate message skipped", slog.String("message.id", msg.GetID()))
+
+		d.metrics.IncDuplicate(messengerapi.MessengerName(msg))
+	}
+
+	return seen
+}
+
+// Release forgets received message, e.g. when handling failed or abandoned, so redelivered message is handled again.
+func (d *Deduplicator) Release(ctx context.Context, msg messengerapi.Message) {
+	if msg.GetID() == "" {
+		return
+	}
+
+	if err := d.store.Release(ctx, key(msg)); err != nil {
+		d.logger.ErrorContext(ctx, "[lowbot][dedupe] failed to release message", logx.Err(err))
+	}
+}
+
+func key(msg messengerapi.Message) string {
+	return msg.GetChatID() + ":" + msg.GetID()
+}
@@ -0,0 +1,59 @@
+package dedupe
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// cleanEvery is count of Seen calls, after which expired keys are removed.
+const cleanEvery = 1000
+
+type memoryStore struct {
+	keys  map[string]time.Time
+	calls int
+	mu    sync.Mutex
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		keys: map[string]time.Time{},
+	}
+}
+
+func (s *memoryStore) Seen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.calls++
+	if s.calls%cleanEvery == 0 {
+		s.clean(now)
+	}
+
+	if expiresAt, ok := s.keys[key]; ok && expiresAt.After(now) {
+		return true, nil
+	}
+
+	s.keys[key] = now.Add(ttl)
+
+	return false, nil
+}
+
+func (s *memoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+
+	return nil
+}
+
+func (s *memoryStore) clean(now time.Time) {
+	for key, expiresAt := range s.keys {
+		if !expiresAt.After(now) {
+			delete(s.keys, key)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package dedupe
+
+import (
+	"context"
+	"time"
+)
+
+// Store keeps keys of handled updates.
+type Store interface {
+	// Seen marks key as seen for ttl. Returns true, when key already seen.
+	Seen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Release removes key, so key is not seen by next Seen call.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,371 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"sync"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	defaultWorkers   = 8
+	defaultQueueSize = 100
+)
+
+var (
+	ErrQueueFull = errors.New("queue is full")
+	// ErrStopped is returned for task, which is not run, because dispatcher stopped.
+	ErrStopped = errors.New("dispatcher stopped")
+	// ErrDeadlock is returned for task, which waiting would deadlock workers, which wait for each other.
+	ErrDeadlock = errors.New("task would deadlock workers")
+	// ErrAbandoned is passed to messengerapi.DoneMessage of message, which is not handled before Wait deadline.
+	ErrAbandoned = errors.New("message abandoned")
+)
+
+// Backpressure defines behaviour when worker queue is full.
+type Backpressure string
+
+const (
+	// BackpressureBlock waits for free place in worker queue.
+	BackpressureBlock Backpressure = "block"
+	// BackpressureDrop drops message.
+	BackpressureDrop Backpressure = "drop"
+)
+
+type Config struct {
+	// Workers is count of goroutines, which handle messages. Default: 8.
+	Workers int
+	// QueueSize is size of queue per worker. Default: 100.
+	QueueSize int
+	// Backpressure defines behaviour when worker queue is full. Default: BackpressureBlock.
+	Backpressure Backpressure
+}
+
+type Handler func(ctx context.Context, msg messengerapi.Message)
+
+// item of worker queue: message or task.
+type item struct {
+	msg  messengerapi.Message
+	task *queuedTask
+}
+
+type queuedTask struct {
+	run  func(ctx context.Context) error
+	done chan error
+}
+
+// workerKey is key of context value, which marks context of worker.
+type workerKey struct{}
+
+type workerMark struct {
+	dispatcher *Dispatcher
+	worker     int
+}
+
+// Dispatcher shards messages by chat id across workers.
+// Messages of one chat are handled strictly in order by one worker,
+// messages of different chats are handled in parallel.
+type Dispatcher struct {
+	cfg     Config
+	handler Handler
+	logger  logx.Logger
+
+	queues []chan item
+	wg     sync.WaitGroup
+	done   chan struct{}
+
+	// sendMu prevents closing of queues during sending.
+	sendMu sync.RWMutex
+	closed bool
+
+	// ctx is passed to handler, ctx canceled when Wait deadline exceeded.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	inFlight  map[int]messengerapi.Message
+	abandoned []messengerapi.Message
+	// waits contains worker, for task of which worker waits.
+	waits map[int]int
+}
+
+func New(cfg Config, handler Handler, logger logx.Logger) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = defaultQueueSize
+	}
+	if cfg.Backpressure == "" {
+		cfg.Backpressure = BackpressureBlock
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	d := &Dispatcher{
+		cfg:      cfg,
+		handler:  handler,
+		logger:   logger,
+		queues:   make([]chan item, cfg.Workers),
+		done:     make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		inFlight: make(map[int]messengerapi.Message, cfg.Workers),
+		waits:    make(map[int]int),
+	}
+
+	for i := range d.queues {
+		d.queues[i] = make(chan item, cfg.QueueSize)
+	}
+
+	return d
+}
+
+// Run starts workers and dispatches messages from ch.
+// Blocks until ch closed and all dispatched messages handled.
+func (d *Dispatcher) Run(ch <-chan messengerapi.Message) {
+	defer close(d.done)
+
+	for i, queue := range d.queues {
+		d.wg.Add(1)
+		go d.work(i, queue)
+	}
+
+	for msg := range ch {
+		ctx := logx.WithChatID(context.Background(), msg.GetChatID())
+
+		if err := d.Dispatch(ctx, msg); err != nil {
+			d.logger.WarnContext(ctx, "[lowbot][dispatcher] message dropped",
+				slog.String("message.id", msg.GetID()),
+				logx.Err(err),
+			)
+
+			reject(msg, err)
+		}
+	}
+
+	d.sendMu.Lock()
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.sendMu.Unlock()
+
+	d.wg.Wait()
+}
+
+// Dispatch sends message to queue of chat worker.
+// Throws ErrQueueFull with BackpressureDrop, ErrStopped.
+func (d *Dispatcher) Dispatch(ctx context.Context, msg messengerapi.Message) error {
+	return d.enqueue(ctx, d.shard(msg.GetChatID()), item{msg: msg})
+}
+
+// Do runs task of chat, e.g. scheduled action, by worker of chat after queued messages of chat
+// and waits for task result.
+// Task is run immediately, when Do called by handler of the same worker.
+// Throws ErrQueueFull with BackpressureDrop, ErrStopped, ErrDeadlock.
+func (d *Dispatcher) Do(ctx context.Context, chatID string, task func(ctx context.Context) error) error {
+	worker := d.shard(chatID)
+
+	mark, fromWorker := ctx.Value(workerKey{}).(workerMark)
+	fromWorker = fromWorker && mark.dispatcher == d
+
+	if fromWorker && mark.worker == worker {
+		return task(ctx)
+	}
+
+	if fromWorker {
+		if err := d.beginWait(mark.worker, worker); err != nil {
+			return err
+		}
+		defer d.endWait(mark.worker)
+	}
+
+	queued := &queuedTask{run: task, done: make(chan error, 1)}
+
+	if err := d.enqueue(ctx, worker, item{task: queued}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-queued.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, worker int, it item) error {
+	d.sendMu.RLock()
+	defer d.sendMu.RUnlock()
+
+	if d.closed {
+		return ErrStopped
+	}
+
+	queue := d.queues[worker]
+
+	if d.cfg.Backpressure == BackpressureDrop {
+		select {
+		case queue <- it:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case queue <- it:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// beginWait marks worker {from} as waiting for worker {to}.
+// Throws ErrDeadlock, when worker {to} waits for worker {from} directly or by other workers.
+func (d *Dispatcher) beginWait(from, to int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for worker, ok := to, true; ok; worker, ok = d.waits[worker] {
+		if worker == from {
+			return ErrDeadlock
+		}
+	}
+
+	d.waits[from] = to
+
+	return nil
+}
+
+func (d *Dispatcher) endWait(from int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.waits, from)
+}
+
+// Wait waits until Run finished: source channel closed and all dispatched messages handled.
+// When ctx done before, Wait cancels context of running handlers and returns abandoned messages:
+// messages in handling and messages in queues.
+func (d *Dispatcher) Wait(ctx context.Context) ([]messengerapi.Message, error) {
+	select {
+	case <-d.done:
+		return nil, nil
+	case <-ctx.Done():
+	}
+
+	d.cancel()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	abandoned := append([]messengerapi.Message{}, d.abandoned...)
+	for _, msg := range d.inFlight {
+		abandoned = append(abandoned, msg)
+	}
+
+	for _, queue := range d.queues {
+		drained := d.drain(queue)
+		for _, msg := range drained {
+			reject(msg, ErrAbandoned)
+		}
+
+		abandoned = append(abandoned, drained...)
+	}
+
+	d.abandoned = abandoned
+
+	return abandoned, ctx.Err()
+}
+
+func (d *Dispatcher) work(worker int, queue chan item) {
+	defer d.wg.Done()
+
+	ctx := context.WithValue(d.ctx, workerKey{}, workerMark{dispatcher: d, worker: worker})
+
+	for it := range queue {
+		if it.task != nil {
+			d.runTask(ctx, it.task)
+			continue
+		}
+
+		if !d.begin(worker, it.msg) {
+			continue
+		}
+
+		d.handler(ctx, it.msg)
+
+		d.mu.Lock()
+		delete(d.inFlight, worker)
+		d.mu.Unlock()
+	}
+}
+
+func (d *Dispatcher) runTask(ctx context.Context, task *queuedTask) {
+	if d.ctx.Err() != nil {
+		task.done <- ErrStopped
+		return
+	}
+
+	task.done <- task.run(ctx)
+}
+
+// begin marks message as in-flight. Returns false, when dispatcher canceled and message abandoned.
+func (d *Dispatcher) begin(worker int, msg messengerapi.Message) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.ctx.Err() != nil {
+		d.abandoned = append(d.abandoned, msg)
+		reject(msg, ErrAbandoned)
+
+		return false
+	}
+
+	d.inFlight[worker] = msg
+
+	return true
+}
+
+// drain removes items of queue: returns messages, stops tasks.
+func (d *Dispatcher) drain(queue chan item) []messengerapi.Message {
+	msgs := make([]messengerapi.Message, 0, len(queue))
+
+	for {
+		select {
+		case it, ok := <-queue:
+			if !ok {
+				return msgs
+			}
+
+			if it.task != nil {
+				it.task.done <- ErrStopped
+				continue
+			}
+
+			msgs = append(msgs, it.msg)
+		default:
+			return msgs
+		}
+	}
+}
+
+// reject notifies sender of message, which is not handled, see messengerapi.DoneMessage.
+func reject(msg messengerapi.Message, err error) {
+	if done, ok := msg.(messengerapi.DoneMessage); ok {
+		done.Done(err)
+	}
+}
+
+func (d *Dispatcher) shard(chatID string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(chatID))
+
+	return int(h.Sum32() % uint32(len(d.queues))) //nolint:gosec // workers count is positive
+}
@@ -0,0 +1,25 @@
+package machine
+
+import (
+	"context"
+)
+
+// ConflictPolicy defines behaviour, when state was changed concurrently (state.ErrStateConflict).
+// Returns true, when message must be handled again with fresh state.
+// attempt starts from 1.
+type ConflictPolicy func(ctx context.Context, req *Request, attempt int) bool
+
+// AbortOnConflict returns error to caller without retrying.
+func AbortOnConflict() ConflictPolicy {
+	return func(context.Context, *Request, int) bool {
+		return false
+	}
+}
+
+// RetryOnConflict handles message again with fresh state up to maxAttempts times.
+// Note, that action will be run again, so actions must be ready for repeated execution.
+func RetryOnConflict(maxAttempts int) ConflictPolicy {
+	return func(_ context.Context, _ *Request, attempt int) bool {
+		return attempt <= maxAttempts
+	}
+}
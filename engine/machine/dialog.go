@@ -18,11 +18,15 @@ import (
 type Dialog struct {
 	State   *state.State
 	Command command.Command
+
+	// Interrupted is true, when stored dialog replaced by dialog of new command.
+	Interrupted bool
 }
 
 type DialogDeterminer struct {
 	router       router.Router
 	stateStorage state.Storage
+	keyStrategy  state.KeyStrategy
 	metrics      *metrics.Command
 	steps        []determineStep
 	logger       logx.Logger
@@ -35,33 +39,43 @@ type determineStep struct {
 }
 
 type determineStepEnv struct {
-	command command.Command
-	state   *state.State
+	command     command.Command
+	state       *state.State
+	interrupted bool
+
+	// key of dialog state in storage.
+	key string
+
+	// forwarded is true, when message handled again for forwarded state.
+	// Message arguments and interruption are ignored, because they already applied.
+	forwarded bool
 }
 
 func newDeterminer(
 	router router.Router,
 	stateStorage state.Storage,
+	keyStrategy state.KeyStrategy,
 	metrics *metrics.Command,
 	logger logx.Logger,
 ) *DialogDeterminer {
 	dd := &DialogDeterminer{
 		router:       router,
 		stateStorage: stateStorage,
+		keyStrategy:  keyStrategy,
 		metrics:      metrics,
 		steps:        []determineStep{},
 		logger:       logger,
 	}
 
 	dd.steps = []determineStep{
-		{
-			name: "get state and command from message arguments (button calls, etc.)",
-			fn:   dd.determineFromMessageArgs,
-		},
 		{
 			name: "get state from storage",
 			fn:   dd.getStateFromStorage,
 		},
+		{
+			name: "get state and command from message arguments (button calls, etc.)",
+			fn:   dd.determineFromMessageArgs,
+		},
 		{
 			name: "if new dialog create new state",
 			fn:   dd.tryCreateNewDialog,
@@ -77,22 +91,35 @@ func newDeterminer(
 
 func (h *DialogDeterminer) Determine(
 	ctx context.Context,
-	message messengerapi.Message,
+	req *Request,
 ) (*Dialog, error) {
 	h.logger.DebugContext(ctx, "[lowbot][machine][determiner] determining dialog")
 
-	env := &determineStepEnv{}
+	env := &determineStepEnv{
+		key:       req.key,
+		forwarded: req.forwarded,
+	}
+	if env.key == "" {
+		env.key = h.key(req.Message)
+	}
+
 	for _, step := range h.steps {
 		h.logger.DebugContext(ctx, "[lowbot][machine][determiner] running step", slog.String("step.name", step.name))
 
-		stop, err := step.fn(ctx, message, env)
+		stop, err := step.fn(ctx, req.Message, env)
 		if err != nil {
 			return nil, err
 		}
 		if stop {
+			// dialog replies to thread of last user message, when runs without user message.
+			if _, internal := req.Message.(*internalMessage); !internal {
+				env.state.SetThreadID(messengerapi.ThreadID(req.Message))
+			}
+
 			return &Dialog{
-				State:   env.state,
-				Command: env.command,
+				State:       env.state,
+				Command:     env.command,
+				Interrupted: env.interrupted,
 			}, nil
 		}
 	}
@@ -106,20 +133,49 @@ func (h *DialogDeterminer) determineFromMessageArgs(
 	env *determineStepEnv,
 ) (bool, error) {
 	args := message.GetArgs()
-	if args == nil {
+	if args == nil || env.forwarded {
 		return false, nil
 	}
 
 	h.logger.DebugContext(ctx, "[lowbot][machine] message has predefined state", slog.Any("args", message.GetArgs()))
 
-	mState := state.NewFullState(message.GetChatID(), args.StateName, args.CommandName, args.Data, time.Now())
 	cmd, err := h.router.Find(args.CommandName)
 	if err != nil {
 		return true, err
 	}
 
+	stored := env.state
+	continued := stored != nil && stored.CommandName() == args.CommandName
+
+	startedAt := time.Now()
+	if continued {
+		startedAt = stored.StartedAt()
+	}
+
+	mState := state.NewFullState(
+		message.GetChatID(),
+		args.StateName,
+		args.CommandName,
+		args.Data,
+		startedAt,
+		time.Now(),
+		h.storedVersion(stored),
+	)
+	mState.SetKey(env.key)
+
+	if continued {
+		// button continues stored dialog: dialog stack and suspended dialogs are kept.
+		mState.SetThreadID(stored.ThreadID())
+		mState.SetParent(stored.Parent())
+
+		if stored.Suspended() != nil {
+			mState.Suspend(stored.Suspended(), stored.ResumeMode())
+		}
+	}
+
 	env.state = mState
 	env.command = cmd
+	env.interrupted = stored != nil && !continued
 
 	return true, nil
 }
@@ -129,7 +185,7 @@ func (h *DialogDeterminer) getStateFromStorage(
 	message messengerapi.Message,
 	env *determineStepEnv,
 ) (bool, error) {
-	dState, err := h.stateStorage.Get(ctx, message.GetChatID())
+	dState, err := h.stateStorage.Get(ctx, env.key)
 	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
 		return true, err
 	}
@@ -168,6 +224,7 @@ func (h *DialogDeterminer) tryCreateNewDialog(
 
 	env.command = cmd
 	env.state = state.NewState(message.GetChatID(), cmd.Definition().Name)
+	env.state.SetKey(env.key)
 
 	return true, nil
 }
@@ -189,13 +246,17 @@ func (h *DialogDeterminer) continueDialog(
 
 	h.logger.DebugContext(ctx, "[machine] command found", logx.CommandName(cmd.Definition().Name))
 
-	if h.detectInterrupt(message, env.state) {
+	if !env.forwarded && h.detectInterrupt(message, env.state) {
 		h.logger.DebugContext(ctx, "[machine] interrupt detected", logx.CommandName(cmd.Definition().Name))
 
+		stored := env.state
+
 		cmd, env.state, err = h.tryInterrupt(ctx, cmd, message, env.state)
 		if err != nil {
 			return true, fmt.Errorf("try interrupt: %w", err)
 		}
+
+		env.interrupted = env.state != stored
 	}
 
 	env.command = cmd
@@ -217,11 +278,17 @@ func (h *DialogDeterminer) tryInterrupt(
 ) (command.Command, *state.State, error) {
 	desiredCommandName := message.ExtractCommandName()
 
-	allow, err := currentCommand.Interrupt(ctx, &command.InterruptRequest{
+	if canceler, findErr := h.router.Find(desiredCommandName); findErr == nil && cancelsDialog(canceler) {
+		return h.cancelDialog(ctx, currentCommand, canceler, message, mState)
+	}
+
+	interruptReq := &command.InterruptRequest{
 		Message:      message,
 		CurrentState: mState.CommandName(),
 		NewCommand:   desiredCommandName,
-	})
+	}
+
+	allow, err := currentCommand.Interrupt(ctx, interruptReq)
 	if err != nil {
 		return nil, nil, fmt.Errorf("defines interruption: %w", err)
 	}
@@ -243,7 +310,93 @@ func (h *DialogDeterminer) tryInterrupt(
 		slog.String("to_command.name", newCommand.Definition().Name),
 	)
 
-	newState := state.NewState(message.GetChatID(), newCommand.Definition().Name)
+	newState := state.NewFullState(
+		message.GetChatID(),
+		"",
+		newCommand.Definition().Name,
+		nil,
+		time.Now(),
+		time.Now(),
+		mState.Version(),
+	)
+	newState.SetKey(mState.Key())
+
+	resumeMode, err := h.suspendMode(ctx, currentCommand, interruptReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("defines suspension: %w", err)
+	}
+
+	if resumeMode != command.ResumeNever {
+		h.logger.DebugContext(ctx, "[handler] suspend interrupted dialog", slog.String("resume_mode", string(resumeMode)))
+
+		newState.Suspend(mState, string(resumeMode))
+	}
 
 	return newCommand, newState, nil
 }
+
+// cancelDialog aborts current dialog by DialogCanceler command, bypassing Interrupt.
+func (h *DialogDeterminer) cancelDialog(
+	ctx context.Context,
+	currentCommand command.Command,
+	canceler command.Command,
+	message messengerapi.Message,
+	mState *state.State,
+) (command.Command, *state.State, error) {
+	h.logger.DebugContext(ctx,
+		"[handler] cancel command",
+		slog.String("from_command.name", currentCommand.Definition().Name),
+		slog.String("to_command.name", canceler.Definition().Name),
+	)
+
+	if handler, ok := currentCommand.(command.CancelHandler); ok {
+		err := handler.OnCancel(ctx, &command.CancelRequest{
+			Message: message,
+			State:   mState,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("handle cancel: %w", err)
+		}
+	}
+
+	if err := h.stateStorage.Delete(ctx, mState); err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return nil, nil, fmt.Errorf("delete canceled state: %w", err)
+	}
+
+	h.metrics.IncInterruption(currentCommand.Definition().Name, mState.Name(), canceler.Definition().Name, true)
+
+	newState := state.NewState(message.GetChatID(), canceler.Definition().Name)
+	newState.SetKey(mState.Key())
+	newState.Set(command.CanceledCommandKey, currentCommand.Definition().Name)
+	// dialog, suspended by canceled dialog, is resumed after cancel command.
+	if mState.Suspended() != nil {
+		newState.Suspend(mState.Suspended(), mState.ResumeMode())
+	}
+
+	return canceler, newState, nil
+}
+
+func cancelsDialog(cmd command.Command) bool {
+	canceler, ok := cmd.(command.DialogCanceler)
+
+	return ok && canceler.CancelsDialog()
+}
+
+// storedVersion returns version of stored state for replacing it with new state.
+func (h *DialogDeterminer) storedVersion(stored *state.State) int64 {
+	if stored == nil {
+		return 0
+	}
+
+	return stored.Version()
+}
+
+// key returns key of dialog state by KeyStrategy.
+func (h *DialogDeterminer) key(message messengerapi.Message) string {
+	senderID := ""
+	if sender := message.GetSender(); sender != nil {
+		senderID = sender.ID
+	}
+
+	return h.keyStrategy.Key(message.GetChatID(), senderID, messengerapi.ThreadID(message))
+}
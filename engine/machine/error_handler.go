@@ -19,9 +19,14 @@ var (
 	invalidArgumentErrHandler ErrorHandler = func(ctx context.Context, req *Request, err error) (bool, error) {
 		validErr := &command.InvalidArgumentError{}
 		if errors.As(err, &validErr) {
-			_, sendErr := req.Responder.Respond(&messengerapi.Answer{
-				Text: validErr.Text,
-			})
+			answer := validErr.Answer
+			if answer == nil {
+				answer = &messengerapi.Answer{
+					Text: validErr.Text,
+				}
+			}
+
+			_, sendErr := req.Responder.Respond(answer)
 			if sendErr != nil {
 				slog.ErrorContext(ctx, "[invalid-argument-handler] failed to respond to invalid argument", slog.Any("err", sendErr))
 			}
@@ -13,14 +13,18 @@ import (
 
 	"github.com/artarts36/lowbot/engine/command"
 	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/schedule"
 	"github.com/artarts36/lowbot/engine/state"
 	"github.com/artarts36/lowbot/messenger/messengerapi"
 )
 
 type Machine struct {
-	router       router.Router
-	stateStorage state.Storage
-	errorHandler ErrorHandler
+	router         router.Router
+	stateStorage   state.Storage
+	schedules      schedule.Store
+	errorHandler   ErrorHandler
+	conflictPolicy ConflictPolicy
+	resumeTexts    ResumeTexts
 
 	commandNotFoundFallback CommandNotFoundFallback
 	metrics                 *metrics.Command
@@ -32,8 +36,12 @@ type Machine struct {
 func New(
 	routes router.Router,
 	stateStorage state.Storage,
+	schedules schedule.Store,
 	errorHandler ErrorHandler,
 	commandNotFoundFallback CommandNotFoundFallback,
+	conflictPolicy ConflictPolicy,
+	resumeTexts ResumeTexts,
+	keyStrategy state.KeyStrategy,
 	metrics *metrics.Group,
 	bus command.Bus,
 	logger logx.Logger,
@@ -41,11 +49,14 @@ func New(
 	return &Machine{
 		router:                  routes,
 		stateStorage:            stateStorage,
+		schedules:               schedules,
 		errorHandler:            errorHandler,
+		conflictPolicy:          conflictPolicy,
+		resumeTexts:             resumeTexts,
 		commandNotFoundFallback: commandNotFoundFallback,
 		metrics:                 metrics.Command(),
 		bus:                     bus,
-		stateDeterminer:         newDeterminer(routes, stateStorage, metrics.Command(), logger),
+		stateDeterminer:         newDeterminer(routes, stateStorage, keyStrategy, metrics.Command(), logger),
 		logger:                  logger,
 	}
 }
@@ -53,6 +64,23 @@ func New(
 type Request struct {
 	Message   messengerapi.Message
 	Responder messengerapi.Responder
+
+	// forwarded is true, when message handled again for forwarded state.
+	forwarded bool
+	// key overrides key of dialog state, which determined by message.
+	key string
+	// answered is set, when bot responded to message. Shared with forwarded requests.
+	answered *bool
+}
+
+func (r *Request) forward() *Request {
+	return &Request{
+		Message:   r.Message,
+		Responder: r.Responder,
+		forwarded: true,
+		key:       r.key,
+		answered:  r.answered,
+	}
 }
 
 func (h *Machine) Handle(ctx context.Context, req *Request) error {
@@ -62,6 +90,20 @@ func (h *Machine) Handle(ctx context.Context, req *Request) error {
 	)
 
 	err := h.handle(ctx, req)
+	for attempt := 1; errors.Is(err, state.ErrStateConflict); attempt++ {
+		retry := h.conflictPolicy(ctx, req, attempt)
+
+		h.metrics.IncStateConflict(retry)
+
+		if !retry {
+			break
+		}
+
+		h.logger.WarnContext(ctx, "[machine] state changed concurrently, handling message again", slog.Int("attempt", attempt))
+
+		err = h.handle(ctx, req)
+	}
+
 	if err != nil {
 		if errors.Is(err, router.ErrCommandNotFound) {
 			h.metrics.IncNotFound()
@@ -76,11 +118,32 @@ func (h *Machine) Handle(ctx context.Context, req *Request) error {
 func (h *Machine) handle(ctx context.Context, req *Request) error {
 	h.logger.DebugContext(ctx, "[machine] handling message")
 
-	dialog, err := h.stateDeterminer.Determine(ctx, req.Message)
+	dialog, err := h.stateDeterminer.Determine(ctx, req)
 	if err != nil {
 		return fmt.Errorf("determine command and state: %w", err)
 	}
 
+	if dialog.State.Version() > 0 && h.timedOut(dialog.Command, dialog.State) {
+		if err = h.expire(ctx, dialog.Command, dialog.State, req.Responder); err != nil {
+			return fmt.Errorf("expire dialog: %w", err)
+		}
+
+		// scheduled action is canceled with expired dialog.
+		if _, internal := req.Message.(*internalMessage); internal {
+			return nil
+		}
+
+		// message is handled as message without dialog: starts new dialog or replied by fallback.
+		return h.handle(ctx, req)
+	}
+
+	if dialog.State.Name() == state.Resume {
+		dialog, err = h.resumeOffered(ctx, req, dialog)
+		if err != nil || dialog == nil {
+			return err
+		}
+	}
+
 	startedAt := time.Now()
 
 	ctx = logx.WithCommandName(ctx, dialog.Command.Definition().Name)
@@ -108,21 +171,37 @@ func (h *Machine) handle(ctx context.Context, req *Request) error {
 		return err
 	}
 
-	err = h.bus.Handle(ctx, &command.Request{
+	cmdReq := &command.Request{
 		Message:   req.Message,
-		Responder: req.Responder,
+		Responder: h.promptResponder(ctx, req, dialog),
 		State:     dialog.State,
-	}, run)
+	}
+
+	err = h.bus.Handle(ctx, cmdReq, run)
 	if err != nil {
 		return err
 	}
 	h.metrics.IncActionHandled(dialog.Command.Definition().Name, act.State(), "OK")
 
+	if !dialog.State.RecentlyTransited() {
+		if err = h.route(ctx, act, cmdReq); err != nil {
+			return fmt.Errorf("route: %w", err)
+		}
+	}
+
+	if call := dialog.State.Called(); call != nil {
+		return h.call(ctx, req, dialog, act, call)
+	}
+
 	if !dialog.State.RecentlyTransited() {
 		nextAct := act.Next()
 		// stop execution, because next action not found.
 		if nextAct == nil {
-			return h.finishState(ctx, act, dialog.State)
+			if dialog.State.Parent() != nil {
+				return h.returnToParent(ctx, req, act, dialog.State)
+			}
+
+			return h.finishState(ctx, req, act, dialog.State)
 		}
 
 		h.logger.InfoContext(
@@ -137,11 +216,27 @@ func (h *Machine) handle(ctx context.Context, req *Request) error {
 
 	h.metrics.IncStateTransition(dialog.Command.Definition().Name, act.State(), dialog.State.Name())
 
+	dialog.State.Touch()
+
 	err = h.stateStorage.Put(ctx, dialog.State)
 	if err != nil {
 		return fmt.Errorf("put state: %w", err)
 	}
 
+	if dialog.Interrupted {
+		if err = h.cancelScheduledActions(ctx, dialog.State); err != nil {
+			return err
+		}
+	}
+
+	if err = h.scheduleActions(ctx, dialog.State); err != nil {
+		return err
+	}
+
+	if err = h.trackTimeout(ctx, dialog.Command, dialog.State); err != nil {
+		return err
+	}
+
 	h.metrics.ObserveActionExecution(dialog.Command.Definition().Name, act.State(), time.Since(startedAt))
 
 	if dialog.State.Forwarded() != nil {
@@ -163,15 +258,112 @@ func (h *Machine) forward(
 		h.logger.DebugContext(ctx, "[lowbot][machine] forwarding", slog.String("to_state", act.State()))
 	}
 
-	return h.handle(ctx, req)
+	return h.handle(ctx, req.forward())
 }
 
-func (h *Machine) finishState(ctx context.Context, act command.Action, mState *state.State) error {
+// call saves current state in dialog stack and immediately runs called command as sub-dialog.
+func (h *Machine) call(
+	ctx context.Context,
+	req *Request,
+	dialog *Dialog,
+	act command.Action,
+	call *state.Call,
+) error {
+	childCmd, err := h.router.Find(call.CommandName())
+	if err != nil {
+		return fmt.Errorf("find called command %q: %w", call.CommandName(), err)
+	}
+
+	h.logger.InfoContext(ctx, "[lowbot][machine] call sub-dialog",
+		slog.String("from_state", act.State()),
+		slog.String("return_state", call.ReturnState()),
+		slog.String("to_command.name", call.CommandName()),
+	)
+
+	dialog.State.Transit(call.ReturnState())
+	h.metrics.IncStateTransition(dialog.Command.Definition().Name, act.State(), call.ReturnState())
+
+	child := state.NewChildState(dialog.State, childCmd.Definition().Name)
+
+	if err = h.stateStorage.Put(ctx, child); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	if err = h.scheduleActions(ctx, dialog.State); err != nil {
+		return err
+	}
+
+	if err = h.trackTimeout(ctx, childCmd, child); err != nil {
+		return err
+	}
+
+	return h.handle(ctx, req.forward())
+}
+
+// returnToParent finishes sub-dialog and immediately runs return state of parent dialog with data of sub-dialog.
+// Message of sub-dialog is not passed to parent dialog: return state runs without user input.
+func (h *Machine) returnToParent(
+	ctx context.Context,
+	req *Request,
+	act command.Action,
+	child *state.State,
+) error {
+	h.logger.InfoContext(ctx, "[lowbot][machine] sub-dialog finished", slog.String("state.name", act.State()))
+
+	h.metrics.IncFinished(child.CommandName())
+	h.metrics.ObserveExecution(child.CommandName(), child.Duration())
+
+	h.discardScheduledActions(ctx, child)
+
+	parent := child.Return()
+
+	parentCmd, err := h.router.Find(parent.CommandName())
+	if err != nil {
+		return fmt.Errorf("find parent command %q: %w", parent.CommandName(), err)
+	}
+
+	postponed, err := takePostponed(parent)
+	if err != nil {
+		return err
+	}
+
+	if err = h.stateStorage.Put(ctx, parent); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	if err = h.trackTimeout(ctx, parentCmd, parent); err != nil {
+		return err
+	}
+
+	if err = h.resumePostponed(ctx, postponed); err != nil {
+		return err
+	}
+
+	return h.handle(ctx, &Request{
+		Message:   &internalMessage{id: "return:" + req.Message.GetID(), chatID: parent.ChatID()},
+		Responder: req.Responder,
+		forwarded: true,
+		key:       parent.Key(),
+		answered:  req.answered,
+	})
+}
+
+func (h *Machine) finishState(ctx context.Context, req *Request, act command.Action, mState *state.State) error {
 	h.logger.InfoContext(ctx, "[lowbot][machine] next state not found", slog.String("state.name", act.State()))
 
 	h.metrics.IncFinished(mState.CommandName())
 	h.metrics.ObserveExecution(mState.CommandName(), mState.Duration())
 
+	h.discardScheduledActions(ctx, mState)
+
+	if err := h.cancelScheduledActions(ctx, mState); err != nil {
+		return err
+	}
+
+	if mState.Suspended() != nil {
+		return h.resume(ctx, req, mState)
+	}
+
 	err := h.stateStorage.Delete(ctx, mState)
 	if err != nil {
 		if errors.Is(err, state.ErrStateNotFound) {
@@ -183,6 +375,40 @@ func (h *Machine) finishState(ctx context.Context, act command.Action, mState *s
 	return nil
 }
 
+// discardScheduledActions logs actions, scheduled by last action of finished dialog: they are not saved.
+func (h *Machine) discardScheduledActions(ctx context.Context, st *state.State) {
+	for _, scheduled := range st.ScheduledActions() {
+		h.logger.WarnContext(ctx, "[lowbot][machine] scheduled action discarded, dialog finished",
+			logx.StateName(scheduled.StateName()),
+			slog.Time("job.at", scheduled.At()),
+		)
+	}
+}
+
+// route forwards state by action transitions.
+func (h *Machine) route(ctx context.Context, act command.Action, req *command.Request) error {
+	router, ok := act.(command.Router)
+	if !ok {
+		return nil
+	}
+
+	target, err := router.Route(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if target != "" {
+		h.logger.DebugContext(ctx, "[lowbot][machine] route",
+			slog.String("from_state", act.State()),
+			slog.String("to_state", target),
+		)
+
+		req.State.Forward(target)
+	}
+
+	return nil
+}
+
 func (h *Machine) findAction(state *state.State, cmd command.Command) (command.Action, error) {
 	acts := cmd.Actions()
 
@@ -192,7 +418,7 @@ func (h *Machine) findAction(state *state.State, cmd command.Command) (command.A
 
 	st, exists := acts.Get(state.Name())
 	if !exists {
-		return nil, fmt.Errorf("action not found")
+		return nil, fmt.Errorf("action for state %q not found in command %q", state.Name(), cmd.Definition().Name)
 	}
 
 	return st, nil
@@ -0,0 +1,38 @@
+package machine
+
+import "github.com/artarts36/lowbot/messenger/messengerapi"
+
+// internalMessage is message without user input, which runs dialog by engine,
+// e.g. scheduled action or proactively started dialog.
+type internalMessage struct {
+	id     string
+	chatID string
+}
+
+func (m *internalMessage) GetID() string {
+	return m.id
+}
+
+func (m *internalMessage) GetChatID() string {
+	return m.chatID
+}
+
+func (m *internalMessage) GetBody() string {
+	return ""
+}
+
+func (m *internalMessage) GetSender() *messengerapi.Sender {
+	return &messengerapi.Sender{}
+}
+
+func (m *internalMessage) ExtractCommandName() string {
+	return ""
+}
+
+func (m *internalMessage) GetArgs() *messengerapi.Args {
+	return nil
+}
+
+func (m *internalMessage) GetAttachments() []*messengerapi.Attachment {
+	return nil
+}
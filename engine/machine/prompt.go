@@ -0,0 +1,93 @@
+package machine
+
+import (
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// promptResponder replaces last prompt of dialog with first answer after button press,
+// see command.Definition ReplacePrompts. First answer can be sent by action of forwarded state, e.g. after branch.
+type promptResponder struct {
+	messengerapi.Responder
+
+	state    *state.State
+	replace  bool
+	answered *bool
+
+	// ctx of handling message, used for logs.
+	ctx    context.Context
+	logger logx.Logger
+}
+
+func (h *Machine) promptResponder(ctx context.Context, req *Request, dialog *Dialog) messengerapi.Responder {
+	if !dialog.Command.Definition().ReplacePrompts {
+		return req.Responder
+	}
+
+	if req.answered == nil {
+		req.answered = new(bool)
+	}
+
+	callback, ok := req.Message.(messengerapi.CallbackMessage)
+
+	return &promptResponder{
+		Responder: req.Responder,
+		state:     dialog.State,
+		replace:   ok && callback.IsCallback(),
+		answered:  req.answered,
+		ctx:       ctx,
+		logger:    h.logger,
+	}
+}
+
+func (r *promptResponder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
+	lastPromptID := r.state.Get(command.LastPromptIDKey)
+	replace := r.replace && !*r.answered
+
+	*r.answered = true
+
+	// reply keyboard can not be set to edited message.
+	if replace && lastPromptID != "" && len(answer.Menu) == 0 {
+		msg, err := r.Responder.Edit(lastPromptID, answer)
+		if err == nil {
+			return r.remember(msg), nil
+		}
+
+		r.logger.WarnContext(r.ctx, "[lowbot][machine] failed to replace prompt, sending new message",
+			slog.String("prompt.id", lastPromptID),
+			logx.Err(err),
+		)
+	}
+
+	msg, err := r.Responder.Respond(answer)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.remember(msg), nil
+}
+
+func (r *promptResponder) remember(msg messengerapi.Message) messengerapi.Message {
+	if msg != nil && msg.GetID() != "" {
+		r.state.Set(command.LastPromptIDKey, msg.GetID())
+	}
+
+	return msg
+}
+
+// Fetch downloads file by responder of messenger, so attachments of dialog with replaced prompts can be opened.
+// Throws messengerapi.ErrFetcherMissing, when responder of messenger does not implement messengerapi.FileFetcher.
+func (r *promptResponder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	fetcher, ok := r.Responder.(messengerapi.FileFetcher)
+	if !ok {
+		return nil, messengerapi.ErrFetcherMissing
+	}
+
+	return fetcher.Fetch(ctx, fileID)
+}
@@ -0,0 +1,262 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+)
+
+const (
+	// scheduleLease is time, after which undelivered job is delivered again.
+	scheduleLease = time.Minute
+	scheduleBatch = 100
+
+	// postponedJobsKey is key of parent state data, which stores jobs postponed until sub-dialog finished.
+	postponedJobsKey = "lowbot.postponed_jobs"
+)
+
+// scheduleActions saves actions, scheduled by state.State Schedule.
+func (h *Machine) scheduleActions(ctx context.Context, st *state.State) error {
+	for _, scheduled := range st.ScheduledActions() {
+		job := &schedule.Job{
+			ID:              uuid.NewString(),
+			StateKey:        st.Key(),
+			ChatID:          st.ChatID(),
+			ThreadID:        st.ThreadID(),
+			CommandName:     st.CommandName(),
+			DialogStartedAt: st.StartedAt(),
+			StateName:       scheduled.StateName(),
+			Data:            scheduled.Data(),
+			At:              scheduled.At(),
+		}
+
+		if err := h.schedules.Add(ctx, job); err != nil {
+			return fmt.Errorf("add scheduled action %q: %w", scheduled.StateName(), err)
+		}
+
+		h.logger.DebugContext(ctx, "[lowbot][machine] action scheduled",
+			slog.String("job.id", job.ID),
+			slog.String("job.state_name", job.StateName),
+			slog.Time("job.at", job.At),
+		)
+	}
+
+	return nil
+}
+
+func (h *Machine) cancelScheduledActions(ctx context.Context, st *state.State) error {
+	if err := h.schedules.Cancel(ctx, st.Key()); err != nil {
+		return fmt.Errorf("cancel scheduled actions: %w", err)
+	}
+
+	return nil
+}
+
+// Executor runs task of chat, e.g. dispatcher.Dispatcher Do, which runs task in order with messages of chat.
+type Executor func(ctx context.Context, chatID string, task func(ctx context.Context) error) error
+
+// WatchSchedule periodically runs scheduled actions and expires idle dialogs, see RunScheduled.
+// Blocks until ctx done.
+func (h *Machine) WatchSchedule(
+	ctx context.Context,
+	interval time.Duration,
+	createResponder ResponderFactory,
+	run Executor,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.RunScheduled(ctx, createResponder, run)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunScheduled runs due scheduled actions and expires idle dialogs by run, waits until all jobs done.
+// Jobs run one by one, when run is nil.
+func (h *Machine) RunScheduled(ctx context.Context, createResponder ResponderFactory, run Executor) {
+	jobs, err := h.schedules.Due(ctx, time.Now(), scheduleLease, scheduleBatch)
+	if err != nil {
+		h.logger.ErrorContext(ctx, "[lowbot][machine] failed to get scheduled actions", logx.Err(err))
+		return
+	}
+
+	if run == nil {
+		for _, job := range jobs {
+			h.runJob(ctx, job, createResponder, func(ctx context.Context, _ string, task func(ctx context.Context) error) error {
+				return task(ctx)
+			})
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			h.runJob(ctx, job, createResponder, run)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func (h *Machine) runJob(ctx context.Context, job *schedule.Job, createResponder ResponderFactory, run Executor) {
+	ctx = logx.WithCommandName(logx.WithChatID(ctx, job.ChatID), job.CommandName)
+
+	err := run(ctx, job.ChatID, func(ctx context.Context) error {
+		return h.deliver(ctx, job, createResponder)
+	})
+	if err != nil {
+		h.logger.ErrorContext(ctx, "[lowbot][machine] failed to run scheduled action",
+			slog.String("job.id", job.ID),
+			logx.Err(err),
+		)
+	}
+}
+
+// deliver runs scheduled action. Job is acknowledged only after successful handling,
+// otherwise job is delivered again after lease.
+func (h *Machine) deliver(ctx context.Context, job *schedule.Job, createResponder ResponderFactory) error {
+	st, err := h.stateStorage.Get(ctx, job.StateKey)
+	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return fmt.Errorf("get state: %w", err)
+	}
+
+	// Timeout of parent dialog is not postponed, because it tracked again after return from sub-dialog.
+	if st != nil && !jobOf(st, job) && job.StateName != state.Timeout {
+		if caller := callerOf(st, job); caller != nil {
+			return h.postpone(ctx, job, st, caller)
+		}
+	}
+
+	if st == nil || !jobOf(st, job) {
+		h.logger.InfoContext(ctx, "[lowbot][machine] scheduled action canceled, dialog finished",
+			slog.String("job.id", job.ID),
+		)
+
+		return h.schedules.Ack(ctx, job)
+	}
+
+	if job.StateName == state.Timeout {
+		return h.deliverTimeout(ctx, job, st, createResponder)
+	}
+
+	h.logger.InfoContext(ctx, "[lowbot][machine] run scheduled action",
+		slog.String("job.id", job.ID),
+		logx.StateName(job.StateName),
+	)
+
+	for k, v := range job.Data {
+		st.Set(k, v)
+	}
+
+	st.Transit(job.StateName)
+	st.Touch()
+
+	if err = h.stateStorage.Put(ctx, st); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	err = h.Handle(ctx, &Request{
+		Message:   &internalMessage{id: "schedule:" + job.ID, chatID: job.ChatID},
+		Responder: createResponder(job.ChatID, job.ThreadID),
+		forwarded: true,
+		key:       job.StateKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.schedules.Ack(ctx, job)
+}
+
+// jobOf returns true, when job scheduled by dialog of state.
+func jobOf(st *state.State, job *schedule.Job) bool {
+	return st.CommandName() == job.CommandName && st.StartedAt().Equal(job.DialogStartedAt)
+}
+
+// callerOf returns parent dialog, which scheduled job and waits for sub-dialog of state, or nil.
+func callerOf(st *state.State, job *schedule.Job) *state.State {
+	for parent := st.Parent(); parent != nil; parent = parent.Parent() {
+		if jobOf(parent, job) {
+			return parent
+		}
+	}
+
+	return nil
+}
+
+// postpone parks job in state of caller dialog until sub-dialog finished, see resumePostponed.
+// Job is removed from schedule, so it is not delivered again while sub-dialog runs.
+func (h *Machine) postpone(ctx context.Context, job *schedule.Job, st, caller *state.State) error {
+	jobs, err := state.GetJSON[[]*schedule.Job](caller, postponedJobsKey)
+	if err != nil && !errors.Is(err, state.ErrDataKeyNotFound) {
+		return fmt.Errorf("get postponed jobs: %w", err)
+	}
+
+	if !slices.ContainsFunc(jobs, func(postponed *schedule.Job) bool { return postponed.ID == job.ID }) {
+		jobs = append(jobs, job)
+	}
+
+	if err = caller.SetJSON(postponedJobsKey, jobs); err != nil {
+		return err
+	}
+
+	if err = h.stateStorage.Put(ctx, st); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	h.logger.DebugContext(ctx, "[lowbot][machine] scheduled action postponed until sub-dialog finished",
+		slog.String("job.id", job.ID),
+	)
+
+	return h.schedules.Ack(ctx, job)
+}
+
+// takePostponed removes jobs, which postponed until sub-dialog finished, from state of dialog.
+func takePostponed(st *state.State) ([]*schedule.Job, error) {
+	jobs, err := state.GetJSON[[]*schedule.Job](st, postponedJobsKey)
+	if errors.Is(err, state.ErrDataKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get postponed jobs: %w", err)
+	}
+
+	st.Unset(postponedJobsKey)
+
+	return jobs, nil
+}
+
+// resumePostponed schedules postponed jobs again. Jobs are due immediately.
+func (h *Machine) resumePostponed(ctx context.Context, jobs []*schedule.Job) error {
+	for _, job := range jobs {
+		job.At = time.Now()
+
+		if err := h.schedules.Add(ctx, job); err != nil {
+			return fmt.Errorf("resume postponed action %q: %w", job.StateName, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+)
+
+// ErrSenderRequired is returned by StartDialog, when dialogs are keyed by state.KeyByChatSender and sender is empty.
+var ErrSenderRequired = errors.New("sender required for dialog keyed by chat sender")
+
+// DialogRef identifies dialog, which started by StartDialog.
+type DialogRef struct {
+	ChatID string
+	// SenderID is member of chat, whose dialog is started. Required for state.KeyByChatSender.
+	SenderID string
+	// ThreadID is thread (topic) of chat, to which dialog replies. Identifies dialog for state.KeyByChatThread.
+	ThreadID string
+}
+
+// StartDialog starts dialog of command {commandName} without user input: runs first action of command.
+// Active dialog with the same key is replaced, see state.KeyStrategy.
+// Throws ErrSenderRequired, when dialogs keyed by state.KeyByChatSender and ref has no sender.
+func (h *Machine) StartDialog(
+	ctx context.Context,
+	ref DialogRef,
+	commandName string,
+	data map[string]string,
+	createResponder ResponderFactory,
+) error {
+	ctx = logx.WithCommandName(logx.WithChatID(ctx, ref.ChatID), commandName)
+
+	if h.stateDeterminer.keyStrategy == state.KeyByChatSender && ref.SenderID == "" {
+		return ErrSenderRequired
+	}
+
+	cmd, err := h.router.Find(commandName)
+	if err != nil {
+		return fmt.Errorf("find command: %w", err)
+	}
+
+	st := state.NewState(ref.ChatID, cmd.Definition().Name)
+	st.SetKey(h.stateDeterminer.keyStrategy.Key(ref.ChatID, ref.SenderID, ref.ThreadID))
+	st.SetThreadID(ref.ThreadID)
+
+	for k, v := range data {
+		st.Set(k, v)
+	}
+
+	stored, err := h.stateStorage.Get(ctx, st.Key())
+	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return fmt.Errorf("get state: %w", err)
+	}
+
+	if stored != nil {
+		h.logger.InfoContext(ctx, "[lowbot][machine] replace active dialog",
+			slog.String("from_command.name", stored.CommandName()),
+		)
+
+		st.SetVersion(stored.Version())
+
+		if err = h.cancelScheduledActions(ctx, stored); err != nil {
+			return err
+		}
+	}
+
+	if err = h.stateStorage.Put(ctx, st); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	if err = h.trackTimeout(ctx, cmd, st); err != nil {
+		return err
+	}
+
+	h.logger.InfoContext(ctx, "[lowbot][machine] start dialog")
+
+	return h.Handle(ctx, &Request{
+		Message:   &internalMessage{id: "start:" + uuid.NewString(), chatID: ref.ChatID},
+		Responder: createResponder(ref.ChatID, ref.ThreadID),
+		forwarded: true,
+		key:       st.Key(),
+	})
+}
@@ -0,0 +1,263 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// suspendMode returns ResumeMode of interrupted command by command.DialogSuspender.
+func (h *DialogDeterminer) suspendMode(
+	ctx context.Context,
+	cmd command.Command,
+	req *command.InterruptRequest,
+) (command.ResumeMode, error) {
+	suspender, ok := cmd.(command.DialogSuspender)
+	if !ok {
+		return command.ResumeNever, nil
+	}
+
+	return suspender.SuspendOnInterrupt(ctx, req)
+}
+
+// resume restores dialog, which suspended by finished dialog.
+func (h *Machine) resume(ctx context.Context, req *Request, finished *state.State) error {
+	suspended := finished.Suspended()
+
+	cmd, err := h.router.Find(suspended.CommandName())
+	if err != nil {
+		return fmt.Errorf("find suspended command %q: %w", suspended.CommandName(), err)
+	}
+
+	if command.ResumeMode(finished.ResumeMode()) == command.ResumeOffer {
+		err = h.stateStorage.Delete(ctx, finished)
+		if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+			return fmt.Errorf("delete state: %w", err)
+		}
+
+		return h.offerResume(ctx, req.Responder, finished.Key(), suspended)
+	}
+
+	suspended.SetVersion(finished.Version())
+
+	return h.restore(ctx, req.Responder, cmd, suspended)
+}
+
+// ResumeTexts are texts of resuming suspended dialog. Texts with %s are formatted with command name.
+type ResumeTexts struct {
+	// Offer is text of message with resume button, e.g. "Command /%s is not finished.".
+	Offer string
+	// Continue is title of resume button.
+	Continue string
+	// Resumed is text of message, when dialog resumed and command does not implement command.ResumeHandler.
+	Resumed string
+	// Expired is text of message, when resume button is outdated: already pressed or replaced by newer offer.
+	Expired string
+}
+
+func DefaultResumeTexts() ResumeTexts {
+	return ResumeTexts{
+		Offer:    "Command /%s is not finished.",
+		Continue: "Continue",
+		Resumed:  "Command /%s resumed.",
+		Expired:  "Command /%s can not be resumed.",
+	}
+}
+
+// resumeOfferKey returns key of offered dialog in state.Storage.
+// Dialog key has single offer: new offer replaces previous one.
+func resumeOfferKey(dialogKey string) string {
+	return state.Resume + ":" + dialogKey
+}
+
+// resumeOffered restores dialog, which offered by resume button. Offer is used once.
+// Current dialog is replaced only when command.Command Interrupt allowed, otherwise
+// current dialog is returned for handling of message.
+func (h *Machine) resumeOffered(ctx context.Context, req *Request, dialog *Dialog) (*Dialog, error) {
+	offer, err := h.stateStorage.Get(ctx, resumeOfferKey(dialog.State.Key()))
+	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return nil, fmt.Errorf("get resume offer: %w", err)
+	}
+
+	id := dialog.State.Get(state.ResumeIDKey)
+	if offer == nil || id == "" || offer.Get(state.ResumeIDKey) != id || offer.CommandName() != dialog.State.CommandName() {
+		h.logger.InfoContext(ctx, "[lowbot][machine] resume offer expired", logx.CommandName(dialog.State.CommandName()))
+
+		_, err = req.Responder.Respond(&messengerapi.Answer{
+			Text: fmt.Sprintf(h.resumeTexts.Expired, dialog.State.CommandName()),
+		})
+
+		return nil, err
+	}
+
+	current, err := h.stateStorage.Get(ctx, dialog.State.Key())
+	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return nil, fmt.Errorf("get state: %w", err)
+	}
+
+	if current != nil {
+		currentCmd, allow, interruptErr := h.interruptByResume(ctx, req, current, offer)
+		if interruptErr != nil {
+			return nil, interruptErr
+		}
+
+		if !allow {
+			return &Dialog{State: current, Command: currentCmd}, nil
+		}
+
+		if err = h.cancelScheduledActions(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+
+	// offer is deleted with version check, so concurrent press is rejected by state.ErrStateConflict.
+	if err = h.stateStorage.Delete(ctx, offer); err != nil {
+		return nil, fmt.Errorf("delete resume offer: %w", err)
+	}
+
+	data := make(map[string]string, len(offer.All()))
+	for k, v := range offer.All() {
+		data[k] = v
+	}
+	delete(data, state.ResumeIDKey)
+
+	now := time.Now()
+
+	st := state.NewFullState(
+		offer.ChatID(),
+		offer.Name(),
+		offer.CommandName(),
+		data,
+		now,
+		now,
+		h.stateDeterminer.storedVersion(current),
+	)
+	st.SetKey(dialog.State.Key())
+
+	return nil, h.restore(ctx, req.Responder, dialog.Command, st)
+}
+
+// interruptByResume asks command of current dialog, whether current dialog can be replaced by offered dialog.
+func (h *Machine) interruptByResume(
+	ctx context.Context,
+	req *Request,
+	current *state.State,
+	offer *state.State,
+) (command.Command, bool, error) {
+	cmd, err := h.router.Find(current.CommandName())
+	if err != nil {
+		return nil, false, fmt.Errorf("find command: %w", err)
+	}
+
+	allow, err := cmd.Interrupt(ctx, &command.InterruptRequest{
+		Message:      req.Message,
+		CurrentState: current.CommandName(),
+		NewCommand:   offer.CommandName(),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("defines interruption: %w", err)
+	}
+
+	h.metrics.IncInterruption(current.CommandName(), current.Name(), offer.CommandName(), allow)
+
+	return cmd, allow, nil
+}
+
+// offerResume saves suspended dialog as offer and sends button, which resumes it.
+// Button contains only random id of offer, so outdated or forged button does not restore dialog.
+// Offered dialog is restored without own suspended dialogs and sub-dialog stack.
+func (h *Machine) offerResume(
+	ctx context.Context,
+	responder messengerapi.Responder,
+	dialogKey string,
+	suspended *state.State,
+) error {
+	h.logger.InfoContext(ctx, "[lowbot][machine] offer to resume dialog",
+		logx.CommandName(suspended.CommandName()),
+		logx.StateName(suspended.Name()),
+	)
+
+	id := uuid.NewString()
+
+	data := make(map[string]string, len(suspended.All())+1)
+	for k, v := range suspended.All() {
+		data[k] = v
+	}
+	data[state.ResumeIDKey] = id
+
+	now := time.Now()
+
+	offer := state.NewFullState(suspended.ChatID(), suspended.Name(), suspended.CommandName(), data, now, now, 0)
+	offer.SetKey(resumeOfferKey(dialogKey))
+
+	previous, err := h.stateStorage.Get(ctx, offer.Key())
+	if err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return fmt.Errorf("get resume offer: %w", err)
+	}
+	offer.SetVersion(h.stateDeterminer.storedVersion(previous))
+
+	if err = h.stateStorage.Put(ctx, offer); err != nil {
+		return fmt.Errorf("put resume offer: %w", err)
+	}
+
+	_, err = responder.Respond(&messengerapi.Answer{
+		Text: fmt.Sprintf(h.resumeTexts.Offer, suspended.CommandName()),
+		Buttons: []messengerapi.Button{
+			&messengerapi.CommandButton{
+				Title: h.resumeTexts.Continue,
+				Args: messengerapi.Args{
+					CommandName: suspended.CommandName(),
+					StateName:   state.Resume,
+					Data: map[string]string{
+						state.ResumeIDKey: id,
+					},
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+func (h *Machine) restore(
+	ctx context.Context,
+	responder messengerapi.Responder,
+	cmd command.Command,
+	st *state.State,
+) error {
+	h.logger.InfoContext(ctx, "[lowbot][machine] resume dialog",
+		logx.CommandName(st.CommandName()),
+		logx.StateName(st.Name()),
+	)
+
+	st.Touch()
+
+	if err := h.stateStorage.Put(ctx, st); err != nil {
+		return fmt.Errorf("put state: %w", err)
+	}
+
+	if err := h.trackTimeout(ctx, cmd, st); err != nil {
+		return err
+	}
+
+	if handler, ok := cmd.(command.ResumeHandler); ok {
+		return handler.OnResume(ctx, &command.ResumeRequest{
+			Responder: responder,
+			State:     st,
+		})
+	}
+
+	_, err := responder.Respond(&messengerapi.Answer{
+		Text: fmt.Sprintf(h.resumeTexts.Resumed, st.CommandName()),
+	})
+
+	return err
+}
@@ -0,0 +1,115 @@
+package machine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// ResponderFactory creates Responder of chat. Responder of chat is created, when threadID is empty,
+// see messengerapi.CreateThreadResponder.
+type ResponderFactory func(chatID, threadID string) messengerapi.Responder
+
+// trackTimeout schedules expiration of dialog by command.Definition IdleTimeout.
+// Deadline is stored as job of schedule.Store, so dialog expires after restart and on any application replica.
+// Job of dialog is replaced on each dialog update.
+func (h *Machine) trackTimeout(ctx context.Context, cmd command.Command, st *state.State) error {
+	timeout := cmd.Definition().IdleTimeout
+	if timeout <= 0 {
+		return nil
+	}
+
+	job := &schedule.Job{
+		ID:              timeoutJobID(st),
+		StateKey:        st.Key(),
+		ChatID:          st.ChatID(),
+		CommandName:     st.CommandName(),
+		DialogStartedAt: st.StartedAt(),
+		StateName:       state.Timeout,
+		At:              st.UpdatedAt().Add(timeout),
+	}
+
+	if err := h.schedules.Add(ctx, job); err != nil {
+		return fmt.Errorf("schedule dialog timeout: %w", err)
+	}
+
+	return nil
+}
+
+// timeoutJobID returns id of job, which expires dialog. Id is same for all updates of dialog.
+func timeoutJobID(st *state.State) string {
+	return "timeout:" + st.Key() + ":" + strconv.FormatInt(st.StartedAt().UnixNano(), 10)
+}
+
+// deliverTimeout expires idle dialog of job. Job of continued dialog is rescheduled by last dialog update.
+func (h *Machine) deliverTimeout(
+	ctx context.Context,
+	job *schedule.Job,
+	st *state.State,
+	createResponder ResponderFactory,
+) error {
+	cmd, err := h.router.Find(st.CommandName())
+	if err != nil {
+		return fmt.Errorf("find command: %w", err)
+	}
+
+	if cmd.Definition().IdleTimeout <= 0 {
+		// timeout disabled after job was scheduled.
+		return h.schedules.Ack(ctx, job)
+	}
+
+	if !h.timedOut(cmd, st) {
+		// dialog was continued, e.g. on another application replica.
+		return h.trackTimeout(ctx, cmd, st)
+	}
+
+	return h.expire(ctx, cmd, st, createResponder(st.ChatID(), st.ThreadID()))
+}
+
+func (h *Machine) timedOut(cmd command.Command, st *state.State) bool {
+	timeout := cmd.Definition().IdleTimeout
+
+	return timeout > 0 && time.Since(st.UpdatedAt()) > timeout
+}
+
+// expire runs command.TimeoutHandler, deletes state and scheduled actions of dialog.
+func (h *Machine) expire(
+	ctx context.Context,
+	cmd command.Command,
+	st *state.State,
+	responder messengerapi.Responder,
+) error {
+	ctx = logx.WithCommandName(ctx, cmd.Definition().Name)
+
+	h.logger.InfoContext(ctx, "[lowbot][machine] dialog expired", logx.StateName(st.Name()))
+
+	if handler, ok := cmd.(command.TimeoutHandler); ok {
+		err := handler.OnTimeout(ctx, &command.TimeoutRequest{
+			Responder: responder,
+			State:     st,
+		})
+		if err != nil {
+			h.logger.ErrorContext(ctx, "[lowbot][machine] failed to handle timeout", logx.Err(err))
+		}
+	}
+
+	h.metrics.IncTimeout(cmd.Definition().Name, st.Name())
+
+	if err := h.cancelScheduledActions(ctx, st); err != nil {
+		return err
+	}
+
+	if err := h.stateStorage.Delete(ctx, st); err != nil && !errors.Is(err, state.ErrStateNotFound) {
+		return fmt.Errorf("delete state: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type CancelCommand struct {
+	command.AlwaysInterruptCommand
+
+	name string
+}
+
+var _ command.DialogCanceler = &CancelCommand{}
+
+// NewCancelCommand creates command, which aborts active dialog of any command.
+func NewCancelCommand(name string) command.Command {
+	return &CancelCommand{
+		name: name,
+	}
+}
+
+func (c *CancelCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        c.name,
+		Description: "Cancel current command",
+	}
+}
+
+func (c *CancelCommand) CancelsDialog() bool {
+	return true
+}
+
+func (c *CancelCommand) Actions() *command.Actions {
+	return command.NewActions().Then(
+		"cancel",
+		func(_ context.Context, req *command.Request) error {
+			text := "No active command to cancel."
+			if canceled := req.State.Get(command.CanceledCommandKey); canceled != "" {
+				text = fmt.Sprintf("Command /%s canceled.", canceled)
+			}
+
+			_, err := req.Responder.Respond(&messengerapi.Answer{
+				Text: text,
+			})
+			return err
+		},
+	)
+}
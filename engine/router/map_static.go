@@ -1,6 +1,10 @@
 package router
 
-import "github.com/artarts36/lowbot/engine/command"
+import (
+	"fmt"
+
+	"github.com/artarts36/lowbot/engine/command"
+)
 
 type MapStaticRouter struct {
 	commands map[string]command.Command
@@ -20,6 +24,16 @@ func (r *MapStaticRouter) Add(cmd command.Command) error {
 		return ErrCommandAlreadyExists
 	}
 
+	if err := command.Validate(cmd); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCommand, err)
+	}
+
+	for _, called := range cmd.Actions().CalledCommands() {
+		if _, registered := r.commands[called]; !registered && called != cmd.Definition().Name {
+			return fmt.Errorf("%w: %w: %q", ErrInvalidCommand, command.ErrUnknownCommand, called)
+		}
+	}
+
 	r.commands[cmd.Definition().Name] = cmd
 
 	return nil
@@ -9,11 +9,13 @@ import (
 var (
 	ErrCommandNotFound      = errors.New("command not found")
 	ErrCommandAlreadyExists = errors.New("command already exists")
+	ErrInvalidCommand       = errors.New("invalid command")
 )
 
 type Router interface {
 	// Add command.
-	// Throws ErrCommandAlreadyExists.
+	// Command must be validated by command.Validate, commands declared by command.Actions Calls must be registered.
+	// Throws ErrCommandAlreadyExists, ErrInvalidCommand.
 	Add(cmd command.Command) error
 
 	// Find command by message.
@@ -0,0 +1,87 @@
+package schedule
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	jobs map[string]*memoryJob
+	mu   sync.Mutex
+}
+
+type memoryJob struct {
+	job *Job
+	// visibleAt is time, when job can be returned by Due: job time or end of lease.
+	visibleAt time.Time
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{
+		jobs: map[string]*memoryJob{},
+	}
+}
+
+func (s *memoryStore) Add(_ context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = &memoryJob{
+		job:       job,
+		visibleAt: job.At,
+	}
+
+	return nil
+}
+
+func (s *memoryStore) Due(_ context.Context, now time.Time, lease time.Duration, limit int) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]*memoryJob, 0)
+	for _, j := range s.jobs {
+		if !j.visibleAt.After(now) {
+			due = append(due, j)
+		}
+	}
+
+	sort.Slice(due, func(i, k int) bool {
+		return due[i].visibleAt.Before(due[k].visibleAt)
+	})
+
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	jobs := make([]*Job, 0, len(due))
+	for _, j := range due {
+		j.visibleAt = now.Add(lease)
+		jobs = append(jobs, j.job)
+	}
+
+	return jobs, nil
+}
+
+func (s *memoryStore) Ack(_ context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, job.ID)
+
+	return nil
+}
+
+func (s *memoryStore) Cancel(_ context.Context, stateKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, j := range s.jobs {
+		if j.job.StateKey == stateKey {
+			delete(s.jobs, id)
+		}
+	}
+
+	return nil
+}
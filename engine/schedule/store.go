@@ -0,0 +1,40 @@
+package schedule
+
+import (
+	"context"
+	"time"
+)
+
+// Job is scheduled action of dialog.
+type Job struct {
+	ID string `json:"id"`
+
+	// StateKey is key of dialog state, see state.State Key.
+	StateKey string `json:"state_key"`
+	ChatID   string `json:"chat_id"`
+	// ThreadID is thread (topic) of chat, to which scheduled action replies.
+	ThreadID    string `json:"thread_id,omitempty"`
+	CommandName string `json:"command_name"`
+	// DialogStartedAt identifies dialog: job is not delivered to another dialog of the same state key.
+	DialogStartedAt time.Time `json:"dialog_started_at"`
+
+	StateName string            `json:"state_name"`
+	Data      map[string]string `json:"data"`
+	At        time.Time         `json:"at"`
+}
+
+// Store keeps scheduled jobs with at-least-once delivery:
+// job leased by Due is returned by Due again after lease, until Ack called.
+type Store interface {
+	// Add saves job. Job with the same ID is replaced and returned by Due at its time, even when leased.
+	Add(ctx context.Context, job *Job) error
+
+	// Due returns jobs, which time is come, and leases them for {lease}.
+	Due(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Job, error)
+
+	// Ack removes delivered job.
+	Ack(ctx context.Context, job *Job) error
+
+	// Cancel removes all jobs of dialog state.
+	Cancel(ctx context.Context, stateKey string) error
+}
@@ -0,0 +1,119 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Typed values are stored in state data as strings, so string-only states and storages stay compatible.
+
+var ErrDataKeyNotFound = errors.New("state data key not found")
+
+// SetInt sets integer value.
+func (m *State) SetInt(key string, value int) {
+	m.Set(key, strconv.Itoa(value))
+}
+
+// GetInt returns integer value.
+// Throws ErrDataKeyNotFound.
+func (m *State) GetInt(key string) (int, error) {
+	raw, ok := m.data[key]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrDataKeyNotFound, key)
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse %q as int: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// SetTime sets time value in RFC3339 with nanoseconds.
+func (m *State) SetTime(key string, value time.Time) {
+	m.Set(key, value.Format(time.RFC3339Nano))
+}
+
+// GetTime returns time value.
+// Throws ErrDataKeyNotFound.
+func (m *State) GetTime(key string) (time.Time, error) {
+	raw, ok := m.data[key]
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrDataKeyNotFound, key)
+	}
+
+	value, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse %q as time: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// SetJSON sets value encoded to JSON.
+func (m *State) SetJSON(key string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("marshal %q to json: %w", key, err)
+	}
+
+	m.Set(key, string(raw))
+
+	return nil
+}
+
+// GetJSON returns value decoded from JSON.
+// Throws ErrDataKeyNotFound.
+func GetJSON[T any](st *State, key string) (T, error) {
+	var value T
+
+	raw, ok := st.data[key]
+	if !ok {
+		return value, fmt.Errorf("%w: %s", ErrDataKeyNotFound, key)
+	}
+
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return value, fmt.Errorf("unmarshal %q from json: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Bag stores command-specific struct in state data.
+type Bag[T any] struct {
+	key string
+}
+
+func NewBag[T any](key string) Bag[T] {
+	return Bag[T]{key: key}
+}
+
+// Get returns stored value or zero value, when value not stored.
+func (b Bag[T]) Get(st *State) (T, error) {
+	value, err := GetJSON[T](st, b.key)
+	if errors.Is(err, ErrDataKeyNotFound) {
+		return value, nil
+	}
+
+	return value, err
+}
+
+func (b Bag[T]) Set(st *State, value T) error {
+	return st.SetJSON(b.key, value)
+}
+
+// Update loads stored value, applies fn and stores value.
+func (b Bag[T]) Update(st *State, fn func(value *T)) error {
+	value, err := b.Get(st)
+	if err != nil {
+		return err
+	}
+
+	fn(&value)
+
+	return b.Set(st, value)
+}
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Schema is version of state encoding, see Encode.
+//
+//   - 0: states, which encoded before versioning: key, thread, update time and version may be absent.
+//   - 1: current encoding.
+const Schema = 1
+
+var ErrUnsupportedSchema = errors.New("unsupported state schema")
+
+// record is encoded State. Data, parent and suspended dialogs are encoded with State.
+type record struct {
+	Schema      int               `json:"schema"`
+	Key         string            `json:"key,omitempty"`
+	ChatID      string            `json:"chat_id"`
+	ThreadID    string            `json:"thread_id,omitempty"`
+	Name        string            `json:"name"`
+	CommandName string            `json:"command_name"`
+	Data        map[string]string `json:"data"`
+	StartedAt   time.Time         `json:"started_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	Version     int64             `json:"version"`
+	Parent      *record           `json:"parent,omitempty"`
+	Suspended   *record           `json:"suspended,omitempty"`
+	ResumeMode  string            `json:"resume_mode,omitempty"`
+}
+
+// Encode encodes state to JSON with current Schema.
+// Field "version" of JSON contains State.Version, so storages can check version without decoding.
+func Encode(st *State) ([]byte, error) {
+	raw, err := json.Marshal(newRecord(st))
+	if err != nil {
+		return nil, fmt.Errorf("marshal state: %w", err)
+	}
+
+	return raw, nil
+}
+
+// Decode decodes state, which encoded by Encode with current or previous Schema.
+// Throws ErrUnsupportedSchema, when state encoded by newer version of lowbot.
+func Decode(raw []byte) (*State, error) {
+	var r record
+
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return nil, fmt.Errorf("unmarshal state: %w", err)
+	}
+
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	return r.state(), nil
+}
+
+func newRecord(st *State) *record {
+	r := &record{
+		Schema:      Schema,
+		Key:         st.Key(),
+		ChatID:      st.chatID,
+		ThreadID:    st.threadID,
+		Name:        st.name,
+		CommandName: st.commandName,
+		Data:        st.data,
+		StartedAt:   st.startedAt,
+		UpdatedAt:   st.updatedAt,
+		Version:     st.version,
+	}
+
+	if st.parent != nil {
+		r.Parent = newRecord(st.parent)
+	}
+
+	if st.suspended != nil {
+		r.Suspended = newRecord(st.suspended)
+		r.ResumeMode = st.resumeMode
+	}
+
+	return r
+}
+
+func (r *record) validate() error {
+	if r.Schema > Schema {
+		return fmt.Errorf("%w %d, max supported %d", ErrUnsupportedSchema, r.Schema, Schema)
+	}
+
+	for _, nested := range []*record{r.Parent, r.Suspended} {
+		if nested == nil {
+			continue
+		}
+
+		if err := nested.validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *record) state() *State {
+	if r.Schema == 0 && r.UpdatedAt.IsZero() {
+		// states, which encoded before update time introduced.
+		r.UpdatedAt = r.StartedAt
+	}
+
+	st := NewFullState(r.ChatID, r.Name, r.CommandName, r.Data, r.StartedAt, r.UpdatedAt, r.Version)
+	// key equals chat id for KeyByChat.
+	if r.Key != r.ChatID {
+		st.key = r.Key
+	}
+	st.threadID = r.ThreadID
+
+	if r.Parent != nil {
+		st.parent = r.Parent.state()
+	}
+
+	if r.Suspended != nil {
+		st.Suspend(r.Suspended.state(), r.ResumeMode)
+	}
+
+	return st
+}
@@ -0,0 +1,32 @@
+package state
+
+// KeyStrategy defines key of State in Storage, so defines which dialogs of chat are independent.
+type KeyStrategy string
+
+const (
+	// KeyByChat holds one dialog per chat.
+	KeyByChat KeyStrategy = "chat"
+	// KeyByChatSender holds one dialog per member of chat, e.g. for group chats.
+	KeyByChatSender KeyStrategy = "chat_sender"
+	// KeyByChatThread holds one dialog per thread (topic) of chat.
+	KeyByChatThread KeyStrategy = "chat_thread"
+)
+
+const keySeparator = ":"
+
+// Key builds key of State. Falls back to chat id, when sender or thread is unknown.
+func (s KeyStrategy) Key(chatID, senderID, threadID string) string {
+	switch s {
+	case KeyByChatSender:
+		if senderID != "" {
+			return chatID + keySeparator + senderID
+		}
+	case KeyByChatThread:
+		if threadID != "" {
+			return chatID + keySeparator + threadID
+		}
+	case KeyByChat:
+	}
+
+	return chatID
+}
@@ -6,13 +6,19 @@ import (
 )
 
 type memoryStorage struct {
-	states map[string]*State
+	states map[string]*memoryState
 	mu     sync.RWMutex
 }
 
+// memoryState is encoded state, see Encode.
+type memoryState struct {
+	version int64
+	payload []byte
+}
+
 func NewMemoryStorage() Storage {
 	return &memoryStorage{
-		states: make(map[string]*State),
+		states: make(map[string]*memoryState),
 		mu:     sync.RWMutex{},
 	}
 }
@@ -21,25 +27,44 @@ func (s *memoryStorage) StorageName() string {
 	return "memory"
 }
 
-func (s *memoryStorage) Get(_ context.Context, chatID string) (*State, error) {
+func (s *memoryStorage) Get(_ context.Context, key string) (*State, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	st, exists := s.states[chatID]
+	st, exists := s.states[key]
 	if !exists {
 		return nil, ErrStateNotFound
 	}
 
-	st.transited = false
-	st.forward = nil
-
-	return st, nil
+	return Decode(st.payload)
 }
 
 func (s *memoryStorage) Put(_ context.Context, state *State) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.states[state.ChatID()] = state
+	stored, exists := s.states[state.Key()]
+	if exists && stored.version != state.version {
+		return ErrStateConflict
+	}
+
+	// state was deleted, e.g. finished by concurrent handler.
+	if !exists && state.version != 0 {
+		return ErrStateConflict
+	}
+
+	state.SetVersion(state.version + 1)
+
+	payload, err := Encode(state)
+	if err != nil {
+		state.SetVersion(state.version - 1)
+
+		return err
+	}
+
+	s.states[state.Key()] = &memoryState{
+		version: state.version,
+		payload: payload,
+	}
 	return nil
 }
 
@@ -47,6 +72,15 @@ func (s *memoryStorage) Delete(_ context.Context, state *State) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.states, state.ChatID())
+	stored, exists := s.states[state.Key()]
+	if !exists {
+		return ErrStateNotFound
+	}
+
+	if stored.version != state.version {
+		return ErrStateConflict
+	}
+
+	delete(s.states, state.Key())
 	return nil
 }
@@ -20,10 +20,10 @@ func (s *ObservableStorage) StorageName() string {
 	return s.storage.StorageName()
 }
 
-func (s *ObservableStorage) Get(ctx context.Context, chatID string) (*State, error) {
+func (s *ObservableStorage) Get(ctx context.Context, key string) (*State, error) {
 	started := time.Now()
 
-	value, err := s.storage.Get(ctx, chatID)
+	value, err := s.storage.Get(ctx, key)
 
 	s.metrics.ObserveOperationExecution(s.StorageName(), "Get", time.Since(started))
 
@@ -36,11 +36,11 @@ func (s *PriorityStorage) StorageName() string {
 	return "priority"
 }
 
-func (s *PriorityStorage) Get(ctx context.Context, chatID string) (*State, error) {
-	state, err := s.fallbackStorage.Get(ctx, chatID)
+func (s *PriorityStorage) Get(ctx context.Context, key string) (*State, error) {
+	state, err := s.fallbackStorage.Get(ctx, key)
 	if err != nil {
 		if errors.Is(err, ErrStateNotFound) {
-			state, err = s.priorityStorage.Get(ctx, chatID)
+			state, err = s.priorityStorage.Get(ctx, key)
 			if err != nil {
 				return nil, err
 			}
@@ -52,18 +52,60 @@ func (s *PriorityStorage) Get(ctx context.Context, chatID string) (*State, error
 	return state, nil
 }
 
+// Put state to storage of its command.
+// State, which replaces dialog of other storage (e.g. dialog interrupted by command of other storage),
+// is moved: stored state is deleted from other storage and state is saved to storage of its command with version 0.
 func (s *PriorityStorage) Put(ctx context.Context, state *State) error {
-	if _, ok := s.priorityCommands[state.commandName]; ok {
-		return s.priorityStorage.Put(ctx, state)
+	target, other := s.storages(state)
+
+	if state.version == 0 {
+		return target.Put(ctx, state)
+	}
+
+	stored, err := other.Get(ctx, state.Key())
+	if err != nil {
+		if errors.Is(err, ErrStateNotFound) {
+			return target.Put(ctx, state)
+		}
+
+		return err
+	}
+
+	if stored.version != state.version {
+		return ErrStateConflict
+	}
+
+	if err = other.Delete(ctx, stored); err != nil {
+		if errors.Is(err, ErrStateNotFound) {
+			return ErrStateConflict
+		}
+
+		return err
 	}
 
-	return s.fallbackStorage.Put(ctx, state)
+	version := state.version
+
+	state.SetVersion(0)
+	if err = target.Put(ctx, state); err != nil {
+		state.SetVersion(version)
+
+		return err
+	}
+
+	return nil
 }
 
 func (s *PriorityStorage) Delete(ctx context.Context, state *State) error {
+	target, _ := s.storages(state)
+
+	return target.Delete(ctx, state)
+}
+
+// storages returns storage of state command and other storage.
+func (s *PriorityStorage) storages(state *State) (Storage, Storage) {
 	if _, ok := s.priorityCommands[state.commandName]; ok {
-		return s.priorityStorage.Delete(ctx, state)
+		return s.priorityStorage, s.fallbackStorage
 	}
 
-	return s.fallbackStorage.Delete(ctx, state)
+	return s.fallbackStorage, s.priorityStorage
 }
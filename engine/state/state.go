@@ -4,19 +4,44 @@ import "time"
 
 const (
 	Passthrough = "lowbot.passthrough" //nolint:gosec //false-positive
+
+	// Resume is name of state, which restores suspended dialog. Used in arguments of resume button.
+	Resume = "lowbot.resume"
+	// Timeout is name of state in scheduled job, which expires idle dialog.
+	Timeout = "lowbot.timeout"
+	// ResumeIDKey is key of data in arguments of resume button, which contains id of stored resume offer.
+	ResumeIDKey = "lowbot.resume_id"
 )
 
 type State struct {
+	// key identifies State in Storage, see KeyStrategy.
+	key         string
 	chatID      string
 	name        string
 	commandName string
+	// threadID is thread (topic) of chat, to which dialog replies without user message, e.g. scheduled action.
+	threadID string
 
 	forward   *Forward
 	transited bool
+	call      *Call
+	scheduled []*Scheduled
+
+	// parent is state of dialog, which called this dialog as sub-dialog.
+	parent *State
+
+	// suspended is state of dialog, which interrupted by this dialog and resumed after it.
+	suspended  *State
+	resumeMode string
 
 	data map[string]string
 
 	startedAt time.Time
+	// updatedAt is time of last dialog activity.
+	updatedAt time.Time
+
+	// version is incremented by Storage on each Put, used for optimistic concurrency.
+	version int64
 }
 
 type Forward struct {
@@ -27,12 +52,47 @@ func (f *Forward) NewStateName() string {
 	return f.newStateName
 }
 
+type Call struct {
+	commandName string
+	returnState string
+}
+
+func (c *Call) CommandName() string {
+	return c.commandName
+}
+
+func (c *Call) ReturnState() string {
+	return c.returnState
+}
+
+// Scheduled is action of dialog, which runs at time without user input.
+type Scheduled struct {
+	at        time.Time
+	stateName string
+	data      map[string]string
+}
+
+func (s *Scheduled) At() time.Time {
+	return s.at
+}
+
+func (s *Scheduled) StateName() string {
+	return s.stateName
+}
+
+func (s *Scheduled) Data() map[string]string {
+	return s.data
+}
+
 func NewState(chatID string, commandName string) *State {
+	now := time.Now()
+
 	return &State{
 		chatID:      chatID,
 		commandName: commandName,
 		data:        make(map[string]string),
-		startedAt:   time.Now(),
+		startedAt:   now,
+		updatedAt:   now,
 	}
 }
 
@@ -42,6 +102,8 @@ func NewFullState(
 	commandName string,
 	data map[string]string,
 	startedAt time.Time,
+	updatedAt time.Time,
+	version int64,
 ) *State {
 	if data == nil {
 		data = make(map[string]string)
@@ -53,6 +115,8 @@ func NewFullState(
 		commandName: commandName,
 		data:        data,
 		startedAt:   startedAt,
+		updatedAt:   updatedAt,
+		version:     version,
 	}
 }
 
@@ -60,6 +124,30 @@ func (m *State) ChatID() string {
 	return m.chatID
 }
 
+// ThreadID returns thread (topic) of chat, in which dialog continued last time, or empty string.
+func (m *State) ThreadID() string {
+	return m.threadID
+}
+
+// SetThreadID sets thread (topic) of chat, see ThreadID.
+func (m *State) SetThreadID(threadID string) {
+	m.threadID = threadID
+}
+
+// Key returns key of State in Storage. Key is chat id, when key not set.
+func (m *State) Key() string {
+	if m.key == "" {
+		return m.chatID
+	}
+
+	return m.key
+}
+
+// SetKey sets key of State in Storage, see KeyStrategy.
+func (m *State) SetKey(key string) {
+	m.key = key
+}
+
 func (m *State) Name() string {
 	return m.name
 }
@@ -89,15 +177,42 @@ func (m *State) Set(key string, value string) {
 	m.data[key] = value
 }
 
+func (m *State) Unset(key string) {
+	delete(m.data, key)
+}
+
 func (m *State) StartedAt() time.Time {
 	return m.startedAt
 }
 
+// UpdatedAt returns time of last dialog activity.
+func (m *State) UpdatedAt() time.Time {
+	return m.updatedAt
+}
+
+// Touch marks dialog as active now.
+func (m *State) Touch() {
+	m.updatedAt = time.Now()
+}
+
+// Version returns version of state, which was loaded from Storage.
+// Version equals 0 for new state.
+func (m *State) Version() int64 {
+	return m.version
+}
+
+// SetVersion sets version of state.
+// Should be called only by Storage after successful Put.
+func (m *State) SetVersion(version int64) {
+	m.version = version
+}
+
 func (m *State) Duration() time.Duration {
 	return time.Since(m.startedAt)
 }
 
 // Forward save current state and immediately run action for state {newStateName}.
+// Target state must be declared by ForwardsTo of command actions, see command.Validate.
 func (m *State) Forward(newStateName string) {
 	m.forward = &Forward{
 		newStateName: newStateName,
@@ -117,3 +232,85 @@ func (m *State) Passthrough() error {
 func (m *State) Forwarded() *Forward {
 	return m.forward
 }
+
+// Call save current state and immediately run command {commandName} as sub-dialog.
+// When sub-dialog finished, action of state {returnState} runs immediately without user input
+// with data of sub-dialog. Sub-dialog must be declared by command.Actions Calls.
+func (m *State) Call(commandName, returnState string) {
+	m.call = &Call{
+		commandName: commandName,
+		returnState: returnState,
+	}
+}
+
+func (m *State) Called() *Call {
+	return m.call
+}
+
+// Schedule runs action for state {stateName} at time {at} without user input.
+// Data is merged into state data before running. Scheduled action is canceled, when dialog deleted or interrupted,
+// so action, scheduled by last action of dialog, is discarded.
+func (m *State) Schedule(at time.Time, stateName string, data map[string]string) {
+	m.scheduled = append(m.scheduled, &Scheduled{
+		at:        at,
+		stateName: stateName,
+		data:      data,
+	})
+}
+
+func (m *State) ScheduledActions() []*Scheduled {
+	return m.scheduled
+}
+
+// NewChildState creates state of sub-dialog, which called from parent state.
+func NewChildState(parent *State, commandName string) *State {
+	child := NewState(parent.chatID, commandName)
+	child.key = parent.key
+	child.threadID = parent.threadID
+	child.parent = parent
+	child.version = parent.version
+
+	return child
+}
+
+// Parent returns state of dialog, which called current dialog, or nil.
+func (m *State) Parent() *State {
+	return m.parent
+}
+
+// SetParent sets state of dialog, which called current dialog.
+// Should be called only by Storage for restoring dialog stack.
+func (m *State) SetParent(parent *State) {
+	m.parent = parent
+}
+
+// Return returns parent state with merged data of current state.
+func (m *State) Return() *State {
+	parent := m.parent
+	if parent.data == nil {
+		parent.data = make(map[string]string, len(m.data))
+	}
+
+	for k, v := range m.data {
+		parent.data[k] = v
+	}
+	parent.version = m.version
+	parent.Touch()
+
+	return parent
+}
+
+// Suspend parks interrupted dialog. Dialog is resumed by resumeMode, when current dialog finished.
+func (m *State) Suspend(interrupted *State, resumeMode string) {
+	m.suspended = interrupted
+	m.resumeMode = resumeMode
+}
+
+// Suspended returns state of dialog, which interrupted by current dialog, or nil.
+func (m *State) Suspended() *State {
+	return m.suspended
+}
+
+func (m *State) ResumeMode() string {
+	return m.resumeMode
+}
@@ -5,18 +5,25 @@ import (
 	"errors"
 )
 
-var ErrStateNotFound = errors.New("state not found")
+var (
+	ErrStateNotFound = errors.New("state not found")
+	ErrStateConflict = errors.New("state conflict")
+)
 
 type Storage interface {
 	StorageName() string
 
-	// Get State by chat id.
+	// Get State by key, see State.Key.
 	// Throws ErrStateNotFound.
-	Get(ctx context.Context, chatID string) (*State, error)
+	Get(ctx context.Context, key string) (*State, error)
 
+	// Put state with optimistic concurrency control.
+	// State is saved only if stored state has same version, or stored state is absent and state version is 0,
+	// then state version is incremented.
+	// Throws ErrStateConflict, when state was changed or deleted concurrently.
 	Put(ctx context.Context, state *State) error
 
-	// Delete state.
-	// Throws ErrStateNotFound.
+	// Delete state with optimistic concurrency control: state is deleted only if stored state has same version.
+	// Throws ErrStateNotFound, ErrStateConflict.
 	Delete(ctx context.Context, state *State) error
 }
@@ -6,9 +6,14 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
+	"github.com/artarts36/lowbot/engine/dedupe"
+	"github.com/artarts36/lowbot/engine/dispatcher"
 	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/messenger/broadcast"
 	"github.com/artarts36/lowbot/messenger/messengerapi"
 
 	"github.com/artarts36/lowbot/logx"
@@ -21,19 +26,66 @@ import (
 	sloghttp "github.com/samber/slog-http"
 )
 
+const (
+	defaultScheduleCheckInterval = 5 * time.Second
+	defaultDedupeTTL             = time.Hour
+)
+
 type Application struct {
-	router  router.Router
-	machine *machine.Machine
-	msngr   messengerapi.Messenger
+	router     router.Router
+	machine    *machine.Machine
+	messengers []NamedMessenger
+	byName     map[string]messengerapi.Messenger
+	// qualified is true, when chat ids are qualified by messenger name, see NewMulti.
+	qualified bool
+
+	dispatcher  *dispatcher.Dispatcher
+	broadcaster *broadcast.Broadcaster
+	dedupe      *dedupe.Deduplicator
+	updates     *metrics.Updates
+
+	scheduleCheckInterval time.Duration
+	watchCtx              context.Context
+	stopWatching          context.CancelFunc
+
+	// intakeCtx is done, when application stops passing messages of messengers to dispatcher.
+	intakeCtx  context.Context
+	stopIntake context.CancelFunc
 
 	server *http.Server
 	logger logx.Logger
 }
 
+// New creates Application with one messenger, which webhook handler mounted to "/".
 func New(
 	msngr messengerapi.Messenger,
 	opts ...Option,
 ) (*Application, error) {
+	return newApplication([]NamedMessenger{
+		{Name: DefaultMessengerName, Path: "/", Messenger: msngr},
+	}, false, opts)
+}
+
+// NewMulti creates Application, which serves same commands on several messengers.
+// Chat ids of messages are qualified by messenger name, see messengerapi.QualifyChatID,
+// so dialogs of chats with same id in different messengers do not collide in state storage.
+// Methods Send, StartDialog and Broadcast accept qualified chat ids, see ChatID.
+func NewMulti(
+	messengers []NamedMessenger,
+	opts ...Option,
+) (*Application, error) {
+	return newApplication(messengers, true, opts)
+}
+
+func newApplication(
+	messengers []NamedMessenger,
+	qualified bool,
+	opts []Option,
+) (*Application, error) {
+	if err := validateMessengers(messengers); err != nil {
+		return nil, err
+	}
+
 	cfg := &config{
 		storageFn: func(metrics *metrics.StateStorage) state.Storage {
 			return state.NewObservableStorage(state.NewMemoryStorage(), metrics)
@@ -48,47 +100,118 @@ func New(
 		startCommandFn: func(r router.Router) command.Command {
 			return router.NewStartCommand("start", r)
 		},
-		logger: slog.Default(),
+		logger:         slog.Default(),
+		webhook:        true,
+		conflictPolicy: machine.AbortOnConflict(),
+		resumeTexts:    machine.DefaultResumeTexts(),
+		keyStrategy:    state.KeyByChat,
+
+		scheduleStore:         schedule.NewMemoryStore(),
+		scheduleCheckInterval: defaultScheduleCheckInterval,
+		dedupeStore:           dedupe.NewMemoryStore(),
+		dedupeTTL:             defaultDedupeTTL,
 	}
 
 	for _, opt := range opts {
 		opt(cfg)
 	}
 
+	if cfg.scheduleCheckInterval <= 0 {
+		return nil, fmt.Errorf("schedule check interval must be positive, got %s", cfg.scheduleCheckInterval)
+	}
+
 	metricsGroup := metrics.NewGroup()
 
 	if err := cfg.prometheusRegisterer.Register(metricsGroup); err != nil {
 		return nil, fmt.Errorf("register metrics: %w", err)
 	}
 
+	// messenger can expose own metrics, e.g. telebot send queue.
+	collectors := make(messengerCollectors, 0)
+	byName := make(map[string]messengerapi.Messenger, len(messengers))
+
+	for _, m := range messengers {
+		byName[m.Name] = m.Messenger
+
+		if collector, ok := m.Messenger.(prometheus.Collector); ok {
+			collectors = append(collectors, collector)
+		}
+	}
+
+	if len(collectors) > 0 {
+		if err := cfg.prometheusRegisterer.Register(collectors); err != nil {
+			return nil, fmt.Errorf("register messenger metrics: %w", err)
+		}
+	}
+
 	app := &Application{
-		router: cfg.router,
-		msngr:  msngr,
-		logger: cfg.logger,
+		router:     cfg.router,
+		messengers: messengers,
+		byName:     byName,
+		qualified:  qualified,
+		updates:    metricsGroup.Updates(),
+		logger:     cfg.logger,
+
+		scheduleCheckInterval: cfg.scheduleCheckInterval,
 	}
 
-	err := app.router.Add(cfg.startCommandFn(app.router))
+	app.watchCtx, app.stopWatching = context.WithCancel(context.Background())
+	app.intakeCtx, app.stopIntake = context.WithCancel(context.Background())
+
+	err := app.AddCommand(cfg.startCommandFn(app.router))
 	if err != nil {
 		return nil, fmt.Errorf("register start command: %w", err)
 	}
 
+	if cfg.cancelCommand != nil {
+		if err = app.AddCommand(cfg.cancelCommand); err != nil {
+			return nil, fmt.Errorf("register cancel command: %w", err)
+		}
+	}
+
+	app.dispatcher = dispatcher.New(cfg.dispatcher, app.handleMessage, cfg.logger)
+	app.broadcaster = broadcast.New(cfg.broadcast, app.createChatResponder)
+
+	if cfg.dedupeStore != nil {
+		app.dedupe = dedupe.New(cfg.dedupeStore, cfg.dedupeTTL, metricsGroup.Updates(), cfg.logger)
+	}
+
 	app.machine = machine.New(
 		app.router,
 		cfg.storageFn(metricsGroup.StateStorage()),
+		cfg.scheduleStore,
 		machine.NewErrorHandler(cfg.logger),
 		cfg.commandNotFoundFallback(app.router),
+		cfg.conflictPolicy,
+		cfg.resumeTexts,
+		cfg.keyStrategy,
 		metricsGroup,
 		command.NewBus(cfg.middlewares),
 		cfg.logger,
 	)
 
-	app.prepareHTTPServer(cfg.httpAddr)
+	if cfg.webhook {
+		app.prepareHTTPServer(cfg.httpAddr)
+	}
 
 	return app, nil
 }
 
+// AddCommand adds command to router, which validates command graph.
+// Warnings of command graph are logged, see command.Warnings.
 func (app *Application) AddCommand(cmd command.Command) error {
-	return app.router.Add(cmd)
+	if err := app.router.Add(cmd); err != nil {
+		return err
+	}
+
+	for _, warning := range command.Warnings(cmd) {
+		app.logger.WarnContext(context.Background(), "[application] command graph has problem",
+			logx.CommandName(cmd.Definition().Name),
+			logx.Err(warning),
+		)
+	}
+
+	return nil
 }
 
 func (app *Application) MustAddCommand(cmd command.Command) {
@@ -101,29 +224,23 @@ func (app *Application) Run() error {
 	ch := make(chan messengerapi.Message)
 
 	go func() {
-		for msg := range ch {
-			ctx := context.Background()
-
-			if err := app.machine.Handle(ctx, &machine.Request{
-				Message:   msg,
-				Responder: app.msngr.CreateResponder(msg.GetChatID()),
-			}); err != nil {
-				slog.ErrorContext(ctx,
-					"[application] failed to handle message",
-					logx.Err(err),
-					slog.String("message.id", msg.GetID()),
-					slog.String("message.chat_id", msg.GetChatID()),
-				)
-			}
-		}
+		app.dispatcher.Run(ch)
 	}()
 
 	go func() {
-		if err := app.msngr.Listen(ch); err != nil {
+		app.machine.WatchSchedule(app.watchCtx, app.scheduleCheckInterval, app.createResponder, app.dispatcher.Do)
+	}()
+
+	if app.server == nil {
+		app.logger.InfoContext(context.Background(), "[application] listen messenger")
+
+		return app.listen(ch)
+	}
+
+	go func() {
+		if err := app.listen(ch); err != nil {
 			slog.Error("[application] failed to listen messenger", slog.Any("err", err))
 		}
-
-		close(ch)
 	}()
 
 	app.logger.InfoContext(context.Background(), "[application] listen http server", slog.String("addr", app.server.Addr))
@@ -131,15 +248,273 @@ func (app *Application) Run() error {
 	return app.server.ListenAndServe()
 }
 
+// listen sends messages of all messengers to ch.
+// Closes ch, when all messengers stopped or intake stopped, see Shutdown.
+func (app *Application) listen(ch chan messengerapi.Message) error {
+	var wg, forwarding sync.WaitGroup
+
+	errs := make([]error, len(app.messengers))
+
+	for i, m := range app.messengers {
+		wg.Add(1)
+		forwarding.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			msgs := make(chan messengerapi.Message)
+			forwarded := make(chan struct{})
+
+			go func() {
+				defer close(forwarded)
+
+				app.forward(ch, m.Name, msgs)
+				forwarding.Done()
+
+				// messenger is still listening, when intake stopped before messenger stopped.
+				for msg := range msgs {
+					app.reject(msg)
+				}
+			}()
+
+			if err := m.Messenger.Listen(msgs); err != nil {
+				errs[i] = fmt.Errorf("listen messenger %q: %w", m.Name, err)
+			}
+
+			close(msgs)
+			<-forwarded
+		}()
+	}
+
+	go func() {
+		forwarding.Wait()
+		close(ch)
+	}()
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// forward sends messages of messenger to ch until messenger or intake stopped.
+func (app *Application) forward(ch chan<- messengerapi.Message, messenger string, msgs <-chan messengerapi.Message) {
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+
+			select {
+			case ch <- app.name(messenger, msg):
+			case <-app.intakeCtx.Done():
+				app.reject(msg)
+				return
+			}
+		case <-app.intakeCtx.Done():
+			return
+		}
+	}
+}
+
+// reject skips message, which received after intake stopped.
+func (app *Application) reject(msg messengerapi.Message) {
+	app.logger.WarnContext(context.Background(), "[application] message rejected, application is stopping",
+		slog.String("message.id", msg.GetID()),
+		slog.String("message.chat_id", msg.GetChatID()),
+	)
+
+	if done, ok := msg.(messengerapi.DoneMessage); ok {
+		done.Done(dispatcher.ErrStopped)
+	}
+}
+
+// name marks message with name of messenger and qualifies chat id.
+func (app *Application) name(messenger string, msg messengerapi.Message) *namedMessage {
+	return &namedMessage{
+		Message:   msg,
+		messenger: messenger,
+		chatID:    app.ChatID(messenger, msg.GetChatID()),
+	}
+}
+
+// ChatID returns chat id of messenger for Send, StartDialog and Broadcast.
+// Chat id is qualified by messenger name, when application created by NewMulti.
+func (app *Application) ChatID(messenger, chatID string) string {
+	if !app.qualified {
+		return chatID
+	}
+
+	return messengerapi.QualifyChatID(messenger, chatID)
+}
+
+// createResponder creates responder of messenger of chat, which replies to thread of chat, when threadID is not empty.
+func (app *Application) createResponder(chatID, threadID string) messengerapi.Responder {
+	msngr, messengerChatID, err := app.messengerOf(chatID)
+	if err != nil {
+		return &failedResponder{err: err}
+	}
+
+	return messengerapi.CreateThreadResponder(msngr, messengerChatID, threadID)
+}
+
+// createReplyResponder creates responder of messenger of message, which relates replies to message.
+func (app *Application) createReplyResponder(msg messengerapi.Message) messengerapi.Responder {
+	msngr, messengerChatID, err := app.messengerOf(msg.GetChatID())
+	if err != nil {
+		return &failedResponder{err: err}
+	}
+
+	return messengerapi.CreateReplyResponder(msngr, messengerChatID, messengerapi.ThreadID(msg), msg.GetID())
+}
+
+// messengerOf returns messenger of chat and chat id in messenger. Throws ErrUnknownMessenger.
+func (app *Application) messengerOf(chatID string) (messengerapi.Messenger, string, error) {
+	if !app.qualified {
+		return app.messengers[0].Messenger, chatID, nil
+	}
+
+	name, messengerChatID, ok := messengerapi.SplitChatID(chatID)
+	msngr, found := app.byName[name]
+	if !ok || !found {
+		return nil, "", fmt.Errorf("%w of chat %q", ErrUnknownMessenger, chatID)
+	}
+
+	return msngr, messengerChatID, nil
+}
+
+// createChatResponder creates responder of messenger of chat out of threads.
+func (app *Application) createChatResponder(chatID string) messengerapi.Responder {
+	return app.createResponder(chatID, "")
+}
+
+// Send sends message to chat out of dialog, e.g. notification from another service.
+// Message is not sent, when ctx done before sending, e.g. while message waits for rate limits of messenger.
+func (app *Application) Send(ctx context.Context, chatID string, answer *messengerapi.Answer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	_, err := messengerapi.ResponderWithContext(ctx, app.createChatResponder(chatID)).Respond(answer)
+	return err
+}
+
+// StartDialog starts dialog of command without waiting message from user.
+// Data is set to dialog state before running first action. Active dialog with the same key is replaced.
+// Dialog is started by worker of chat after queued messages of chat, see WithDispatcher.
+// Throws machine.ErrSenderRequired, when dialogs keyed by state.KeyByChatSender and dialog has no sender.
+func (app *Application) StartDialog(
+	ctx context.Context,
+	dialog machine.DialogRef,
+	commandName string,
+	data map[string]string,
+) error {
+	return app.dispatcher.Do(ctx, dialog.ChatID, func(ctx context.Context) error {
+		return app.machine.StartDialog(ctx, dialog, commandName, data, app.createResponder)
+	})
+}
+
+// Broadcast sends message to chats with rate limits, see WithBroadcast.
+// Blocks until message sent to all chats or ctx done. Throws *broadcast.SendError with failed chats.
+func (app *Application) Broadcast(ctx context.Context, chatIDs []string, answer *messengerapi.Answer) error {
+	return app.broadcaster.Send(ctx, chatIDs, answer)
+}
+
+func (app *Application) handleMessage(ctx context.Context, msg messengerapi.Message) {
+	if done, ok := msg.(messengerapi.DoneMessage); ok {
+		// context is canceled, when handling abandoned on shutdown.
+		defer func() {
+			done.Done(ctx.Err())
+		}()
+	}
+
+	messenger := messengerapi.MessengerName(msg)
+	ctx = logx.WithMessenger(ctx, messenger)
+
+	app.updates.IncReceived(messenger)
+
+	if app.dedupe != nil && app.dedupe.Duplicate(ctx, msg) {
+		return
+	}
+
+	if err := app.machine.Handle(ctx, &machine.Request{
+		Message:   msg,
+		Responder: app.createReplyResponder(msg),
+	}); err != nil {
+		slog.ErrorContext(ctx,
+			"[application] failed to handle message",
+			logx.Err(err),
+			slog.String("message.id", msg.GetID()),
+			slog.String("message.chat_id", msg.GetChatID()),
+			slog.String("messenger.name", messenger),
+		)
+
+		// message is handled again, when messenger redelivers it.
+		if app.dedupe != nil {
+			app.dedupe.Release(context.WithoutCancel(ctx), msg)
+		}
+	}
+}
+
 func (app *Application) Close() error {
 	errs := make([]error, 0)
 
-	if err := app.server.Close(); err != nil {
-		errs = append(errs, err)
+	app.stopWatching()
+	app.stopIntake()
+
+	if app.server != nil {
+		if err := app.server.Close(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	if err := app.msngr.Close(); err != nil {
-		errs = append(errs, err)
+	for _, m := range app.messengers {
+		if err := m.Messenger.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close messenger %q: %w", m.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Shutdown gracefully stops application: stops accepting messenger updates,
+// waits for handling of received messages until ctx done and then shuts messengers down,
+// so running actions respond through open messengers.
+// When ctx done before, context of running actions is canceled and AbandonedMessagesError returned.
+func (app *Application) Shutdown(ctx context.Context) error {
+	errs := make([]error, 0)
+
+	app.logger.InfoContext(ctx, "[application] shutting down")
+
+	app.stopWatching()
+
+	if app.server != nil {
+		if err := app.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown http server: %w", err))
+		}
+	}
+
+	app.stopIntake()
+
+	abandoned, err := app.dispatcher.Wait(ctx)
+	if err != nil {
+		for _, msg := range abandoned {
+			app.logger.WarnContext(ctx, "[application] message abandoned",
+				slog.String("message.id", msg.GetID()),
+				slog.String("message.chat_id", msg.GetChatID()),
+			)
+		}
+
+		errs = append(errs, &AbandonedMessagesError{
+			Messages: abandoned,
+			Err:      err,
+		})
+	}
+
+	for _, m := range app.messengers {
+		if err = shutdownMessenger(ctx, m.Messenger); err != nil {
+			errs = append(errs, fmt.Errorf("close messenger %q: %w", m.Name, err))
+		}
 	}
 
 	return errors.Join(errs...)
@@ -151,7 +526,9 @@ func (app *Application) prepareHTTPServer(addr string) {
 	log := sloghttp.New(slog.Default())
 
 	mux := http.NewServeMux()
-	mux.Handle("/", log(app.msngr))
+	for _, m := range app.messengers {
+		mux.Handle(m.Path, log(m.Messenger))
+	}
 
 	app.server = &http.Server{
 		Addr:              addr,
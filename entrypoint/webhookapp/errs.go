@@ -0,0 +1,22 @@
+package webhookapp
+
+import (
+	"fmt"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// AbandonedMessagesError is returned by Application.Shutdown, when messages were not handled before deadline.
+type AbandonedMessagesError struct {
+	// Messages which were in handling or waited in queue.
+	Messages []messengerapi.Message
+	Err      error
+}
+
+func (e *AbandonedMessagesError) Error() string {
+	return fmt.Sprintf("%d messages abandoned: %s", len(e.Messages), e.Err)
+}
+
+func (e *AbandonedMessagesError) Unwrap() error {
+	return e.Err
+}
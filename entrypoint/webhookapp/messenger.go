@@ -0,0 +1,154 @@
+package webhookapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultMessengerName is name of messenger of application, created by New.
+const DefaultMessengerName = "default"
+
+var ErrUnknownMessenger = errors.New("unknown messenger")
+
+// NamedMessenger is messenger of application, which webhook handler mounted to Path.
+type NamedMessenger struct {
+	// Name labels logs and metrics and qualifies chat ids, see messengerapi.QualifyChatID.
+	Name string
+	// Path of webhook handler, e.g. "/staff".
+	Path      string
+	Messenger messengerapi.Messenger
+}
+
+func validateMessengers(messengers []NamedMessenger) error {
+	if len(messengers) == 0 {
+		return errors.New("missing messengers")
+	}
+
+	names := make(map[string]bool, len(messengers))
+	paths := make(map[string]bool, len(messengers))
+
+	for _, m := range messengers {
+		if m.Name == "" || strings.Contains(m.Name, ":") {
+			return fmt.Errorf("invalid messenger name %q", m.Name)
+		}
+
+		if !strings.HasPrefix(m.Path, "/") {
+			return fmt.Errorf("invalid webhook path %q of messenger %q", m.Path, m.Name)
+		}
+
+		if m.Messenger == nil {
+			return fmt.Errorf("missing messenger %q", m.Name)
+		}
+
+		if names[m.Name] {
+			return fmt.Errorf("duplicate messenger name %q", m.Name)
+		}
+
+		if paths[m.Path] {
+			return fmt.Errorf("duplicate webhook path %q", m.Path)
+		}
+
+		names[m.Name] = true
+		paths[m.Path] = true
+	}
+
+	return nil
+}
+
+// shutdowner is implemented by messengers, which flush pending work until ctx done on shutdown.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// shutdownMessenger stops messenger within ctx, when messenger supports it.
+func shutdownMessenger(ctx context.Context, messenger messengerapi.Messenger) error {
+	if s, ok := messenger.(shutdowner); ok {
+		return s.Shutdown(ctx)
+	}
+
+	return messenger.Close()
+}
+
+// namedMessage is message of named messenger. Chat id is qualified, when application serves several messengers.
+type namedMessage struct {
+	messengerapi.Message
+
+	messenger string
+	chatID    string
+}
+
+func (m *namedMessage) GetChatID() string {
+	return m.chatID
+}
+
+func (m *namedMessage) GetMessengerName() string {
+	return m.messenger
+}
+
+func (m *namedMessage) GetThreadID() string {
+	if thread, ok := m.Message.(messengerapi.ThreadMessage); ok {
+		return thread.GetThreadID()
+	}
+
+	return ""
+}
+
+func (m *namedMessage) IsCallback() bool {
+	if callback, ok := m.Message.(messengerapi.CallbackMessage); ok {
+		return callback.IsCallback()
+	}
+
+	return false
+}
+
+func (m *namedMessage) Done(err error) {
+	if done, ok := m.Message.(messengerapi.DoneMessage); ok {
+		done.Done(err)
+	}
+}
+
+// failedResponder is Responder of chat with unknown messenger.
+type failedResponder struct {
+	err error
+}
+
+func (r *failedResponder) Respond(*messengerapi.Answer) (messengerapi.Message, error) {
+	return nil, r.err
+}
+
+func (r *failedResponder) RespondObject(messengerapi.Object) (messengerapi.Message, error) {
+	return nil, r.err
+}
+
+func (r *failedResponder) Edit(string, *messengerapi.Answer) (messengerapi.Message, error) {
+	return nil, r.err
+}
+
+func (r *failedResponder) Delete(string) error {
+	return r.err
+}
+
+func (r *failedResponder) EditButtons(string, []messengerapi.Button) error {
+	return r.err
+}
+
+// messengerCollectors joins metrics of messengers, e.g. telebot send queues,
+// which share metric names and differ by messenger label.
+type messengerCollectors []prometheus.Collector
+
+func (c messengerCollectors) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c {
+		collector.Describe(ch)
+	}
+}
+
+func (c messengerCollectors) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c {
+		collector.Collect(ch)
+	}
+}
@@ -1,14 +1,20 @@
 package webhookapp
 
 import (
+	"time"
+
 	"github.com/artarts36/lowbot/logx"
 	"github.com/artarts36/lowbot/metrics"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/dedupe"
+	"github.com/artarts36/lowbot/engine/dispatcher"
 	"github.com/artarts36/lowbot/engine/machine"
 	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/schedule"
 	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/messenger/broadcast"
 )
 
 type config struct {
@@ -20,6 +26,17 @@ type config struct {
 	middlewares             []command.Middleware
 	startCommandFn          func(router.Router) command.Command
 	logger                  logx.Logger
+	webhook                 bool
+	dispatcher              dispatcher.Config
+	conflictPolicy          machine.ConflictPolicy
+	resumeTexts             machine.ResumeTexts
+	cancelCommand           command.Command
+	keyStrategy             state.KeyStrategy
+	scheduleStore           schedule.Store
+	scheduleCheckInterval   time.Duration
+	broadcast               broadcast.Config
+	dedupeStore             dedupe.Store
+	dedupeTTL               time.Duration
 }
 
 type Option func(*config)
@@ -102,3 +119,80 @@ func WithLogger(logger logx.Logger) Option {
 		c.logger = logger
 	}
 }
+
+// WithoutWebhook disables http server with messenger webhook handler.
+// Application listens messenger in foreground, e.g. for telebot.PollingMessenger.
+func WithoutWebhook() Option {
+	return func(c *config) {
+		c.webhook = false
+	}
+}
+
+// WithDispatcher configures workers, which handle messages.
+// Messages of one chat are handled strictly in order, messages of different chats are handled in parallel.
+func WithDispatcher(cfg dispatcher.Config) Option {
+	return func(c *config) {
+		c.dispatcher = cfg
+	}
+}
+
+// WithStateConflictPolicy defines behaviour, when state was changed concurrently.
+// By default, message handling is aborted.
+func WithStateConflictPolicy(policy machine.ConflictPolicy) Option {
+	return func(c *config) {
+		c.conflictPolicy = policy
+	}
+}
+
+// WithResumeTexts sets texts of resuming suspended dialogs, see command.DialogSuspender.
+func WithResumeTexts(texts machine.ResumeTexts) Option {
+	return func(c *config) {
+		c.resumeTexts = texts
+	}
+}
+
+// WithCancelCommand registers command, which aborts active dialog of any command, e.g. router.NewCancelCommand.
+func WithCancelCommand(cmd command.Command) Option {
+	return func(c *config) {
+		c.cancelCommand = cmd
+	}
+}
+
+// WithDialogKey sets state.KeyStrategy, which defines independent dialogs of chat.
+// Use state.KeyByChatSender for holding one dialog per member of group chat. Default: state.KeyByChat.
+func WithDialogKey(strategy state.KeyStrategy) Option {
+	return func(c *config) {
+		c.keyStrategy = strategy
+	}
+}
+
+// WithScheduleStore sets store of actions, scheduled by command.Request Schedule,
+// and deadlines of dialogs by command.Definition IdleTimeout. Default: schedule.NewMemoryStore.
+func WithScheduleStore(store schedule.Store) Option {
+	return func(c *config) {
+		c.scheduleStore = store
+	}
+}
+
+// WithScheduleCheckInterval sets interval of checking scheduled actions and idle dialogs. Interval must be positive.
+func WithScheduleCheckInterval(interval time.Duration) Option {
+	return func(c *config) {
+		c.scheduleCheckInterval = interval
+	}
+}
+
+// WithBroadcast configures rate limits of Application.Broadcast. By default, telegram limits are used.
+func WithBroadcast(cfg broadcast.Config) Option {
+	return func(c *config) {
+		c.broadcast = cfg
+	}
+}
+
+// WithDedupe sets store of received messages for skipping redelivered updates.
+// Nil store disables deduplication. Default: dedupe.NewMemoryStore with ttl 1h.
+func WithDedupe(store dedupe.Store, ttl time.Duration) Option {
+	return func(c *config) {
+		c.dedupeStore = store
+		c.dedupeTTL = ttl
+	}
+}
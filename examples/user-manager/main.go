@@ -2,15 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/artarts36/lowbot/entrypoint/webhookapp"
 
+	"github.com/artarts36/lowbot/messenger/console"
 	"github.com/artarts36/lowbot/messenger/tg-telebot/telebot"
 
 	"github.com/artarts36/lowbot/middleware"
@@ -18,12 +24,17 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/command/form"
+	"github.com/artarts36/lowbot/engine/router"
 	"github.com/artarts36/lowbot/logx"
 	"github.com/artarts36/lowbot/messenger/messengerapi"
 	"github.com/cappuccinotm/slogx"
 )
 
-const readHTTPTimeout = 30 * time.Second
+const (
+	readHTTPTimeout = 30 * time.Second
+	shutdownTimeout = 10 * time.Second
+)
 
 func main() {
 	slog.SetDefault(slog.New(slogx.Accumulator(slogx.NewChain(
@@ -33,16 +44,17 @@ func main() {
 		logx.PropagateMessageID(),
 		logx.PropagateChatID(),
 		logx.PropagateCommandName(),
+		logx.PropagateMessenger(),
 	))))
 
-	msgr, err := createMessenger()
+	msgr, msgrOpts, err := createMessenger()
 	if err != nil {
 		panic(err)
 	}
 
-	app, err := webhookapp.New(
-		msgr,
+	opts := append([]webhookapp.Option{
 		webhookapp.WithCommandSuggestion(),
+		webhookapp.WithCancelCommand(router.NewCancelCommand("cancel")),
 		webhookapp.WithHTTPAddr(":9005"),
 		webhookapp.WithMiddleware(
 			func(ctx context.Context, req *command.Request, next command.ActionCallback) error {
@@ -52,7 +64,9 @@ func main() {
 			middleware.OnlyChatsWithMessage([]string{"493731328"}, "denied"),
 			middleware.PleaseRepeatAgain(),
 		),
-	)
+	}, msgrOpts...)
+
+	app, err := webhookapp.New(msgr, opts...)
 	if err != nil {
 		slog.Error("failed to create application", slog.Any("err", err))
 		os.Exit(1)
@@ -61,6 +75,7 @@ func main() {
 	app.MustAddCommand(&addUserCommand{})
 	app.MustAddCommand(&deleteUserCommand{})
 	app.MustAddCommand(&updateUserCommand{})
+	app.MustAddCommand(&remindCommand{})
 
 	go func() {
 		mux := http.NewServeMux()
@@ -76,16 +91,40 @@ func main() {
 		}
 	}()
 
-	if err = app.Run(); err != nil {
-		slog.Error("failed to run application", slog.Any("err", err))
-		os.Exit(1)
+	go func() {
+		if rerr := app.Run(); rerr != nil && !errors.Is(rerr, http.ErrServerClosed) {
+			slog.Error("failed to run application", slog.Any("err", rerr))
+			os.Exit(1)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err = app.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to shutdown application", slog.Any("err", err))
 	}
 }
 
-func createMessenger() (messengerapi.Messenger, error) {
-	return telebot.NewWebhookMessenger(telebot.WebhookConfig{
+// createMessenger creates telegram messenger or console messenger, when CONSOLE env is set.
+func createMessenger() (messengerapi.Messenger, []webhookapp.Option, error) {
+	if os.Getenv("CONSOLE") != "" {
+		return console.NewMessenger(console.Config{
+			// chat is allowed by middleware.
+			ChatID: "493731328",
+		}, slog.Default()), []webhookapp.Option{webhookapp.WithoutWebhook()}, nil
+	}
+
+	msgr, err := telebot.NewWebhookMessenger(telebot.WebhookConfig{
 		Token: os.Getenv("TELEGRAM_TOKEN"),
 	}, slog.Default())
+
+	return msgr, nil, err
 }
 
 type addUserCommand struct {
@@ -96,11 +135,37 @@ func (addUserCommand) Definition() *command.Definition {
 	return &command.Definition{
 		Name:        "add",
 		Description: "Add user",
+		IdleTimeout: 10 * time.Minute,
 	}
 }
 
+func (addUserCommand) OnTimeout(_ context.Context, req *command.TimeoutRequest) error {
+	_, err := req.Responder.Respond(&messengerapi.Answer{
+		Text: "Your /add session expired",
+	})
+	return err
+}
+
+func (addUserCommand) SuspendOnInterrupt(context.Context, *command.InterruptRequest) (command.ResumeMode, error) {
+	return command.ResumeOffer, nil
+}
+
+func (addUserCommand) OnResume(_ context.Context, req *command.ResumeRequest) error {
+	_, err := req.Responder.Respond(&messengerapi.Answer{
+		Text: "Let's continue adding user",
+	})
+	return err
+}
+
+type user struct {
+	Name   string
+	Email  string
+	Type   string
+	Avatar *messengerapi.Attachment
+}
+
 func (addUserCommand) Actions() *command.Actions {
-	return command.NewActions().
+	actions := command.NewActions().
 		Then("start", func(_ context.Context, req *command.Request) error {
 			file, err := os.Open("./examples/user-manager/image.jpg")
 			if err != nil {
@@ -114,44 +179,42 @@ func (addUserCommand) Actions() *command.Actions {
 				return fmt.Errorf("send image: %w", err)
 			}
 
-			return req.Respond(&messengerapi.Answer{
-				Text: "Enter user name",
-			})
-		}).
-		Then("name", func(_ context.Context, req *command.Request) error {
-			req.State.Set("user.name", req.Message.GetBody())
+			return req.State.Passthrough()
+		})
 
-			return req.Respond(&messengerapi.Answer{
-				Text: "Enter email",
-			})
+	return form.New[user]("user").
+		Text("name", "Enter user name", func(u *user, name string) {
+			u.Name = name
 		}).
-		Then("email", func(_ context.Context, req *command.Request) error {
-			if !strings.Contains(req.Message.GetBody(), "@") {
-				return command.NewInvalidArgumentError("invalid email")
+		Email("email", "Enter email", func(u *user, email string) {
+			u.Email = email
+		}).
+		Enum("type", "Select user type", messengerapi.EnumFromMap(map[string]string{
+			"int": "internal",
+			"ext": "external",
+		}), func(u *user, typ string) {
+			u.Type = typ
+		}).
+		Attachment("avatar", "Send user avatar", messengerapi.AttachmentPhoto, func(u *user, avatar *messengerapi.Attachment) {
+			u.Avatar = avatar
+		}).
+		Submit(func(ctx context.Context, req *command.Request, u *user) error {
+			avatar, err := u.Avatar.Open(ctx)
+			if err != nil {
+				return fmt.Errorf("open avatar: %w", err)
 			}
+			defer avatar.Close()
 
-			req.State.Set("user.email", req.Message.GetBody())
+			size, err := io.Copy(io.Discard, avatar)
+			if err != nil {
+				return fmt.Errorf("download avatar: %w", err)
+			}
 
 			return req.Respond(&messengerapi.Answer{
-				Text: "Select user type",
-				Enum: messengerapi.EnumFromMap(map[string]string{
-					"int": "internal",
-					"ext": "external",
-				}),
+				Text: fmt.Sprintf("name: %s, email: %s, type: %s, avatar: %d bytes", u.Name, u.Email, u.Type, size),
 			})
 		}).
-		Then("type", func(_ context.Context, req *command.Request) error {
-			req.State.Set("user.type", req.Message.GetBody())
-
-			return req.Respond(&messengerapi.Answer{
-				Text: fmt.Sprintf(
-					"name: %s, email: %s, type: %s",
-					req.State.Get("user.name"),
-					req.State.Get("user.email"),
-					req.State.Get("user.type"),
-				),
-			})
-		})
+		AppendTo(actions)
 }
 
 type deleteUserCommand struct {
@@ -160,8 +223,9 @@ type deleteUserCommand struct {
 
 func (deleteUserCommand) Definition() *command.Definition {
 	return &command.Definition{
-		Name:        "delete",
-		Description: "Delete user",
+		Name:           "delete",
+		Description:    "Delete user",
+		ReplacePrompts: true,
 	}
 }
 
@@ -207,15 +271,13 @@ func (deleteUserCommand) Actions() *command.Actions {
 				}),
 			})
 		}).
-		Then("confirming.dispatch", func(_ context.Context, req *command.Request) error {
-			if req.Message.GetBody() == "true" {
-				req.State.Forward("confirmed")
-			} else {
-				req.State.Forward("canceled")
-			}
-
-			return nil
-		})
+		Branch("confirming.dispatch").
+		On("true", "confirmed").
+		On("false", "canceled").
+		// any other answer cancels deletion.
+		Switch(func(context.Context, *command.Request) (string, error) {
+			return "canceled", nil
+		}, "canceled")
 }
 
 type updateUserCommand struct {
@@ -250,9 +312,16 @@ func (updateUserCommand) Actions() *command.Actions {
 			})
 		}).
 		Then("saving_new_email", func(_ context.Context, req *command.Request) error {
+			if !strings.Contains(req.Message.GetBody(), "@") {
+				req.State.Forward("prompt_new_email")
+				return nil
+			}
+
 			req.State.Set("user.email", req.Message.GetBody())
 			return req.State.Passthrough()
 		}).
+		// targets of State.Forward must be declared, otherwise command is rejected by validation.
+		ForwardsTo("prompt_new_email").
 		Then("finished", func(_ context.Context, req *command.Request) error {
 			return req.Respond(&messengerapi.Answer{
 				Text: fmt.Sprintf(
@@ -264,3 +333,47 @@ func (updateUserCommand) Actions() *command.Actions {
 			})
 		})
 }
+
+type remindCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (remindCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "remind",
+		Description: "Remind me later",
+	}
+}
+
+func (remindCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("start", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: "In how many minutes remind you?",
+			})
+		}).
+		Then("scheduling", func(_ context.Context, req *command.Request) error {
+			minutes, err := strconv.Atoi(req.Message.GetBody())
+			if err != nil || minutes <= 0 {
+				return command.NewInvalidArgumentError("Send count of minutes")
+			}
+
+			req.Schedule(time.Now().Add(time.Duration(minutes)*time.Minute), "reminding", nil)
+
+			return req.Respond(&messengerapi.Answer{
+				Text: fmt.Sprintf("I will remind you in %d minutes. Send /cancel to cancel reminder", minutes),
+			})
+		}).
+		Then("waiting", func(_ context.Context, req *command.Request) error {
+			req.State.Transit("waiting")
+
+			return req.Respond(&messengerapi.Answer{
+				Text: "Reminder is waiting",
+			})
+		}).
+		Then("reminding", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: "Reminder!",
+			})
+		})
+}
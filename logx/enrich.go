@@ -11,6 +11,7 @@ type (
 	messageID   struct{}
 	chatID      struct{}
 	commandName struct{}
+	messenger   struct{}
 )
 
 func WithMessageID(ctx context.Context, id string) context.Context {
@@ -52,6 +53,20 @@ func PropagateCommandName() slogx.Middleware {
 	return propagate("command.name", GetCommandName)
 }
 
+// WithMessenger sets name of messenger, which received message, see webhookapp.NewMulti.
+func WithMessenger(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, messenger{}, name)
+}
+
+func GetMessenger(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(messenger{}).(string)
+	return value, ok
+}
+
+func PropagateMessenger() slogx.Middleware {
+	return propagate("messenger.name", GetMessenger)
+}
+
 func propagate(key string, value func(ctx context.Context) (string, bool)) slogx.Middleware {
 	return func(next slogx.HandleFunc) slogx.HandleFunc {
 		return func(ctx context.Context, rec slog.Record) error {
@@ -0,0 +1,166 @@
+// Package lowbottest provides in-memory messenger and conversation assertions
+// for testing commands with real machine.Machine, router and state storage.
+package lowbottest
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/metrics"
+)
+
+// Bot handles messages of fake Messenger by machine.Machine synchronously.
+type Bot struct {
+	router    router.Router
+	storage   state.Storage
+	machine   *machine.Machine
+	messenger *Messenger
+}
+
+type config struct {
+	commands       []command.Command
+	storage        state.Storage
+	schedules      schedule.Store
+	middlewares    []command.Middleware
+	conflictPolicy machine.ConflictPolicy
+	resumeTexts    machine.ResumeTexts
+	keyStrategy    state.KeyStrategy
+	logger         logx.Logger
+}
+
+type Option func(*config)
+
+// WithCommands adds commands to router. Commands are validated by router.
+func WithCommands(cmds ...command.Command) Option {
+	return func(c *config) {
+		c.commands = append(c.commands, cmds...)
+	}
+}
+
+func WithStateStorage(storage state.Storage) Option {
+	return func(c *config) {
+		c.storage = storage
+	}
+}
+
+func WithScheduleStore(store schedule.Store) Option {
+	return func(c *config) {
+		c.schedules = store
+	}
+}
+
+func WithMiddlewares(middlewares ...command.Middleware) Option {
+	return func(c *config) {
+		c.middlewares = append(c.middlewares, middlewares...)
+	}
+}
+
+func WithConflictPolicy(policy machine.ConflictPolicy) Option {
+	return func(c *config) {
+		c.conflictPolicy = policy
+	}
+}
+
+func WithResumeTexts(texts machine.ResumeTexts) Option {
+	return func(c *config) {
+		c.resumeTexts = texts
+	}
+}
+
+func WithDialogKey(strategy state.KeyStrategy) Option {
+	return func(c *config) {
+		c.keyStrategy = strategy
+	}
+}
+
+// WithLogger sets logger of machine. By default, logs are discarded.
+func WithLogger(logger logx.Logger) Option {
+	return func(c *config) {
+		c.logger = logger
+	}
+}
+
+// New creates Bot with start command and commands of WithCommands.
+// Invalid command fails test.
+func New(t testing.TB, opts ...Option) *Bot {
+	t.Helper()
+
+	cfg := &config{
+		storage:        state.NewMemoryStorage(),
+		schedules:      schedule.NewMemoryStore(),
+		conflictPolicy: machine.AbortOnConflict(),
+		resumeTexts:    machine.DefaultResumeTexts(),
+		keyStrategy:    state.KeyByChat,
+		logger:         slog.New(slog.DiscardHandler),
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	bot := &Bot{
+		router:    router.NewMapStaticRouter(),
+		storage:   cfg.storage,
+		messenger: NewMessenger(),
+	}
+
+	cmds := append([]command.Command{router.NewStartCommand("start", bot.router)}, cfg.commands...)
+	for _, cmd := range cmds {
+		if err := bot.router.Add(cmd); err != nil {
+			t.Fatalf("add command %q: %v", cmd.Definition().Name, err)
+		}
+	}
+
+	bot.machine = machine.New(
+		bot.router,
+		cfg.storage,
+		cfg.schedules,
+		machine.NewErrorHandler(cfg.logger),
+		machine.ErrorCommandNotFoundFallback(),
+		cfg.conflictPolicy,
+		cfg.resumeTexts,
+		cfg.keyStrategy,
+		metrics.NewGroup(),
+		command.NewBus(cfg.middlewares),
+		cfg.logger,
+	)
+
+	return bot
+}
+
+// Conversation starts conversation of user with bot in chat. Failed assertions of conversation stop test t.
+func (b *Bot) Conversation(t testing.TB, chatID string) *Conversation {
+	return newConversation(t, b, chatID)
+}
+
+// Handle handles message by machine and returns error of handling.
+func (b *Bot) Handle(ctx context.Context, msg *Message) error {
+	return b.machine.Handle(ctx, &machine.Request{
+		Message:   msg,
+		Responder: b.messenger.CreateThreadResponder(msg.ChatID, msg.ThreadID),
+	})
+}
+
+// RunScheduled runs due scheduled actions and expires idle dialogs, see machine.Machine RunScheduled.
+func (b *Bot) RunScheduled(ctx context.Context) {
+	b.machine.RunScheduled(ctx, b.messenger.CreateThreadResponder, nil)
+}
+
+func (b *Bot) Machine() *machine.Machine {
+	return b.machine
+}
+
+func (b *Bot) Messenger() *Messenger {
+	return b.messenger
+}
+
+func (b *Bot) Storage() state.Storage {
+	return b.storage
+}
@@ -0,0 +1,321 @@
+package lowbottest
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// Conversation sends messages of user to Bot and asserts replies in order of sending.
+// Failed assertion stops test by Fatalf.
+//
+//	bot.Conversation(t, "chat-1").
+//		Send("/add").
+//		ExpectText("Enter user name").
+//		Send("bob").
+//		ExpectText("Select user type").
+//		Press("internal")
+type Conversation struct {
+	t        testing.TB
+	bot      *Bot
+	chatID   string
+	threadID string
+	sender   *messengerapi.Sender
+
+	// cursor is index of first not expected reply.
+	cursor int
+	// last is last expected reply.
+	last *Reply
+	err  error
+}
+
+func newConversation(t testing.TB, bot *Bot, chatID string) *Conversation {
+	return &Conversation{
+		t:      t,
+		bot:    bot,
+		chatID: chatID,
+		sender: &messengerapi.Sender{
+			ID:       chatID,
+			Username: "user_" + chatID,
+		},
+	}
+}
+
+// As sets sender of next messages.
+func (c *Conversation) As(sender *messengerapi.Sender) *Conversation {
+	c.sender = sender
+	return c
+}
+
+// InThread sends next messages to thread of chat. Replies are expected in thread.
+func (c *Conversation) InThread(threadID string) *Conversation {
+	c.threadID = threadID
+	return c
+}
+
+// Send sends text, e.g. command "/add" or answer to question.
+func (c *Conversation) Send(text string) *Conversation {
+	return c.SendMessage(&Message{Text: text})
+}
+
+// SendAttachment sends attachment with caption. See Messenger.Upload.
+func (c *Conversation) SendAttachment(attachment *messengerapi.Attachment, caption string) *Conversation {
+	return c.SendMessage(&Message{
+		Text:        caption,
+		Attachments: []*messengerapi.Attachment{attachment},
+	})
+}
+
+// SendMessage sends message. Empty ID, ChatID, ThreadID and Sender are filled by conversation.
+func (c *Conversation) SendMessage(msg *Message) *Conversation {
+	if msg.ID == "" {
+		msg.ID = c.bot.messenger.messageID()
+	}
+
+	if msg.ChatID == "" {
+		msg.ChatID = c.chatID
+	}
+
+	if msg.ThreadID == "" {
+		msg.ThreadID = c.threadID
+	}
+
+	if msg.Sender == nil {
+		msg.Sender = c.sender
+	}
+
+	c.err = c.bot.Handle(context.Background(), msg)
+
+	return c
+}
+
+// Press presses button with title of last sent message, which contains it:
+// CommandButton sends Args, Enum item sends value, Menu item sends title as text.
+func (c *Conversation) Press(title string) *Conversation {
+	c.t.Helper()
+
+	replies := c.replies()
+
+	for i := len(replies) - 1; i >= 0; i-- {
+		reply := replies[i]
+		if reply.Deleted || reply.Answer == nil || c.replaced(replies, i) {
+			continue
+		}
+
+		if msg := c.press(reply.Answer, title); msg != nil {
+			return c.SendMessage(msg)
+		}
+	}
+
+	c.t.Fatalf("button %q not found in messages of chat %q", title, c.chatID)
+
+	return c
+}
+
+// ExpectText asserts that next reply is Answer with text.
+func (c *Conversation) ExpectText(text string) *Conversation {
+	c.t.Helper()
+
+	reply := c.next()
+	if reply.Answer == nil {
+		c.t.Fatalf("expected answer %q, got object %T", text, reply.Object)
+	}
+
+	if reply.Answer.Text != text {
+		c.t.Fatalf("expected answer %q, got %q", text, reply.Answer.Text)
+	}
+
+	return c
+}
+
+// ExpectTextContains asserts that next reply is Answer, which text contains substr.
+func (c *Conversation) ExpectTextContains(substr string) *Conversation {
+	c.t.Helper()
+
+	reply := c.next()
+	if reply.Answer == nil {
+		c.t.Fatalf("expected answer containing %q, got object %T", substr, reply.Object)
+	}
+
+	if !strings.Contains(reply.Answer.Text, substr) {
+		c.t.Fatalf("expected answer containing %q, got %q", substr, reply.Answer.Text)
+	}
+
+	return c
+}
+
+// ExpectObject asserts that next reply is Object.
+func (c *Conversation) ExpectObject() *Conversation {
+	c.t.Helper()
+
+	reply := c.next()
+	if reply.Object == nil {
+		c.t.Fatalf("expected object, got answer %q", reply.Answer.Text)
+	}
+
+	return c
+}
+
+// ExpectReply passes next reply to assert.
+func (c *Conversation) ExpectReply(assert func(reply *Reply)) *Conversation {
+	c.t.Helper()
+
+	assert(c.next())
+
+	return c
+}
+
+// ExpectButtons asserts titles of Buttons, Enum and Menu of last expected reply.
+func (c *Conversation) ExpectButtons(titles ...string) *Conversation {
+	c.t.Helper()
+
+	if c.last == nil || c.last.Answer == nil {
+		c.t.Fatalf("expected buttons %q, but answer was not expected", titles)
+	}
+
+	got := buttonTitles(c.last.Answer)
+	if !slices.Equal(got, titles) {
+		c.t.Fatalf("expected buttons %q, got %q", titles, got)
+	}
+
+	return c
+}
+
+// ExpectNoReply asserts that bot did not send other replies.
+func (c *Conversation) ExpectNoReply() *Conversation {
+	c.t.Helper()
+
+	replies := c.replies()
+	if len(replies) > c.cursor {
+		c.t.Fatalf("expected no reply, got %d replies, first: %s", len(replies)-c.cursor, describe(replies[c.cursor]))
+	}
+
+	return c
+}
+
+// ExpectError asserts that handling of last message failed with target error, see errors.Is.
+func (c *Conversation) ExpectError(target error) *Conversation {
+	c.t.Helper()
+
+	if !errors.Is(c.err, target) {
+		c.t.Fatalf("expected error %v, got %v", target, c.err)
+	}
+
+	return c
+}
+
+// ExpectNoError asserts that last message handled without error.
+func (c *Conversation) ExpectNoError() *Conversation {
+	c.t.Helper()
+
+	if c.err != nil {
+		c.t.Fatalf("expected no error, got %v", c.err)
+	}
+
+	return c
+}
+
+// Err returns error of handling of last message.
+func (c *Conversation) Err() error {
+	return c.err
+}
+
+func (c *Conversation) next() *Reply {
+	c.t.Helper()
+
+	replies := c.replies()
+	if len(replies) <= c.cursor {
+		c.t.Fatalf("expected reply, got no reply")
+	}
+
+	c.last = replies[c.cursor]
+	c.cursor++
+
+	return c.last
+}
+
+// replies returns replies to chat in thread of conversation.
+func (c *Conversation) replies() []*Reply {
+	replies := make([]*Reply, 0)
+	for _, reply := range c.bot.messenger.Replies(c.chatID) {
+		if reply.ThreadID == c.threadID {
+			replies = append(replies, reply)
+		}
+	}
+
+	return replies
+}
+
+// replaced reports whether reply i was edited later.
+func (c *Conversation) replaced(replies []*Reply, i int) bool {
+	for _, reply := range replies[i+1:] {
+		if reply.ID == replies[i].ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Conversation) press(answer *messengerapi.Answer, title string) *Message {
+	for _, button := range answer.Buttons {
+		cmdButton, ok := button.(*messengerapi.CommandButton)
+		if !ok || cmdButton.Title != title {
+			continue
+		}
+
+		return &Message{
+			Text: answer.Text,
+			Args: &messengerapi.Args{
+				CommandName: cmdButton.Args.CommandName,
+				StateName:   cmdButton.Args.StateName,
+				Data:        maps.Clone(cmdButton.Args.Data),
+			},
+			Callback: true,
+		}
+	}
+
+	for _, item := range answer.Enum.Values {
+		if item.Title == title {
+			return &Message{
+				Text:     item.Value,
+				Callback: true,
+			}
+		}
+	}
+
+	if slices.Contains(answer.Menu, title) {
+		return &Message{
+			Text: title,
+		}
+	}
+
+	return nil
+}
+
+func buttonTitles(answer *messengerapi.Answer) []string {
+	titles := make([]string, 0, len(answer.Buttons)+len(answer.Enum.Values)+len(answer.Menu))
+
+	for _, button := range answer.Buttons {
+		titles = append(titles, button.GetTitle())
+	}
+
+	for _, item := range answer.Enum.Values {
+		titles = append(titles, item.Title)
+	}
+
+	return append(titles, answer.Menu...)
+}
+
+func describe(reply *Reply) string {
+	if reply.Answer == nil {
+		return "object"
+	}
+
+	return "answer " + strings.TrimSpace(reply.Answer.Text)
+}
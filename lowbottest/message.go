@@ -0,0 +1,64 @@
+package lowbottest
+
+import (
+	"strings"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// Message is message of fake messenger. Fields are sent to machine as is.
+type Message struct {
+	ID       string
+	ChatID   string
+	ThreadID string
+	Text     string
+	Sender   *messengerapi.Sender
+
+	Args        *messengerapi.Args
+	Attachments []*messengerapi.Attachment
+	// Callback is true, when message created by pressing button.
+	Callback bool
+}
+
+func (m *Message) GetID() string {
+	return m.ID
+}
+
+func (m *Message) GetChatID() string {
+	return m.ChatID
+}
+
+func (m *Message) GetThreadID() string {
+	return m.ThreadID
+}
+
+func (m *Message) GetBody() string {
+	return m.Text
+}
+
+func (m *Message) GetSender() *messengerapi.Sender {
+	if m.Sender == nil {
+		return &messengerapi.Sender{}
+	}
+
+	return m.Sender
+}
+
+func (m *Message) ExtractCommandName() string {
+	if strings.HasPrefix(m.Text, "/") {
+		return strings.TrimSpace(m.Text[1:])
+	}
+	return ""
+}
+
+func (m *Message) IsCallback() bool {
+	return m.Callback
+}
+
+func (m *Message) GetArgs() *messengerapi.Args {
+	return m.Args
+}
+
+func (m *Message) GetAttachments() []*messengerapi.Attachment {
+	return m.Attachments
+}
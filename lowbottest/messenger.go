@@ -0,0 +1,208 @@
+package lowbottest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var (
+	_ messengerapi.Messenger       = &Messenger{}
+	_ messengerapi.ThreadMessenger = &Messenger{}
+)
+
+var errMessageNotFound = errors.New("message not found")
+
+// Reply is message, which bot sent to chat. Edit of message is recorded as new Reply with same ID.
+type Reply struct {
+	ID     string
+	ChatID string
+	// ThreadID is thread of chat, to which reply sent, or empty string.
+	ThreadID string
+
+	// Answer is nil, when bot sent Object.
+	Answer *messengerapi.Answer
+	Object messengerapi.Object
+
+	Edited  bool
+	Deleted bool
+}
+
+// Messenger is in-memory messengerapi.Messenger, which records replies of bot.
+// Messages are sent by Push, when Messenger used with application, or handled directly by Bot.
+type Messenger struct {
+	mu      sync.Mutex
+	replies []*Reply
+	files   map[string][]byte
+	lastID  int
+
+	ch     chan messengerapi.Message
+	closed chan struct{}
+	once   sync.Once
+}
+
+func NewMessenger() *Messenger {
+	return &Messenger{
+		replies: make([]*Reply, 0),
+		files:   make(map[string][]byte),
+		closed:  make(chan struct{}),
+	}
+}
+
+// ServeHTTP responds 404: fake messenger does not receive webhooks.
+func (m *Messenger) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	http.NotFound(w, req)
+}
+
+// Listen blocks until Close. Messages are received by Push.
+func (m *Messenger) Listen(ch chan messengerapi.Message) error {
+	m.mu.Lock()
+	m.ch = ch
+	m.mu.Unlock()
+
+	<-m.closed
+
+	return nil
+}
+
+func (m *Messenger) Close() error {
+	m.once.Do(func() {
+		close(m.closed)
+	})
+
+	return nil
+}
+
+// Push sends message to channel of Listen.
+func (m *Messenger) Push(msg messengerapi.Message) error {
+	m.mu.Lock()
+	ch := m.ch
+	m.mu.Unlock()
+
+	if ch == nil {
+		return errors.New("messenger is not listening")
+	}
+
+	select {
+	case ch <- msg:
+		return nil
+	case <-m.closed:
+		return errors.New("messenger closed")
+	}
+}
+
+func (m *Messenger) CreateResponder(chatID string) messengerapi.Responder {
+	return &Responder{
+		chatID:    chatID,
+		messenger: m,
+	}
+}
+
+// CreateThreadResponder creates Responder, which records replies to thread of chat.
+func (m *Messenger) CreateThreadResponder(chatID, threadID string) messengerapi.Responder {
+	return &Responder{
+		chatID:    chatID,
+		threadID:  threadID,
+		messenger: m,
+	}
+}
+
+// Replies returns replies of bot to chat in order of sending.
+func (m *Messenger) Replies(chatID string) []*Reply {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	replies := make([]*Reply, 0)
+	for _, reply := range m.replies {
+		if reply.ChatID == chatID {
+			replies = append(replies, reply)
+		}
+	}
+
+	return replies
+}
+
+// Upload stores file, which can be downloaded by Attachment.Open, and returns attachment for Message.
+func (m *Messenger) Upload(kind messengerapi.AttachmentKind, name string, content []byte) *messengerapi.Attachment {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextID()
+	m.files[id] = content
+
+	return &messengerapi.Attachment{
+		Kind:     kind,
+		FileID:   id,
+		FileName: name,
+		Size:     int64(len(content)),
+		Fetcher:  m,
+	}
+}
+
+// Fetch implements messengerapi.FileFetcher for files of Upload.
+func (m *Messenger) Fetch(_ context.Context, fileID string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	content, ok := m.files[fileID]
+	if !ok {
+		return nil, fmt.Errorf("file %q: %w", fileID, messengerapi.ErrNoFile)
+	}
+
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (m *Messenger) record(reply *Reply) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if reply.ID == "" {
+		reply.ID = m.nextID()
+	}
+
+	m.replies = append(m.replies, reply)
+
+	var text string
+	if reply.Answer != nil {
+		text = reply.Answer.Text
+	}
+
+	return &Message{
+		ID:       reply.ID,
+		ChatID:   reply.ChatID,
+		ThreadID: reply.ThreadID,
+		Text:     text,
+	}
+}
+
+// last returns last version of sent message.
+func (m *Messenger) last(chatID, msgID string) (*Reply, error) {
+	for i := len(m.replies) - 1; i >= 0; i-- {
+		if m.replies[i].ChatID == chatID && m.replies[i].ID == msgID {
+			return m.replies[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("message %q: %w", msgID, errMessageNotFound)
+}
+
+// messageID returns id for message of user.
+func (m *Messenger) messageID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.nextID()
+}
+
+func (m *Messenger) nextID() string {
+	m.lastID++
+
+	return strconv.Itoa(m.lastID)
+}
@@ -0,0 +1,105 @@
+package lowbottest
+
+import (
+	"context"
+	"io"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// Responder records replies to chat or thread of chat in Messenger.
+type Responder struct {
+	chatID    string
+	threadID  string
+	messenger *Messenger
+}
+
+func (r *Responder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
+	return r.messenger.record(&Reply{
+		ChatID:   r.chatID,
+		ThreadID: r.threadID,
+		Answer:   answer,
+	}), nil
+}
+
+func (r *Responder) RespondObject(object messengerapi.Object) (messengerapi.Message, error) {
+	if group, ok := object.(*messengerapi.MediaGroup); ok {
+		if err := group.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.messenger.record(&Reply{
+		ChatID:   r.chatID,
+		ThreadID: r.threadID,
+		Object:   object,
+	}), nil
+}
+
+func (r *Responder) Edit(msgID string, answer *messengerapi.Answer) (messengerapi.Message, error) {
+	r.messenger.mu.Lock()
+	last, err := r.messenger.last(r.chatID, msgID)
+	r.messenger.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.messenger.record(&Reply{
+		ID:       msgID,
+		ChatID:   r.chatID,
+		ThreadID: last.ThreadID,
+		Answer:   answer,
+		Edited:   true,
+	}), nil
+}
+
+func (r *Responder) Delete(msgID string) error {
+	r.messenger.mu.Lock()
+	defer r.messenger.mu.Unlock()
+
+	if _, err := r.messenger.last(r.chatID, msgID); err != nil {
+		return err
+	}
+
+	for _, reply := range r.messenger.replies {
+		if reply.ChatID == r.chatID && reply.ID == msgID {
+			reply.Deleted = true
+		}
+	}
+
+	return nil
+}
+
+func (r *Responder) EditButtons(msgID string, buttons []messengerapi.Button) error {
+	r.messenger.mu.Lock()
+	last, err := r.messenger.last(r.chatID, msgID)
+	r.messenger.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	answer := &messengerapi.Answer{
+		Buttons: buttons,
+	}
+	if last.Answer != nil {
+		answer.Text = last.Answer.Text
+		answer.Menu = last.Answer.Menu
+	}
+
+	r.messenger.record(&Reply{
+		ID:       msgID,
+		ChatID:   r.chatID,
+		ThreadID: last.ThreadID,
+		Answer:   answer,
+		Edited:   true,
+	})
+
+	return nil
+}
+
+// Fetch downloads file, uploaded by Messenger.Upload.
+func (r *Responder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return r.messenger.Fetch(ctx, fileID)
+}
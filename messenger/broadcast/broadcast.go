@@ -0,0 +1,150 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	// defaultRate is telegram limit of messages per second for all chats.
+	defaultRate = 30
+	// defaultChatInterval is telegram limit of messages for one chat.
+	defaultChatInterval = time.Second
+
+	// maxTrackedChats is size of last sending times, after which outdated times are cleaned.
+	maxTrackedChats = 10000
+)
+
+type Config struct {
+	// Rate is max count of messages per second for all chats. Default: 30.
+	Rate int
+	// ChatInterval is min interval between messages to one chat. Default: 1s.
+	ChatInterval time.Duration
+}
+
+type ResponderFactory func(chatID string) messengerapi.Responder
+
+// Broadcaster sends message to many chats with rate limits.
+// Limits are shared between concurrent Send calls.
+type Broadcaster struct {
+	cfg             Config
+	createResponder ResponderFactory
+
+	mu       sync.Mutex
+	next     time.Time
+	lastSent map[string]time.Time
+}
+
+// SendError contains errors of failed chats.
+type SendError struct {
+	Failed map[string]error
+}
+
+func New(cfg Config, createResponder ResponderFactory) *Broadcaster {
+	if cfg.Rate <= 0 {
+		cfg.Rate = defaultRate
+	}
+	if cfg.ChatInterval <= 0 {
+		cfg.ChatInterval = defaultChatInterval
+	}
+
+	return &Broadcaster{
+		cfg:             cfg,
+		createResponder: createResponder,
+		lastSent:        map[string]time.Time{},
+	}
+}
+
+// Send sends answer to chats. Blocks until answer sent to all chats or ctx done.
+// Throws *SendError, when answer not sent to some chats.
+func (b *Broadcaster) Send(ctx context.Context, chatIDs []string, answer *messengerapi.Answer) error {
+	failed := map[string]error{}
+
+	for i, chatID := range chatIDs {
+		if err := b.wait(ctx, chatID); err != nil {
+			for _, skipped := range chatIDs[i:] {
+				failed[skipped] = err
+			}
+
+			break
+		}
+
+		responder := messengerapi.ResponderWithContext(ctx, b.createResponder(chatID))
+		if _, err := responder.Respond(answer); err != nil {
+			failed[chatID] = err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &SendError{Failed: failed}
+	}
+
+	return nil
+}
+
+// wait waits for free slot of global and chat limits.
+func (b *Broadcaster) wait(ctx context.Context, chatID string) error {
+	delay := b.reserve(chatID)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve reserves time of sending to chat and returns delay before sending.
+func (b *Broadcaster) reserve(chatID string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+
+	at := now
+	if b.next.After(at) {
+		at = b.next
+	}
+	if chatAt := b.lastSent[chatID].Add(b.cfg.ChatInterval); chatAt.After(at) {
+		at = chatAt
+	}
+
+	b.next = at.Add(time.Second / time.Duration(b.cfg.Rate))
+	b.lastSent[chatID] = at
+
+	if len(b.lastSent) > maxTrackedChats {
+		b.clean(now)
+	}
+
+	return at.Sub(now)
+}
+
+func (b *Broadcaster) clean(now time.Time) {
+	for chatID, sentAt := range b.lastSent {
+		if sentAt.Add(b.cfg.ChatInterval).Before(now) {
+			delete(b.lastSent, chatID)
+		}
+	}
+}
+
+func (e *SendError) Error() string {
+	chatIDs := make([]string, 0, len(e.Failed))
+	for chatID := range e.Failed {
+		chatIDs = append(chatIDs, chatID)
+	}
+	sort.Strings(chatIDs)
+
+	return fmt.Sprintf("failed to send message to %d chats: %s", len(chatIDs), strings.Join(chatIDs, ", "))
+}
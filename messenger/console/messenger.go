@@ -0,0 +1,303 @@
+// Package console provides messenger, which chats with bot in terminal: reads messages from stdin
+// and prints answers to stdout. Useful for local development without messenger token.
+package console
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	defaultChatID = "console"
+
+	// attachPrefix starts line, which sends file: "!photo ./avatar.jpg caption".
+	attachPrefix = "!"
+)
+
+var _ messengerapi.Messenger = &Messenger{}
+
+// Messenger reads lines of In as messages of one chat and prints answers to Out.
+// Menu, Enum and Buttons are printed as numbered choices, which picked by sending number.
+//
+// Messenger does not receive webhooks, so use it with webhookapp.WithoutWebhook:
+//
+//	app, err := webhookapp.New(console.NewMessenger(console.Config{}, logger), webhookapp.WithoutWebhook())
+type Messenger struct {
+	cfg    Config
+	logger logx.Logger
+
+	mu      sync.Mutex
+	choices []choice
+	lastID  int
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type Config struct {
+	// In is source of messages. Default: os.Stdin.
+	In io.Reader
+	// Out is destination of answers. Default: os.Stdout.
+	Out io.Writer
+
+	// ChatID of console chat. Default: "console".
+	ChatID string
+	// Sender of messages. Default: user with ChatID.
+	Sender *messengerapi.Sender
+
+	// FilesDir is directory, where sent objects are saved. Default: new temporary directory.
+	FilesDir string
+}
+
+// choice is pickable item of Menu, Enum or Buttons.
+type choice struct {
+	title    string
+	text     string
+	args     *messengerapi.Args
+	callback bool
+}
+
+func NewMessenger(cfg Config, logger logx.Logger) *Messenger {
+	if cfg.In == nil {
+		cfg.In = os.Stdin
+	}
+
+	if cfg.Out == nil {
+		cfg.Out = os.Stdout
+	}
+
+	if cfg.ChatID == "" {
+		cfg.ChatID = defaultChatID
+	}
+
+	if cfg.Sender == nil {
+		cfg.Sender = &messengerapi.Sender{
+			ID:       cfg.ChatID,
+			Username: cfg.ChatID,
+		}
+	}
+
+	return &Messenger{
+		cfg:    cfg,
+		logger: logger,
+		closed: make(chan struct{}),
+	}
+}
+
+// ServeHTTP always responds 404, because console Messenger does not receive updates by HTTP.
+func (m *Messenger) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// Listen reads lines of In until EOF or Close.
+func (m *Messenger) Listen(ch chan messengerapi.Message) error {
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(m.cfg.In)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-m.closed:
+				return
+			}
+		}
+
+		readErr <- scanner.Err()
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				select {
+				case err := <-readErr:
+					return err
+				default:
+					return nil
+				}
+			}
+
+			if msg := m.parse(line); msg != nil {
+				ch <- msg
+			}
+		case <-m.closed:
+			return nil
+		}
+	}
+}
+
+func (m *Messenger) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+	})
+
+	return nil
+}
+
+func (m *Messenger) CreateResponder(chatID string) messengerapi.Responder {
+	return &responder{
+		chatID:    chatID,
+		messenger: m,
+	}
+}
+
+// parse converts line to message: number of choice, file or text.
+func (m *Messenger) parse(line string) *message {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil
+	}
+
+	msg := &message{
+		id:     m.nextID(),
+		chatID: m.cfg.ChatID,
+		text:   line,
+		sender: m.cfg.Sender,
+	}
+
+	if picked, ok := m.pick(line); ok {
+		msg.text = picked.text
+		msg.args = picked.args
+		msg.callback = picked.callback
+
+		return msg
+	}
+
+	if strings.HasPrefix(line, attachPrefix) {
+		attachment, caption, err := m.attachment(strings.TrimPrefix(line, attachPrefix))
+		if err != nil {
+			m.print(fmt.Sprintf("[console] %s", err))
+			return nil
+		}
+
+		msg.text = caption
+		msg.attachments = []*messengerapi.Attachment{attachment}
+	}
+
+	return msg
+}
+
+// pick returns choice by its number.
+func (m *Messenger) pick(line string) (choice, bool) {
+	number, err := strconv.Atoi(line)
+	if err != nil {
+		return choice{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if number < 1 || number > len(m.choices) {
+		return choice{}, false
+	}
+
+	return m.choices[number-1], true
+}
+
+// attachment parses "<kind> <path> [caption]". File is read lazily by Fetch.
+func (m *Messenger) attachment(value string) (*messengerapi.Attachment, string, error) {
+	const usage = "usage: !<photo|document|video|audio|voice|animation|sticker> <path> [caption]"
+
+	parts := strings.SplitN(value, " ", 3)
+	if len(parts) < 2 {
+		return nil, "", errors.New(usage)
+	}
+
+	kind := messengerapi.AttachmentKind(parts[0])
+	switch kind {
+	case messengerapi.AttachmentPhoto, messengerapi.AttachmentDocument, messengerapi.AttachmentVideo,
+		messengerapi.AttachmentAudio, messengerapi.AttachmentVoice, messengerapi.AttachmentAnimation,
+		messengerapi.AttachmentSticker:
+	default:
+		return nil, "", fmt.Errorf("unsupported attachment %q, %s", kind, usage)
+	}
+
+	path, err := filepath.Abs(parts[1])
+	if err != nil {
+		return nil, "", fmt.Errorf("resolve path: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("stat file: %w", err)
+	}
+
+	var caption string
+	if len(parts) == 3 {
+		caption = parts[2]
+	}
+
+	return &messengerapi.Attachment{
+		Kind:     kind,
+		FileID:   path,
+		FileName: info.Name(),
+		Size:     info.Size(),
+		Fetcher:  m,
+	}, caption, nil
+}
+
+// Fetch opens file, which sent by console user. File id is absolute path of file.
+func (m *Messenger) Fetch(_ context.Context, fileID string) (io.ReadCloser, error) {
+	return os.Open(fileID)
+}
+
+// setChoices replaces choices by items of last answer. Empty choices clear choices of previous answer.
+func (m *Messenger) setChoices(choices []choice) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.choices = choices
+}
+
+// filesDir returns directory of sent objects and creates it on first call.
+func (m *Messenger) filesDir() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cfg.FilesDir != "" {
+		return m.cfg.FilesDir, os.MkdirAll(m.cfg.FilesDir, 0o755)
+	}
+
+	dir, err := os.MkdirTemp("", "lowbot-console-")
+	if err != nil {
+		return "", fmt.Errorf("create files dir: %w", err)
+	}
+
+	m.cfg.FilesDir = dir
+
+	return dir, nil
+}
+
+func (m *Messenger) print(text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := fmt.Fprintln(m.cfg.Out, text); err != nil {
+		m.logger.ErrorContext(context.Background(), "[lowbot][console] failed to print", logx.Err(err))
+	}
+}
+
+func (m *Messenger) nextID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+
+	return strconv.Itoa(m.lastID)
+}
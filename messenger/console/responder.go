@@ -0,0 +1,224 @@
+package console
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type responder struct {
+	chatID    string
+	messenger *Messenger
+}
+
+// Fetch opens file, which sent by console user.
+func (r *responder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return r.messenger.Fetch(ctx, fileID)
+}
+
+func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
+	id := r.messenger.nextID()
+
+	// choices of previous answer are stale, so answer without choices clears them.
+	r.messenger.setChoices(r.printAnswer(fmt.Sprintf("[bot #%s]", id), answer))
+
+	return r.sent(id, answer.Text), nil
+}
+
+func (r *responder) RespondObject(object messengerapi.Object) (messengerapi.Message, error) {
+	id := r.messenger.nextID()
+
+	lines, err := r.describe(id, object)
+	if err != nil {
+		return nil, err
+	}
+
+	r.messenger.print(fmt.Sprintf("[bot #%s] %s", id, strings.Join(lines, "\n")))
+
+	return r.sent(id, ""), nil
+}
+
+func (r *responder) Edit(msgID string, answer *messengerapi.Answer) (messengerapi.Message, error) {
+	r.messenger.setChoices(r.printAnswer(fmt.Sprintf("[bot #%s edited]", msgID), answer))
+
+	return r.sent(msgID, answer.Text), nil
+}
+
+func (r *responder) Delete(msgID string) error {
+	r.messenger.print(fmt.Sprintf("[bot #%s deleted]", msgID))
+
+	return nil
+}
+
+func (r *responder) EditButtons(msgID string, buttons []messengerapi.Button) error {
+	// empty buttons remove choices of message.
+	r.messenger.setChoices(r.printAnswer(fmt.Sprintf("[bot #%s buttons]", msgID), &messengerapi.Answer{
+		Buttons: buttons,
+	}))
+
+	return nil
+}
+
+// printAnswer prints text and numbered choices of answer and returns choices.
+func (r *responder) printAnswer(header string, answer *messengerapi.Answer) []choice {
+	choices := make([]choice, 0, len(answer.Menu)+len(answer.Enum.Values)+len(answer.Buttons))
+
+	for _, item := range answer.Menu {
+		choices = append(choices, choice{
+			title: item,
+			text:  item,
+		})
+	}
+
+	for _, item := range answer.Enum.Values {
+		choices = append(choices, choice{
+			title:    item.Title,
+			text:     item.Value,
+			callback: true,
+		})
+	}
+
+	for _, button := range answer.Buttons {
+		cmdButton, ok := button.(*messengerapi.CommandButton)
+		if !ok {
+			continue
+		}
+
+		choices = append(choices, choice{
+			title: cmdButton.Title,
+			text:  answer.Text,
+			args: &messengerapi.Args{
+				CommandName: cmdButton.Args.CommandName,
+				StateName:   cmdButton.Args.StateName,
+				Data:        cmdButton.Args.Data,
+			},
+			callback: true,
+		})
+	}
+
+	var out strings.Builder
+
+	out.WriteString(header)
+	if answer.Text != "" {
+		out.WriteString(" ")
+		out.WriteString(answer.Text)
+	}
+
+	for i, item := range choices {
+		fmt.Fprintf(&out, "\n  %d) %s", i+1, item.title)
+	}
+
+	r.messenger.print(out.String())
+
+	return choices
+}
+
+// describe saves files of object and returns description of object.
+func (r *responder) describe(id string, object messengerapi.Object) ([]string, error) {
+	switch obj := object.(type) {
+	case *messengerapi.LocalImage:
+		return r.describeFile(id, "image", messengerapi.File{Reader: obj.Reader}, "")
+	case *messengerapi.Photo:
+		return r.describeFile(id, "photo", obj.File, obj.Caption)
+	case *messengerapi.Document:
+		return r.describeFile(id, "document", obj.File, obj.Caption)
+	case *messengerapi.Video:
+		return r.describeFile(id, "video", obj.File, obj.Caption)
+	case *messengerapi.Audio:
+		return r.describeFile(id, "audio", obj.File, obj.Caption)
+	case *messengerapi.Voice:
+		return r.describeFile(id, "voice", obj.File, obj.Caption)
+	case *messengerapi.Animation:
+		return r.describeFile(id, "animation", obj.File, obj.Caption)
+	case *messengerapi.Sticker:
+		return r.describeFile(id, "sticker", obj.File, "")
+	case *messengerapi.Location:
+		return []string{fmt.Sprintf("location %f, %f", obj.Latitude, obj.Longitude)}, nil
+	case *messengerapi.MediaGroup:
+		if err := obj.Validate(); err != nil {
+			return nil, err
+		}
+
+		lines := make([]string, 0, len(obj.Items))
+
+		for i, item := range obj.Items {
+			itemLines, err := r.describe(fmt.Sprintf("%s-%d", id, i+1), item)
+			if err != nil {
+				return nil, err
+			}
+
+			lines = append(lines, itemLines...)
+		}
+
+		return lines, nil
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", object)
+	}
+}
+
+func (r *responder) describeFile(id, kind string, file messengerapi.File, caption string) ([]string, error) {
+	var source string
+
+	switch {
+	case file.Reader != nil:
+		path, err := r.save(id, kind, file)
+		if err != nil {
+			return nil, fmt.Errorf("save %s: %w", kind, err)
+		}
+
+		source = "saved to " + path
+	case file.URL != "":
+		source = "from " + file.URL
+	case file.ID != "":
+		source = "with id " + file.ID
+	default:
+		return nil, fmt.Errorf("%s has no file source", kind)
+	}
+
+	line := fmt.Sprintf("%s %s", kind, source)
+	if caption != "" {
+		line += ": " + caption
+	}
+
+	return []string{line}, nil
+}
+
+func (r *responder) save(id, kind string, file messengerapi.File) (string, error) {
+	dir, err := r.messenger.filesDir()
+	if err != nil {
+		return "", err
+	}
+
+	name := file.Name
+	if name == "" {
+		name = kind
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s", id, filepath.Base(name)))
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err = io.Copy(f, file.Reader); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+func (r *responder) sent(id, text string) *message {
+	return &message{
+		id:     id,
+		chatID: r.chatID,
+		text:   text,
+		sender: &messengerapi.Sender{},
+	}
+}
@@ -0,0 +1,86 @@
+package httpjson
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// buttonStore keeps args of sent command buttons. Frontend receives only id of button,
+// so client can not move dialog to arbitrary state by forged args.
+type buttonStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	buttons map[string]*storedButton
+	sweptAt time.Time
+}
+
+type storedButton struct {
+	chatID    string
+	args      *messengerapi.Args
+	createdAt time.Time
+}
+
+func newButtonStore(ttl time.Duration) *buttonStore {
+	return &buttonStore{
+		ttl:     ttl,
+		buttons: make(map[string]*storedButton),
+	}
+}
+
+// bind stores args of button, sent to chat, and returns id of button.
+func (s *buttonStore) bind(chatID string, args messengerapi.Args) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	id := uuid.NewString()
+	s.buttons[id] = &storedButton{
+		chatID:    chatID,
+		args:      &args,
+		createdAt: now,
+	}
+
+	return id
+}
+
+// take returns and removes args of pressed button.
+// Returns false, when button is unknown, expired or sent to another chat.
+func (s *buttonStore) take(chatID, id string) (*messengerapi.Args, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	button, ok := s.buttons[id]
+	if !ok || button.chatID != chatID {
+		return nil, false
+	}
+
+	delete(s.buttons, id)
+
+	if time.Since(button.createdAt) >= s.ttl {
+		return nil, false
+	}
+
+	return button.args, true
+}
+
+// sweep removes buttons, which are not pressed until ttl. Caller must hold lock.
+func (s *buttonStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < s.ttl {
+		return
+	}
+
+	s.sweptAt = now
+
+	for id, button := range s.buttons {
+		if now.Sub(button.createdAt) >= s.ttl {
+			delete(s.buttons, id)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package httpjson
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type EventType string
+
+const (
+	// EventMessage is new message with Answer or Object.
+	EventMessage EventType = "message"
+	// EventEdit replaces Answer of sent message.
+	EventEdit EventType = "edit"
+	// EventDelete deletes sent message.
+	EventDelete EventType = "delete"
+	// EventButtons replaces buttons of sent message. Buttons are removed, when list is empty.
+	EventButtons EventType = "buttons"
+)
+
+// Event is reply of bot to chat.
+type Event struct {
+	Type      EventType `json:"type"`
+	ChatID    string    `json:"chat_id"`
+	ThreadID  string    `json:"thread_id,omitempty"`
+	MessageID string    `json:"message_id"`
+	// ReplyTo is ID of IncomingMessage, which handling produced event.
+	ReplyTo string `json:"reply_to,omitempty"`
+
+	Answer  *Answer  `json:"answer,omitempty"`
+	Object  *Object  `json:"object,omitempty"`
+	Buttons []Button `json:"buttons,omitempty"`
+}
+
+// IncomingMessage is body of POST request.
+type IncomingMessage struct {
+	// ID of message. Generated, when empty.
+	ID       string               `json:"id,omitempty"`
+	ChatID   string               `json:"chat_id"`
+	ThreadID string               `json:"thread_id,omitempty"`
+	Text     string               `json:"text,omitempty"`
+	Sender   *messengerapi.Sender `json:"sender,omitempty"`
+
+	// Button is ID of pressed Button. Message is rejected, when button is unknown, expired or already pressed.
+	Button string `json:"button,omitempty"`
+	// Callback is true, when message sent by pressing Button or picking item of Enum.
+	Callback bool `json:"callback,omitempty"`
+
+	Attachments []*messengerapi.Attachment `json:"attachments,omitempty"`
+
+	// Wait holds request until message handled and responds with replies to message, see Event.ReplyTo.
+	// Application should call messengerapi.DoneMessage, as webhookapp does, otherwise request waits Config.ReplyTimeout.
+	// Request is failed with status 503, when message is not handled, e.g. dropped by full queue.
+	Wait bool `json:"wait,omitempty"`
+}
+
+// PostResponse is response to IncomingMessage.
+type PostResponse struct {
+	ID string `json:"id"`
+	// Replies to message, when IncomingMessage.Wait is true. Other events of chat are delivered by GET requests.
+	Events []Event `json:"events,omitempty"`
+}
+
+// PollResponse is response to pull request.
+type PollResponse struct {
+	Events []Event `json:"events"`
+}
+
+type Answer struct {
+	Text    string     `json:"text"`
+	Menu    []string   `json:"menu,omitempty"`
+	Enum    []EnumItem `json:"enum,omitempty"`
+	Buttons []Button   `json:"buttons,omitempty"`
+}
+
+// EnumItem is choice of user. Frontend sends Value as text of callback message, when user picks item.
+type EnumItem struct {
+	Value string `json:"value"`
+	Title string `json:"title"`
+}
+
+// Button is command button. Frontend sends ID in IncomingMessage.Button, when user presses button.
+// Args of button are kept by messenger until Config.ButtonTTL, button can be pressed once.
+type Button struct {
+	ID    string `json:"id,omitempty"`
+	Title string `json:"title"`
+}
+
+// Object is photo, document, video, audio, voice, animation, sticker, location or album.
+type Object struct {
+	// Type is one of messengerapi.AttachmentKind or "album".
+	Type    string `json:"type"`
+	Caption string `json:"caption,omitempty"`
+	File    *File  `json:"file,omitempty"`
+
+	Title     string `json:"title,omitempty"`
+	Performer string `json:"performer,omitempty"`
+	// Duration of video, audio or voice in seconds.
+	Duration float64 `json:"duration,omitempty"`
+
+	Location *Location `json:"location,omitempty"`
+	// Items of album.
+	Items []*Object `json:"items,omitempty"`
+}
+
+// File is sent file. Content of file from reader is encoded in base64.
+type File struct {
+	ID      string `json:"id,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Name    string `json:"name,omitempty"`
+	MIME    string `json:"mime,omitempty"`
+	Content []byte `json:"content,omitempty"`
+}
+
+type Location struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+const objectAlbum = "album"
+
+func newAnswer(answer *messengerapi.Answer, buttons []Button) *Answer {
+	enum := make([]EnumItem, len(answer.Enum.Values))
+	for i, item := range answer.Enum.Values {
+		enum[i] = EnumItem{Value: item.Value, Title: item.Title}
+	}
+
+	return &Answer{
+		Text:    answer.Text,
+		Menu:    answer.Menu,
+		Enum:    enum,
+		Buttons: buttons,
+	}
+}
+
+func newObject(object messengerapi.Object) (*Object, error) {
+	switch obj := object.(type) {
+	case *messengerapi.LocalImage:
+		return newFileObject(messengerapi.AttachmentPhoto, messengerapi.File{Reader: obj.Reader, Name: "image.jpg"}, "")
+	case *messengerapi.Photo:
+		return newFileObject(messengerapi.AttachmentPhoto, obj.File, obj.Caption)
+	case *messengerapi.Document:
+		return newFileObject(messengerapi.AttachmentDocument, obj.File, obj.Caption)
+	case *messengerapi.Video:
+		result, err := newFileObject(messengerapi.AttachmentVideo, obj.File, obj.Caption)
+		if err == nil {
+			result.Duration = obj.Duration.Seconds()
+		}
+		return result, err
+	case *messengerapi.Audio:
+		result, err := newFileObject(messengerapi.AttachmentAudio, obj.File, obj.Caption)
+		if err == nil {
+			result.Title = obj.Title
+			result.Performer = obj.Performer
+			result.Duration = obj.Duration.Seconds()
+		}
+		return result, err
+	case *messengerapi.Voice:
+		result, err := newFileObject(messengerapi.AttachmentVoice, obj.File, obj.Caption)
+		if err == nil {
+			result.Duration = obj.Duration.Seconds()
+		}
+		return result, err
+	case *messengerapi.Animation:
+		return newFileObject(messengerapi.AttachmentAnimation, obj.File, obj.Caption)
+	case *messengerapi.Sticker:
+		return newFileObject(messengerapi.AttachmentSticker, obj.File, "")
+	case *messengerapi.Location:
+		return &Object{
+			Type:     string(messengerapi.AttachmentLocation),
+			Location: &Location{Latitude: obj.Latitude, Longitude: obj.Longitude},
+		}, nil
+	case *messengerapi.MediaGroup:
+		if err := obj.Validate(); err != nil {
+			return nil, err
+		}
+
+		items := make([]*Object, len(obj.Items))
+		for i, item := range obj.Items {
+			var err error
+			if items[i], err = newObject(item); err != nil {
+				return nil, fmt.Errorf("album item %d: %w", i, err)
+			}
+		}
+
+		return &Object{Type: objectAlbum, Items: items}, nil
+	default:
+		return nil, fmt.Errorf("unsupported object type %T", object)
+	}
+}
+
+func newFileObject(kind messengerapi.AttachmentKind, file messengerapi.File, caption string) (*Object, error) {
+	result := &File{
+		ID:   file.ID,
+		URL:  file.URL,
+		Name: file.Name,
+		MIME: file.MIME,
+	}
+
+	if file.Reader != nil {
+		content, err := io.ReadAll(file.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+
+		result.Content = content
+	}
+
+	return &Object{
+		Type:    string(kind),
+		Caption: caption,
+		File:    result,
+	}, nil
+}
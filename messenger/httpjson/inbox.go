@@ -0,0 +1,254 @@
+package httpjson
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// inbox keeps events of chats until frontend pulls them. Each event is delivered once.
+type inbox struct {
+	limit int
+	ttl   time.Duration
+
+	mu      sync.Mutex
+	chats   map[string]*chatEvents
+	sweptAt time.Time
+}
+
+type chatEvents struct {
+	events []pendingEvent
+	// updated is closed, when event pushed.
+	updated chan struct{}
+	waiters int
+	// awaited are ids of incoming messages, which senders wait for replies, see await.
+	awaited map[string]struct{}
+}
+
+type pendingEvent struct {
+	event    Event
+	pushedAt time.Time
+}
+
+func newInbox(limit int, ttl time.Duration) *inbox {
+	return &inbox{
+		limit: limit,
+		ttl:   ttl,
+		chats: make(map[string]*chatEvents),
+	}
+}
+
+func (b *inbox) push(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.sweep(now)
+
+	c := b.chat(event.ChatID)
+	c.events = append(c.events, pendingEvent{event: event, pushedAt: now})
+
+	if len(c.events) > b.limit {
+		c.events = append([]pendingEvent(nil), c.events[len(c.events)-b.limit:]...)
+	}
+
+	if !c.reserved(event) {
+		c.notify()
+	}
+}
+
+// await reserves replies to message for its sender: replies are not delivered to consumers of chat until take or release.
+func (b *inbox) await(chatID, msgID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c := b.chat(chatID)
+	if c.awaited == nil {
+		c.awaited = make(map[string]struct{})
+	}
+
+	c.awaited[msgID] = struct{}{}
+}
+
+// take returns pending replies to message and stops reserving them, see await.
+func (b *inbox) take(chatID, msgID string) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.chats[chatID]
+	if !ok {
+		return nil
+	}
+
+	delete(c.awaited, msgID)
+
+	replies := make([]Event, 0)
+	kept := c.events[:0]
+
+	for _, pending := range c.events {
+		if pending.event.ReplyTo == msgID {
+			replies = append(replies, pending.event)
+		} else {
+			kept = append(kept, pending)
+		}
+	}
+
+	c.events = kept
+	b.clean(chatID, c)
+
+	return replies
+}
+
+// release stops reserving replies to message, e.g. when message is not handled: replies are delivered to consumers of chat.
+func (b *inbox) release(chatID, msgID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.chats[chatID]
+	if !ok {
+		return
+	}
+
+	delete(c.awaited, msgID)
+
+	if len(c.events) > 0 {
+		c.notify()
+	}
+
+	b.clean(chatID, c)
+}
+
+// wait returns pending events of chat. When chat has no events, waits for new event until timeout.
+func (b *inbox) wait(ctx context.Context, chatID string, timeout time.Duration) ([]Event, error) {
+	b.mu.Lock()
+
+	if events := b.drain(chatID); len(events) > 0 || timeout <= 0 {
+		b.mu.Unlock()
+		return events, nil
+	}
+
+	c := b.chat(chatID)
+	c.waiters++
+	updated := c.updated
+
+	b.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		var err error
+		expired := false
+
+		select {
+		case <-updated:
+		case <-timer.C:
+			expired = true
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+
+		b.mu.Lock()
+
+		if err != nil {
+			c.waiters--
+			b.clean(chatID, c)
+			b.mu.Unlock()
+
+			return nil, err
+		}
+
+		// events may be reserved by senders of messages, see await.
+		events := b.drain(chatID)
+		if len(events) > 0 || expired {
+			c.waiters--
+			b.clean(chatID, c)
+			b.mu.Unlock()
+
+			return events, nil
+		}
+
+		updated = c.updated
+		b.mu.Unlock()
+	}
+}
+
+func (b *inbox) chat(chatID string) *chatEvents {
+	c, ok := b.chats[chatID]
+	if !ok {
+		c = &chatEvents{updated: make(chan struct{})}
+		b.chats[chatID] = c
+	}
+
+	return c
+}
+
+// drain removes and returns pending events of chat, which are not reserved by await. Caller must hold lock.
+func (b *inbox) drain(chatID string) []Event {
+	c, ok := b.chats[chatID]
+	if !ok {
+		return nil
+	}
+
+	events := make([]Event, 0, len(c.events))
+	kept := c.events[:0]
+
+	for _, pending := range c.events {
+		if c.reserved(pending.event) {
+			kept = append(kept, pending)
+		} else {
+			events = append(events, pending.event)
+		}
+	}
+
+	c.events = kept
+	b.clean(chatID, c)
+
+	return events
+}
+
+// clean removes chat without events, waiters and awaited messages. Caller must hold lock.
+func (b *inbox) clean(chatID string, c *chatEvents) {
+	if len(c.events) == 0 && c.waiters == 0 && len(c.awaited) == 0 {
+		delete(b.chats, chatID)
+	}
+}
+
+// reserved returns true, when event is reply to message, which sender waits for replies.
+func (c *chatEvents) reserved(event Event) bool {
+	if event.ReplyTo == "" {
+		return false
+	}
+
+	_, ok := c.awaited[event.ReplyTo]
+
+	return ok
+}
+
+// notify wakes waiters of chat.
+func (c *chatEvents) notify() {
+	close(c.updated)
+	c.updated = make(chan struct{})
+}
+
+// sweep removes expired events of chats, which frontend does not pull, e.g. after broadcast.
+// Caller must hold lock.
+func (b *inbox) sweep(now time.Time) {
+	if now.Sub(b.sweptAt) < b.ttl {
+		return
+	}
+
+	b.sweptAt = now
+
+	for chatID, c := range b.chats {
+		kept := c.events[:0]
+		for _, pending := range c.events {
+			if now.Sub(pending.pushedAt) < b.ttl {
+				kept = append(kept, pending)
+			}
+		}
+
+		c.events = kept
+		b.clean(chatID, c)
+	}
+}
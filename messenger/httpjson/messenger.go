@@ -0,0 +1,503 @@
+// Package httpjson provides messenger for custom frontends, e.g. web widget, which talk to bot by JSON over HTTP.
+//
+// Frontend sends IncomingMessage by POST request. Replies of bot are delivered as Event:
+//   - replies to message in response to POST request, when IncomingMessage.Wait is true;
+//   - by GET request ?chat_id=<id>&timeout=<seconds>, which waits for events (long polling);
+//   - by GET request ?chat_id=<id> with header "Accept: text/event-stream" (server-sent events).
+//
+// Args of command buttons are kept by messenger, frontend receives and sends back only id of button.
+//
+// Each event is delivered once, to first consumer. Messenger trusts chat_id of requests,
+// so requests are authorized by Config.Token and should be sent by backend of frontend, which authenticates users.
+//
+// Handler of messenger is mounted by webhookapp, e.g. with webhookapp.NewMulti:
+//
+//	widget, err := httpjson.NewMessenger(cfg, logger)
+//	webhookapp.NamedMessenger{Name: "widget", Path: "/widget", Messenger: widget}
+package httpjson
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	defaultReplyTimeout     = 10 * time.Second
+	defaultPollTimeout      = 25 * time.Second
+	defaultMaxPendingEvents = 100
+	defaultEventTTL         = 10 * time.Minute
+	defaultButtonTTL        = time.Hour
+
+	// maxBodySize limits body of incoming request.
+	maxBodySize = 1 << 20
+)
+
+var (
+	_ messengerapi.Messenger       = &Messenger{}
+	_ messengerapi.ThreadMessenger = &Messenger{}
+	_ messengerapi.ReplyMessenger  = &Messenger{}
+)
+
+type Messenger struct {
+	cfg     Config
+	inbox   *inbox
+	buttons *buttonStore
+	logger  logx.Logger
+
+	mu     sync.RWMutex
+	ch     chan messengerapi.Message
+	closed chan struct{}
+	once   sync.Once
+}
+
+type Config struct {
+	// Token authorizes requests by header "Authorization: Bearer <token>". Required, unless AllowUnauthenticated is set.
+	Token string
+	// AllowUnauthenticated accepts requests without token, e.g. behind gateway, which authorizes requests.
+	AllowUnauthenticated bool
+
+	// ReplyTimeout limits waiting for handling of IncomingMessage with Wait. Default: 10s.
+	ReplyTimeout time.Duration
+	// PollTimeout limits long polling and is interval of keep-alive comments of event stream. Default: 25s.
+	PollTimeout time.Duration
+
+	// MaxPendingEvents limits not delivered events of chat, older events are dropped. Default: 100.
+	MaxPendingEvents int
+	// EventTTL drops not delivered events, e.g. broadcast to chats without open frontend. Default: 10m.
+	EventTTL time.Duration
+	// ButtonTTL expires buttons, which are not pressed. Default: 1h.
+	ButtonTTL time.Duration
+
+	// Files downloads files of incoming attachments by file id, e.g. from storage of frontend.
+	// Attachments with files can not be opened, when Files is nil.
+	Files messengerapi.FileFetcher
+}
+
+func NewMessenger(cfg Config, logger logx.Logger) (*Messenger, error) {
+	if cfg.Token == "" && !cfg.AllowUnauthenticated {
+		return nil, errors.New("missing token: set Config.Token or Config.AllowUnauthenticated")
+	}
+
+	if cfg.ReplyTimeout == 0 {
+		cfg.ReplyTimeout = defaultReplyTimeout
+	}
+
+	if cfg.PollTimeout == 0 {
+		cfg.PollTimeout = defaultPollTimeout
+	}
+
+	if cfg.MaxPendingEvents == 0 {
+		cfg.MaxPendingEvents = defaultMaxPendingEvents
+	}
+
+	if cfg.EventTTL == 0 {
+		cfg.EventTTL = defaultEventTTL
+	}
+
+	if cfg.ButtonTTL == 0 {
+		cfg.ButtonTTL = defaultButtonTTL
+	}
+
+	return &Messenger{
+		cfg:     cfg,
+		inbox:   newInbox(cfg.MaxPendingEvents, cfg.EventTTL),
+		buttons: newButtonStore(cfg.ButtonTTL),
+		logger:  logger,
+		closed:  make(chan struct{}),
+	}, nil
+}
+
+// ServeHTTP receives IncomingMessage by POST request and delivers events by GET request.
+func (m *Messenger) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !m.authorized(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		m.serveMessage(w, req)
+	case http.MethodGet:
+		chatID := req.URL.Query().Get("chat_id")
+		if chatID == "" {
+			http.Error(w, "missing chat_id", http.StatusBadRequest)
+			return
+		}
+
+		if acceptsEventStream(req) {
+			m.serveStream(w, req, chatID)
+			return
+		}
+
+		m.servePoll(w, req, chatID)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *Messenger) authorized(req *http.Request) bool {
+	if m.cfg.Token == "" {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(m.cfg.Token)) == 1
+}
+
+func (m *Messenger) serveMessage(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var in struct {
+		IncomingMessage
+
+		// Args are rejected: args of buttons are kept by messenger, see Button.
+		Args json.RawMessage `json:"args"`
+	}
+	if err = json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if in.Args != nil {
+		http.Error(w, "args are not accepted, send id of pressed button", http.StatusBadRequest)
+		return
+	}
+
+	if in.ChatID == "" {
+		http.Error(w, "missing chat_id", http.StatusBadRequest)
+		return
+	}
+
+	if in.Text == "" && in.Button == "" && len(in.Attachments) == 0 {
+		http.Error(w, "empty message", http.StatusBadRequest)
+		return
+	}
+
+	msg, ok := m.adaptMessage(&in.IncomingMessage)
+	if !ok {
+		m.logger.WarnContext(req.Context(), "[lowbot][httpjson] pressed button not found",
+			slog.String("message.chat_id", in.ChatID),
+			slog.String("button.id", in.Button),
+		)
+
+		http.Error(w, "unknown button", http.StatusBadRequest)
+		return
+	}
+
+	if in.Wait {
+		// replies are reserved before handling, so consumers of chat do not take them.
+		m.inbox.await(msg.chatID, msg.id)
+	}
+
+	if !m.push(w, req, msg) {
+		m.inbox.release(msg.chatID, msg.id)
+		return
+	}
+
+	if !in.Wait {
+		writeJSON(w, http.StatusAccepted, &PostResponse{ID: msg.id})
+		return
+	}
+
+	timer := time.NewTimer(m.cfg.ReplyTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-msg.done:
+		if msg.err != nil {
+			m.logger.WarnContext(req.Context(), "[lowbot][httpjson] message is not handled",
+				slog.String("message.id", msg.id),
+				slog.String("message.chat_id", msg.chatID),
+				logx.Err(msg.err),
+			)
+
+			m.inbox.release(msg.chatID, msg.id)
+			http.Error(w, "message is not handled", http.StatusServiceUnavailable)
+
+			return
+		}
+	case <-timer.C:
+		m.logger.WarnContext(req.Context(), "[lowbot][httpjson] message is not handled in time",
+			slog.String("message.id", msg.id),
+			slog.String("message.chat_id", msg.chatID),
+		)
+	case <-req.Context().Done():
+		m.inbox.release(msg.chatID, msg.id)
+		return
+	}
+
+	// replies after timeout are delivered to consumers of chat.
+	writeJSON(w, http.StatusOK, &PostResponse{
+		ID:     msg.id,
+		Events: m.inbox.take(msg.chatID, msg.id),
+	})
+}
+
+// adaptMessage creates message with args of pressed button. Returns false, when button is not found.
+func (m *Messenger) adaptMessage(in *IncomingMessage) (*message, bool) {
+	msg := &message{
+		id:          in.ID,
+		chatID:      in.ChatID,
+		threadID:    in.ThreadID,
+		text:        in.Text,
+		sender:      in.Sender,
+		attachments: in.Attachments,
+		callback:    in.Callback,
+	}
+
+	if in.Button != "" {
+		args, ok := m.buttons.take(in.ChatID, in.Button)
+		if !ok {
+			return nil, false
+		}
+
+		msg.args = args
+		msg.callback = true
+	}
+
+	if msg.id == "" {
+		msg.id = uuid.NewString()
+	}
+
+	if msg.sender == nil {
+		msg.sender = &messengerapi.Sender{ID: in.ChatID}
+	}
+
+	for _, attachment := range msg.attachments {
+		attachment.Fetcher = m
+	}
+
+	if in.Wait {
+		msg.done = make(chan struct{})
+	}
+
+	return msg, true
+}
+
+// push sends message to listener. Returns false, when message is not accepted.
+func (m *Messenger) push(w http.ResponseWriter, req *http.Request, msg *message) bool {
+	// lock prevents closing of channel by listener during sending.
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.ch == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return false
+	}
+
+	m.logger.DebugContext(req.Context(), "[lowbot][httpjson] received message",
+		slog.String("message.id", msg.id),
+		slog.String("message.chat_id", msg.chatID),
+	)
+
+	select {
+	case m.ch <- msg:
+		return true
+	case <-m.closed:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case <-req.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	return false
+}
+
+// servePoll responds with pending events of chat. Waits for events until timeout query parameter in seconds.
+func (m *Messenger) servePoll(w http.ResponseWriter, req *http.Request, chatID string) {
+	timeout := m.cfg.PollTimeout
+
+	if raw := req.URL.Query().Get("timeout"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			http.Error(w, "invalid timeout", http.StatusBadRequest)
+			return
+		}
+
+		timeout = min(time.Duration(seconds)*time.Second, m.cfg.PollTimeout)
+	}
+
+	ctx, cancel := m.requestContext(req)
+	defer cancel()
+
+	events, err := m.inbox.wait(ctx, chatID, timeout)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	if events == nil {
+		events = []Event{}
+	}
+
+	writeJSON(w, http.StatusOK, &PollResponse{Events: events})
+}
+
+// serveStream sends events of chat as server-sent events until client disconnects or messenger closed.
+func (m *Messenger) serveStream(w http.ResponseWriter, req *http.Request, chatID string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	ctx, cancel := m.requestContext(req)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		events, err := m.inbox.wait(ctx, chatID, m.cfg.PollTimeout)
+		if err != nil {
+			return
+		}
+
+		if len(events) == 0 {
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+		}
+
+		for _, event := range events {
+			if err = writeEvent(w, &event); err != nil {
+				break
+			}
+		}
+
+		if err != nil {
+			m.logger.WarnContext(ctx, "[lowbot][httpjson] failed to write events", logx.Err(err))
+			return
+		}
+
+		flusher.Flush()
+	}
+}
+
+// requestContext returns context of request, which canceled on Close.
+func (m *Messenger) requestContext(req *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(req.Context())
+
+	go func() {
+		select {
+		case <-m.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Listen sends messages of ServeHTTP to ch. Blocks until Close.
+// Requests, which wait for sending to ch, are answered with 503 on Close. Handling of sent messages is not awaited.
+func (m *Messenger) Listen(ch chan messengerapi.Message) error {
+	m.mu.Lock()
+	m.ch = ch
+	m.mu.Unlock()
+
+	<-m.closed
+
+	m.mu.Lock()
+	m.ch = nil
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *Messenger) Close() error {
+	m.once.Do(func() {
+		close(m.closed)
+	})
+
+	return nil
+}
+
+func (m *Messenger) CreateResponder(chatID string) messengerapi.Responder {
+	return &responder{
+		chatID:    chatID,
+		messenger: m,
+	}
+}
+
+// CreateThreadResponder creates Responder, which publishes events with thread of chat.
+func (m *Messenger) CreateThreadResponder(chatID, threadID string) messengerapi.Responder {
+	return &responder{
+		chatID:    chatID,
+		threadID:  threadID,
+		messenger: m,
+	}
+}
+
+// CreateReplyResponder creates Responder, which publishes events with id of incoming message.
+// Events are delivered in response to message, when IncomingMessage.Wait is true.
+func (m *Messenger) CreateReplyResponder(chatID, threadID, msgID string) messengerapi.Responder {
+	return &responder{
+		chatID:    chatID,
+		threadID:  threadID,
+		replyTo:   msgID,
+		messenger: m,
+	}
+}
+
+// Fetch downloads file of incoming attachment by Config.Files.
+func (m *Messenger) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	if m.cfg.Files == nil {
+		return nil, messengerapi.ErrFetcherMissing
+	}
+
+	return m.cfg.Files.Fetch(ctx, fileID)
+}
+
+func acceptsEventStream(req *http.Request) bool {
+	for _, accept := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == "text/event-stream" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeEvent(w io.Writer, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "event: "+string(event.Type)+"\ndata: "+string(data)+"\n\n")
+
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,76 @@
+package httpjson
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type message struct {
+	id       string
+	chatID   string
+	threadID string
+	text     string
+	sender   *messengerapi.Sender
+
+	args        *messengerapi.Args
+	attachments []*messengerapi.Attachment
+	callback    bool
+
+	// done is closed, when message handled. Nil, when sender does not wait.
+	done     chan struct{}
+	doneOnce sync.Once
+	// err is reason, why message is not handled. Set before closing of done.
+	err error
+}
+
+func (m *message) GetID() string {
+	return m.id
+}
+
+func (m *message) GetChatID() string {
+	return m.chatID
+}
+
+func (m *message) GetThreadID() string {
+	return m.threadID
+}
+
+func (m *message) GetBody() string {
+	return m.text
+}
+
+func (m *message) GetSender() *messengerapi.Sender {
+	return m.sender
+}
+
+func (m *message) ExtractCommandName() string {
+	if strings.HasPrefix(m.text, "/") {
+		return strings.TrimSpace(m.text[1:])
+	}
+	return ""
+}
+
+func (m *message) IsCallback() bool {
+	return m.callback
+}
+
+func (m *message) GetArgs() *messengerapi.Args {
+	return m.args
+}
+
+func (m *message) GetAttachments() []*messengerapi.Attachment {
+	return m.attachments
+}
+
+func (m *message) Done(err error) {
+	if m.done == nil {
+		return
+	}
+
+	m.doneOnce.Do(func() {
+		m.err = err
+		close(m.done)
+	})
+}
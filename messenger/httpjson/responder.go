@@ -0,0 +1,122 @@
+package httpjson
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// responder publishes replies as events of chat.
+type responder struct {
+	chatID   string
+	threadID string
+	// replyTo is id of incoming message, to which responder replies, or empty string.
+	replyTo   string
+	messenger *Messenger
+}
+
+// Fetch downloads file by id, e.g. attachment restored from dialog state.
+func (r *responder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return r.messenger.Fetch(ctx, fileID)
+}
+
+func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
+	msg := r.message(uuid.NewString(), answer.Text)
+
+	r.messenger.inbox.push(Event{
+		Type:      EventMessage,
+		ChatID:    r.chatID,
+		ThreadID:  r.threadID,
+		ReplyTo:   r.replyTo,
+		MessageID: msg.id,
+		Answer:    newAnswer(answer, r.buttons(answer.Buttons)),
+	})
+
+	return msg, nil
+}
+
+func (r *responder) RespondObject(object messengerapi.Object) (messengerapi.Message, error) {
+	obj, err := newObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := r.message(uuid.NewString(), obj.Caption)
+
+	r.messenger.inbox.push(Event{
+		Type:      EventMessage,
+		ChatID:    r.chatID,
+		ThreadID:  r.threadID,
+		ReplyTo:   r.replyTo,
+		MessageID: msg.id,
+		Object:    obj,
+	})
+
+	return msg, nil
+}
+
+func (r *responder) Edit(msgID string, answer *messengerapi.Answer) (messengerapi.Message, error) {
+	r.messenger.inbox.push(Event{
+		Type:      EventEdit,
+		ChatID:    r.chatID,
+		ThreadID:  r.threadID,
+		ReplyTo:   r.replyTo,
+		MessageID: msgID,
+		Answer:    newAnswer(answer, r.buttons(answer.Buttons)),
+	})
+
+	return r.message(msgID, answer.Text), nil
+}
+
+func (r *responder) Delete(msgID string) error {
+	r.messenger.inbox.push(Event{
+		Type:      EventDelete,
+		ChatID:    r.chatID,
+		ThreadID:  r.threadID,
+		ReplyTo:   r.replyTo,
+		MessageID: msgID,
+	})
+
+	return nil
+}
+
+func (r *responder) EditButtons(msgID string, buttons []messengerapi.Button) error {
+	r.messenger.inbox.push(Event{
+		Type:      EventButtons,
+		ChatID:    r.chatID,
+		ThreadID:  r.threadID,
+		ReplyTo:   r.replyTo,
+		MessageID: msgID,
+		Buttons:   r.buttons(buttons),
+	})
+
+	return nil
+}
+
+// buttons binds args of command buttons to chat, see buttonStore.
+func (r *responder) buttons(buttons []messengerapi.Button) []Button {
+	result := make([]Button, len(buttons))
+
+	for i, button := range buttons {
+		result[i] = Button{Title: button.GetTitle()}
+
+		if cmd, ok := button.(*messengerapi.CommandButton); ok {
+			result[i].ID = r.messenger.buttons.bind(r.chatID, cmd.Args)
+		}
+	}
+
+	return result
+}
+
+func (r *responder) message(id, text string) *message {
+	return &message{
+		id:       id,
+		chatID:   r.chatID,
+		threadID: r.threadID,
+		text:     text,
+		sender:   &messengerapi.Sender{},
+	}
+}
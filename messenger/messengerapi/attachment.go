@@ -0,0 +1,82 @@
+package messengerapi
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrNoFile         = errors.New("attachment has no file")
+	ErrFetcherMissing = errors.New("file fetcher missing")
+)
+
+type AttachmentKind string
+
+const (
+	AttachmentPhoto     AttachmentKind = "photo"
+	AttachmentDocument  AttachmentKind = "document"
+	AttachmentVideo     AttachmentKind = "video"
+	AttachmentAudio     AttachmentKind = "audio"
+	AttachmentVoice     AttachmentKind = "voice"
+	AttachmentAnimation AttachmentKind = "animation"
+	AttachmentSticker   AttachmentKind = "sticker"
+	AttachmentContact   AttachmentKind = "contact"
+	AttachmentLocation  AttachmentKind = "location"
+)
+
+// Attachment is file, contact or location, which user sent in message.
+type Attachment struct {
+	Kind AttachmentKind `json:"kind"`
+
+	// FileID is id of file in messenger. Empty for contact and location.
+	FileID   string `json:"file_id,omitempty"`
+	FileName string `json:"file_name,omitempty"`
+	MIME     string `json:"mime,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+
+	Contact  *Contact  `json:"contact,omitempty"`
+	Location *Location `json:"location,omitempty"`
+
+	// Fetcher downloads file lazily, see Open.
+	Fetcher FileFetcher `json:"-"`
+}
+
+type Contact struct {
+	PhoneNumber string `json:"phone_number"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name,omitempty"`
+	// UserID is id of user in messenger, when contact is user of messenger.
+	UserID string `json:"user_id,omitempty"`
+}
+
+// FileFetcher downloads files of messenger.
+// Responder of messenger may implement FileFetcher for downloading attachments restored from state.
+type FileFetcher interface {
+	Fetch(ctx context.Context, fileID string) (io.ReadCloser, error)
+}
+
+// Open downloads file of attachment. Caller must close reader.
+// Throws ErrNoFile, ErrFetcherMissing.
+func (a *Attachment) Open(ctx context.Context) (io.ReadCloser, error) {
+	if a.FileID == "" {
+		return nil, ErrNoFile
+	}
+
+	if a.Fetcher == nil {
+		return nil, ErrFetcherMissing
+	}
+
+	return a.Fetcher.Fetch(ctx, a.FileID)
+}
+
+// FindAttachment returns first attachment of message with kind or nil.
+func FindAttachment(msg Message, kind AttachmentKind) *Attachment {
+	for _, attachment := range msg.GetAttachments() {
+		if attachment.Kind == kind {
+			return attachment
+		}
+	}
+
+	return nil
+}
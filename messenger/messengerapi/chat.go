@@ -0,0 +1,31 @@
+package messengerapi
+
+import "strings"
+
+// chatIDSeparator separates messenger name and chat id in qualified chat id.
+const chatIDSeparator = ":"
+
+// MessengerMessage is optional interface of Message, which received by named messenger of application.
+type MessengerMessage interface {
+	// GetMessengerName returns name of messenger, which received message.
+	GetMessengerName() string
+}
+
+// QualifyChatID returns chat id, which unique across messengers of application: "<messenger>:<chat id>".
+func QualifyChatID(messenger, chatID string) string {
+	return messenger + chatIDSeparator + chatID
+}
+
+// SplitChatID splits chat id, returned by QualifyChatID.
+func SplitChatID(qualified string) (messenger, chatID string, ok bool) {
+	return strings.Cut(qualified, chatIDSeparator)
+}
+
+// MessengerName returns name of messenger, which received message, or empty string.
+func MessengerName(msg Message) string {
+	if named, ok := msg.(MessengerMessage); ok {
+		return named.GetMessengerName()
+	}
+
+	return ""
+}
@@ -3,6 +3,7 @@ package messengerapi
 type Message interface {
 	GetID() string
 	GetChatID() string
+	// GetBody returns text of message or caption of attachment.
 	GetBody() string
 
 	// GetSender returns User that sent this message.
@@ -10,4 +11,26 @@ type Message interface {
 
 	ExtractCommandName() string
 	GetArgs() *Args
+
+	// GetAttachments returns files, contacts and locations, which user sent in message.
+	GetAttachments() []*Attachment
+}
+
+// ThreadMessage is optional interface of Message, which sent to thread (topic) of chat.
+type ThreadMessage interface {
+	// GetThreadID returns thread id or empty string, when message sent out of thread.
+	GetThreadID() string
+}
+
+// CallbackMessage is optional interface of Message, which created by pressing button of sent message.
+type CallbackMessage interface {
+	IsCallback() bool
+}
+
+// DoneMessage is optional interface of Message, which sender waits for end of handling,
+// e.g. webhook, which responds with replies synchronously.
+type DoneMessage interface {
+	// Done is called, when message handled or skipped.
+	// Err is not nil, when message is not handled, e.g. dropped by full queue or abandoned on shutdown.
+	Done(err error)
 }
@@ -10,3 +10,46 @@ type Messenger interface {
 	// CreateResponder creates Responder.
 	CreateResponder(chatID string) Responder
 }
+
+// ThreadMessenger is optional interface of Messenger, which replies to thread (topic) of chat.
+type ThreadMessenger interface {
+	// CreateThreadResponder creates Responder, which sends messages to thread of chat.
+	// Messages are sent to chat, when threadID is empty.
+	CreateThreadResponder(chatID, threadID string) Responder
+}
+
+// ReplyMessenger is optional interface of Messenger, which relates replies to incoming message,
+// e.g. to respond with replies synchronously.
+type ReplyMessenger interface {
+	// CreateReplyResponder creates Responder, which replies to message with id {msgID} in thread of chat.
+	CreateReplyResponder(chatID, threadID, msgID string) Responder
+}
+
+// CreateThreadResponder creates Responder of thread by ThreadMessenger.
+// Responder of chat is created, when messenger does not support threads or threadID is empty.
+func CreateThreadResponder(messenger Messenger, chatID, threadID string) Responder {
+	if threadMessenger, ok := messenger.(ThreadMessenger); ok && threadID != "" {
+		return threadMessenger.CreateThreadResponder(chatID, threadID)
+	}
+
+	return messenger.CreateResponder(chatID)
+}
+
+// CreateReplyResponder creates Responder of message by ReplyMessenger.
+// Responder of thread is created, when messenger does not relate replies to messages.
+func CreateReplyResponder(messenger Messenger, chatID, threadID, msgID string) Responder {
+	if replyMessenger, ok := messenger.(ReplyMessenger); ok {
+		return replyMessenger.CreateReplyResponder(chatID, threadID, msgID)
+	}
+
+	return CreateThreadResponder(messenger, chatID, threadID)
+}
+
+// ThreadID returns thread of message, see ThreadMessage, or empty string.
+func ThreadID(msg Message) string {
+	if threadMsg, ok := msg.(ThreadMessage); ok {
+		return threadMsg.GetThreadID()
+	}
+
+	return ""
+}
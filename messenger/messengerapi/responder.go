@@ -1,5 +1,7 @@
 package messengerapi
 
+import "context"
+
 type Responder interface {
 	// Respond responds text and buttons.
 	// See Answer.
@@ -8,4 +10,29 @@ type Responder interface {
 	// RespondObject responds media files.
 	// See LocalImage
 	RespondObject(file Object) (Message, error)
+
+	// Edit replaces text and buttons of sent message with id {msgID}, returned by Respond.
+	Edit(msgID string, answer *Answer) (Message, error)
+
+	// Delete deletes sent message with id {msgID}.
+	Delete(msgID string) error
+
+	// EditButtons replaces buttons of sent message with id {msgID}. Empty buttons remove keyboard.
+	EditButtons(msgID string, buttons []Button) error
+}
+
+// ContextResponder is optional interface of Responder, which waits for sending, e.g. by rate limits.
+type ContextResponder interface {
+	// WithContext returns copy of responder, which stops waiting for sending, when ctx done.
+	WithContext(ctx context.Context) Responder
+}
+
+// ResponderWithContext binds responder to ctx by ContextResponder.
+// Responder is returned as is, when it does not support context.
+func ResponderWithContext(ctx context.Context, responder Responder) Responder {
+	if r, ok := responder.(ContextResponder); ok {
+		return r.WithContext(ctx)
+	}
+
+	return responder
 }
@@ -1,13 +1,157 @@
 package messengerapi
 
-import "io"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// MinMediaGroupItems is min count of items in MediaGroup.
+	MinMediaGroupItems = 2
+	// MaxMediaGroupItems is max count of items in MediaGroup.
+	MaxMediaGroupItems = 10
+)
+
+var ErrInvalidMediaGroup = errors.New("invalid media group")
 
 type Object interface {
 	object()
 }
 
+// AlbumItem is Object, which can be sent in MediaGroup.
+type AlbumItem interface {
+	Object
+	albumItem()
+}
+
+// File is source of sent file: Reader, URL or ID of file, which already uploaded to messenger.
+// Only one source should be set.
+type File struct {
+	Reader io.Reader
+	URL    string
+	// ID is reusable id of file in messenger.
+	ID string
+
+	// Name is file name, shown to user.
+	Name string
+	// MIME is mime type of file, e.g. "application/pdf".
+	MIME string
+}
+
+func FileFromReader(reader io.Reader, name string) File {
+	return File{Reader: reader, Name: name}
+}
+
+func FileFromURL(url string) File {
+	return File{URL: url}
+}
+
+func FileFromID(id string) File {
+	return File{ID: id}
+}
+
 type LocalImage struct {
 	Reader io.Reader
 }
 
-func (i LocalImage) object() {}
+type Photo struct {
+	File    File
+	Caption string
+}
+
+type Document struct {
+	File    File
+	Caption string
+}
+
+type Video struct {
+	File     File
+	Caption  string
+	Duration time.Duration
+}
+
+type Audio struct {
+	File      File
+	Caption   string
+	Title     string
+	Performer string
+	Duration  time.Duration
+}
+
+type Voice struct {
+	File     File
+	Caption  string
+	Duration time.Duration
+}
+
+type Animation struct {
+	File    File
+	Caption string
+}
+
+type Sticker struct {
+	File File
+}
+
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// MediaGroup is album of 2-10 items, sent as one message.
+// Photos and videos can be mixed, documents and audios are grouped only with items of the same type.
+type MediaGroup struct {
+	Items []AlbumItem
+}
+
+// Validate checks count of items and mixing of item types. Throws ErrInvalidMediaGroup.
+func (g *MediaGroup) Validate() error {
+	if len(g.Items) < MinMediaGroupItems || len(g.Items) > MaxMediaGroupItems {
+		return fmt.Errorf(
+			"%w: contains %d items, expected from %d to %d",
+			ErrInvalidMediaGroup, len(g.Items), MinMediaGroupItems, MaxMediaGroupItems,
+		)
+	}
+
+	for i, item := range g.Items {
+		if item == nil {
+			return fmt.Errorf("%w: item %d is nil", ErrInvalidMediaGroup, i)
+		}
+
+		if albumGroup(item) != albumGroup(g.Items[0]) {
+			return fmt.Errorf("%w: item %d of type %T can not be mixed with %T", ErrInvalidMediaGroup, i, item, g.Items[0])
+		}
+	}
+
+	return nil
+}
+
+// albumGroup returns group of album item: only items of the same group can be sent in one MediaGroup.
+func albumGroup(item AlbumItem) string {
+	switch item.(type) {
+	case *Document:
+		return "document"
+	case *Audio:
+		return "audio"
+	default:
+		return "media"
+	}
+}
+
+func (i *LocalImage) object() {}
+func (p *Photo) object()      {}
+func (d *Document) object()   {}
+func (v *Video) object()      {}
+func (a *Audio) object()      {}
+func (v *Voice) object()      {}
+func (a *Animation) object()  {}
+func (s *Sticker) object()    {}
+func (l *Location) object()   {}
+func (g *MediaGroup) object() {}
+
+func (p *Photo) albumItem()    {}
+func (d *Document) albumItem() {}
+func (v *Video) albumItem()    {}
+func (a *Audio) albumItem()    {}
@@ -0,0 +1,156 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is error, returned by Slack Web API in "error" field of response.
+type APIError struct {
+	Method string
+	Code   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("slack method %s: %s", e.Method, e.Code)
+}
+
+// RateLimitedError is returned, when Slack Web API rate limit exceeded.
+type RateLimitedError struct {
+	Method     string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("slack method %s: rate limited, retry after %s", e.Method, e.RetryAfter)
+}
+
+// apiClient calls methods of Slack Web API with bot token.
+type apiClient struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+func (r *apiResponse) response() *apiResponse {
+	return r
+}
+
+type apiResult interface {
+	response() *apiResponse
+}
+
+// callJSON calls method with JSON body.
+func (c *apiClient) callJSON(ctx context.Context, method string, params any, result apiResult) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshal params: %w", err)
+	}
+
+	return c.call(ctx, method, "application/json; charset=utf-8", bytes.NewReader(body), result)
+}
+
+// callForm calls method with form body. Some methods, e.g. files.getUploadURLExternal, do not accept JSON.
+func (c *apiClient) callForm(ctx context.Context, method string, params url.Values, result apiResult) error {
+	return c.call(ctx, method, "application/x-www-form-urlencoded", strings.NewReader(params.Encode()), result)
+}
+
+func (c *apiClient) call(ctx context.Context, method, contentType string, body io.Reader, result apiResult) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/"+method, body)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("call slack method %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+
+		return &RateLimitedError{
+			Method:     method,
+			RetryAfter: time.Duration(retryAfter) * time.Second,
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("call slack method %s: unexpected status %d", method, resp.StatusCode)
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("decode response of %s: %w", method, err)
+	}
+
+	if !result.response().OK {
+		return &APIError{
+			Method: method,
+			Code:   result.response().Error,
+		}
+	}
+
+	return nil
+}
+
+// download gets file by private url with bot token.
+func (c *apiClient) download(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+
+		return nil, fmt.Errorf("download file: unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// upload sends file content to url, returned by files.getUploadURLExternal.
+func (c *apiClient) upload(ctx context.Context, uploadURL string, content []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload file: unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
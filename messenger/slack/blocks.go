@@ -0,0 +1,132 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	actionPrefix = "lowbot_"
+	actionEnum   = "enum"
+	actionButton = "button"
+	actionMenu   = "menu"
+
+	// maxButtonValue is limit of button value in Slack.
+	maxButtonValue = 2000
+)
+
+// block is Slack Block Kit block, see https://api.slack.com/block-kit.
+type block struct {
+	Type     string        `json:"type"`
+	Text     *textObject   `json:"text,omitempty"`
+	Elements []blockButton `json:"elements,omitempty"`
+	ImageURL string        `json:"image_url,omitempty"`
+	AltText  string        `json:"alt_text,omitempty"`
+}
+
+type textObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type blockButton struct {
+	Type     string      `json:"type"`
+	Text     *textObject `json:"text"`
+	ActionID string      `json:"action_id"`
+	Value    string      `json:"value"`
+}
+
+// buttonValue is value of CommandButton, which contains Args.
+type buttonValue struct {
+	CommandName string            `json:"c"`
+	StateName   string            `json:"s,omitempty"`
+	Data        map[string]string `json:"d,omitempty"`
+}
+
+// renderAnswer converts Answer to blocks: text section and actions with Menu, Enum and Buttons.
+// Slack has no reply keyboard, so Menu items are rendered as buttons, which send title as text.
+func renderAnswer(answer *messengerapi.Answer) ([]block, error) {
+	blocks := make([]block, 0, 2)
+
+	if answer.Text != "" {
+		blocks = append(blocks, block{
+			Type: "section",
+			Text: &textObject{Type: "mrkdwn", Text: answer.Text},
+		})
+	}
+
+	buttons, err := renderButtons(answer)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(buttons) > 0 {
+		blocks = append(blocks, block{
+			Type:     "actions",
+			Elements: buttons,
+		})
+	}
+
+	return blocks, nil
+}
+
+func renderButtons(answer *messengerapi.Answer) ([]blockButton, error) {
+	buttons := make([]blockButton, 0, len(answer.Menu)+len(answer.Enum.Values)+len(answer.Buttons))
+
+	for _, item := range answer.Menu {
+		buttons = append(buttons, newBlockButton(item, actionMenu, len(buttons), item))
+	}
+
+	for _, item := range answer.Enum.Values {
+		buttons = append(buttons, newBlockButton(item.Title, actionEnum, len(buttons), item.Value))
+	}
+
+	for _, button := range answer.Buttons {
+		cmdButton, ok := button.(*messengerapi.CommandButton)
+		if !ok {
+			return nil, fmt.Errorf("unsupported button type %T", button)
+		}
+
+		value, err := json.Marshal(&buttonValue{
+			CommandName: cmdButton.Args.CommandName,
+			StateName:   cmdButton.Args.StateName,
+			Data:        cmdButton.Args.Data,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("marshal button value: %w", err)
+		}
+
+		if len(value) > maxButtonValue {
+			return nil, fmt.Errorf("args of button %q exceed %d bytes", cmdButton.Title, maxButtonValue)
+		}
+
+		buttons = append(buttons, newBlockButton(cmdButton.Title, actionButton, len(buttons), string(value)))
+	}
+
+	return buttons, nil
+}
+
+// newBlockButton creates button with unique in block action id "lowbot_<kind>_<index>".
+func newBlockButton(title, kind string, index int, value string) blockButton {
+	return blockButton{
+		Type:     "button",
+		Text:     &textObject{Type: "plain_text", Text: title},
+		ActionID: actionPrefix + kind + "_" + strconv.Itoa(index),
+		Value:    value,
+	}
+}
+
+// actionKind returns kind of button by action id. Returns empty string for actions of other apps.
+func actionKind(actionID string) string {
+	if !strings.HasPrefix(actionID, actionPrefix) {
+		return ""
+	}
+
+	kind, _, _ := strings.Cut(strings.TrimPrefix(actionID, actionPrefix), "_")
+
+	return kind
+}
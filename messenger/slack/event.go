@@ -0,0 +1,176 @@
+package slack
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	envelopeURLVerification = "url_verification"
+	envelopeEventCallback   = "event_callback"
+
+	eventMessage    = "message"
+	eventAppMention = "app_mention"
+
+	subtypeFileShare = "file_share"
+
+	interactionBlockActions = "block_actions"
+)
+
+// mentionPattern matches leading mention of bot in app_mention text, e.g. "<@U0LAN0Z89> ".
+var mentionPattern = regexp.MustCompile(`^\s*<@[A-Z0-9]+>\s*`)
+
+// eventEnvelope is payload of Events API request.
+type eventEnvelope struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	EventID   string `json:"event_id"`
+	Event     *event `json:"event"`
+}
+
+type event struct {
+	Type     string      `json:"type"`
+	Subtype  string      `json:"subtype"`
+	BotID    string      `json:"bot_id"`
+	User     string      `json:"user"`
+	Channel  string      `json:"channel"`
+	Text     string      `json:"text"`
+	TS       string      `json:"ts"`
+	ThreadTS string      `json:"thread_ts"`
+	Files    []eventFile `json:"files"`
+}
+
+type eventFile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Mimetype string `json:"mimetype"`
+	Size     int64  `json:"size"`
+}
+
+// interaction is payload of interactive components request.
+type interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Message struct {
+		Text     string `json:"text"`
+		TS       string `json:"ts"`
+		ThreadTS string `json:"thread_ts"`
+	} `json:"message"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+		ActionTS string `json:"action_ts"`
+	} `json:"actions"`
+}
+
+// adaptEvent converts event of user message to message. Returns nil for other events, e.g. messages of bots.
+func (m *Messenger) adaptEvent(evt *event) *message {
+	if evt == nil || evt.BotID != "" || evt.User == "" {
+		return nil
+	}
+
+	switch {
+	case evt.Type == eventAppMention:
+	case evt.Type == eventMessage && (evt.Subtype == "" || evt.Subtype == subtypeFileShare):
+	default:
+		return nil
+	}
+
+	return &message{
+		id:          evt.TS,
+		chatID:      evt.Channel,
+		threadID:    evt.ThreadTS,
+		text:        mentionPattern.ReplaceAllString(evt.Text, ""),
+		sender:      &messengerapi.Sender{ID: evt.User},
+		attachments: m.adaptFiles(evt.Files),
+	}
+}
+
+func (m *Messenger) adaptFiles(files []eventFile) []*messengerapi.Attachment {
+	if len(files) == 0 {
+		return nil
+	}
+
+	attachments := make([]*messengerapi.Attachment, 0, len(files))
+	for _, file := range files {
+		attachments = append(attachments, &messengerapi.Attachment{
+			Kind:     attachmentKind(file.Mimetype),
+			FileID:   file.ID,
+			FileName: file.Name,
+			MIME:     file.Mimetype,
+			Size:     file.Size,
+			Fetcher:  m,
+		})
+	}
+
+	return attachments
+}
+
+// adaptInteraction converts pressed button to message. Returns nil for other interactions.
+func (m *Messenger) adaptInteraction(payload *interaction) (*message, error) {
+	if payload.Type != interactionBlockActions || len(payload.Actions) == 0 {
+		return nil, nil //nolint:nilnil // interaction is skipped
+	}
+
+	action := payload.Actions[0]
+
+	msg := &message{
+		id:       action.ActionTS,
+		chatID:   payload.Channel.ID,
+		threadID: payload.Message.ThreadTS,
+		text:     action.Value,
+		sender: &messengerapi.Sender{
+			ID:        payload.User.ID,
+			Username:  payload.User.Username,
+			FirstName: payload.User.Name,
+		},
+		callback: true,
+	}
+
+	switch actionKind(action.ActionID) {
+	case actionMenu:
+		// menu item is sent as text of user.
+		msg.callback = false
+	case actionButton:
+		var value buttonValue
+		if err := json.Unmarshal([]byte(action.Value), &value); err != nil {
+			return nil, fmt.Errorf("unmarshal button value: %w", err)
+		}
+
+		msg.text = payload.Message.Text
+		msg.args = &messengerapi.Args{
+			CommandName: value.CommandName,
+			StateName:   value.StateName,
+			Data:        value.Data,
+		}
+	case actionEnum:
+	default:
+		return nil, nil //nolint:nilnil // action of other app
+	}
+
+	return msg, nil
+}
+
+func attachmentKind(mimetype string) messengerapi.AttachmentKind {
+	switch {
+	case strings.HasPrefix(mimetype, "image/"):
+		return messengerapi.AttachmentPhoto
+	case strings.HasPrefix(mimetype, "video/"):
+		return messengerapi.AttachmentVideo
+	case strings.HasPrefix(mimetype, "audio/"):
+		return messengerapi.AttachmentAudio
+	default:
+		return messengerapi.AttachmentDocument
+	}
+}
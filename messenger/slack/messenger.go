@@ -0,0 +1,256 @@
+// Package slack provides messenger for Slack Events API and interactive components.
+//
+// Messenger receives events and button presses by webhook, so Event Subscriptions Request URL
+// and Interactivity Request URL of Slack app should point to http server of application.
+// Bot token requires scopes: chat:write, channels:history (for editing buttons), files:read, files:write.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const (
+	defaultAPIURL        = "https://slack.com/api"
+	defaultMaxRequestAge = 5 * time.Minute
+	defaultHTTPTimeout   = 10 * time.Second
+
+	// maxBodySize limits body of incoming request.
+	maxBodySize = 1 << 20
+)
+
+var (
+	_ messengerapi.Messenger       = &Messenger{}
+	_ messengerapi.ThreadMessenger = &Messenger{}
+)
+
+type Messenger struct {
+	cfg    Config
+	api    *apiClient
+	logger logx.Logger
+
+	mu     sync.RWMutex
+	ch     chan messengerapi.Message
+	closed chan struct{}
+	once   sync.Once
+}
+
+type Config struct {
+	// SigningSecret verifies requests from Slack.
+	SigningSecret string
+	// BotToken is token of bot user, "xoxb-...".
+	BotToken string
+
+	// APIURL is url of Slack Web API. Default: https://slack.com/api.
+	APIURL string
+	// HTTPClient calls Slack Web API. Default: client with 10s timeout.
+	HTTPClient *http.Client
+
+	// MaxRequestAge rejects older requests to prevent replay attacks. Default: 5m.
+	MaxRequestAge time.Duration
+}
+
+func NewMessenger(cfg Config, logger logx.Logger) (*Messenger, error) {
+	if cfg.SigningSecret == "" {
+		return nil, errors.New("missing signing secret")
+	}
+
+	if cfg.BotToken == "" {
+		return nil, errors.New("missing bot token")
+	}
+
+	if cfg.APIURL == "" {
+		cfg.APIURL = defaultAPIURL
+	}
+
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+
+	if cfg.MaxRequestAge == 0 {
+		cfg.MaxRequestAge = defaultMaxRequestAge
+	}
+
+	return &Messenger{
+		cfg: cfg,
+		api: &apiClient{
+			url:    cfg.APIURL,
+			token:  cfg.BotToken,
+			client: cfg.HTTPClient,
+		},
+		logger: logger,
+		closed: make(chan struct{}),
+	}, nil
+}
+
+// ServeHTTP verifies signature of request and handles Events API or interactive components payload.
+func (m *Messenger) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err = verifySignature(m.cfg.SigningSecret, req.Header, body, time.Now(), m.cfg.MaxRequestAge); err != nil {
+		m.logger.WarnContext(req.Context(), "[lowbot][slack] rejected request", logx.Err(err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if contentType == "application/x-www-form-urlencoded" {
+		m.serveInteraction(w, req, body)
+		return
+	}
+
+	m.serveEvent(w, req, body)
+}
+
+func (m *Messenger) serveEvent(w http.ResponseWriter, req *http.Request, body []byte) {
+	var envelope eventEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch envelope.Type {
+	case envelopeURLVerification:
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(envelope.Challenge))
+	case envelopeEventCallback:
+		m.push(w, req, m.adaptEvent(envelope.Event))
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (m *Messenger) serveInteraction(w http.ResponseWriter, req *http.Request, body []byte) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var payload interaction
+	if err = json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	msg, err := m.adaptInteraction(&payload)
+	if err != nil {
+		m.logger.ErrorContext(req.Context(), "[lowbot][slack] failed to adapt interaction", logx.Err(err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	m.push(w, req, msg)
+}
+
+// push sends message to listener. Slack retries request, when response is not 2xx.
+func (m *Messenger) push(w http.ResponseWriter, req *http.Request, msg *message) {
+	if msg == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// lock prevents closing of channel by listener during sending.
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.ch == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	m.logger.DebugContext(req.Context(), "[lowbot][slack] received message",
+		slog.String("message.id", msg.id),
+		slog.String("message.chat_id", msg.chatID),
+	)
+
+	select {
+	case m.ch <- msg:
+		w.WriteHeader(http.StatusOK)
+	case <-m.closed:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case <-req.Context().Done():
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// Listen sends messages of ServeHTTP to ch. Blocks until Close.
+// Requests, which wait for sending to ch, are answered with 503 on Close. Handling of sent messages is not awaited.
+func (m *Messenger) Listen(ch chan messengerapi.Message) error {
+	m.mu.Lock()
+	m.ch = ch
+	m.mu.Unlock()
+
+	<-m.closed
+
+	m.mu.Lock()
+	m.ch = nil
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *Messenger) Close() error {
+	m.once.Do(func() {
+		close(m.closed)
+	})
+
+	return nil
+}
+
+func (m *Messenger) CreateResponder(chatID string) messengerapi.Responder {
+	return &responder{
+		channel:   chatID,
+		messenger: m,
+	}
+}
+
+// CreateThreadResponder creates Responder, which posts messages to thread of channel.
+func (m *Messenger) CreateThreadResponder(chatID, threadID string) messengerapi.Responder {
+	return &responder{
+		channel:   chatID,
+		threadTS:  threadID,
+		messenger: m,
+	}
+}
+
+// Fetch downloads file, which user sent in message.
+func (m *Messenger) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	var resp struct {
+		apiResponse
+		File struct {
+			URLPrivateDownload string `json:"url_private_download"`
+		} `json:"file"`
+	}
+
+	if err := m.api.callForm(ctx, "files.info", url.Values{"file": {fileID}}, &resp); err != nil {
+		return nil, fmt.Errorf("get file info: %w", err)
+	}
+
+	if resp.File.URLPrivateDownload == "" {
+		return nil, messengerapi.ErrNoFile
+	}
+
+	return m.api.download(ctx, resp.File.URLPrivateDownload)
+}
@@ -0,0 +1,280 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type responder struct {
+	channel string
+	// threadTS is timestamp of thread parent message, to which messages are posted, or empty string.
+	threadTS  string
+	messenger *Messenger
+}
+
+type postMessageParams struct {
+	Channel  string  `json:"channel"`
+	TS       string  `json:"ts,omitempty"`
+	ThreadTS string  `json:"thread_ts,omitempty"`
+	Text     string  `json:"text"`
+	Blocks   []block `json:"blocks,omitempty"`
+}
+
+type postMessageResponse struct {
+	apiResponse
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+}
+
+// uploadedFile is file, uploaded by files.getUploadURLExternal.
+type uploadedFile struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// Fetch downloads file by id, e.g. attachment restored from dialog state.
+func (r *responder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return r.messenger.Fetch(ctx, fileID)
+}
+
+func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
+	blocks, err := renderAnswer(answer)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.post("chat.postMessage", &postMessageParams{
+		Channel: r.channel,
+		Text:    answer.Text,
+		Blocks:  blocks,
+	})
+}
+
+func (r *responder) Edit(msgID string, answer *messengerapi.Answer) (messengerapi.Message, error) {
+	blocks, err := renderAnswer(answer)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.post("chat.update", &postMessageParams{
+		Channel: r.channel,
+		TS:      msgID,
+		Text:    answer.Text,
+		Blocks:  blocks,
+	})
+}
+
+func (r *responder) Delete(msgID string) error {
+	var resp apiResponse
+
+	return r.messenger.api.callJSON(context.Background(), "chat.delete", map[string]string{
+		"channel": r.channel,
+		"ts":      msgID,
+	}, &resp)
+}
+
+// EditButtons replaces buttons of sent message. Text of message is loaded from history,
+// because chat.update replaces all blocks of message.
+func (r *responder) EditButtons(msgID string, buttons []messengerapi.Button) error {
+	ctx := context.Background()
+
+	var history struct {
+		apiResponse
+		Messages []struct {
+			Text string `json:"text"`
+		} `json:"messages"`
+	}
+
+	err := r.messenger.api.callForm(ctx, "conversations.history", url.Values{
+		"channel":   {r.channel},
+		"latest":    {msgID},
+		"inclusive": {"true"},
+		"limit":     {"1"},
+	}, &history)
+	if err != nil {
+		return fmt.Errorf("get message: %w", err)
+	}
+
+	if len(history.Messages) == 0 {
+		return fmt.Errorf("message %q not found", msgID)
+	}
+
+	_, err = r.Edit(msgID, &messengerapi.Answer{
+		Text:    history.Messages[0].Text,
+		Buttons: buttons,
+	})
+
+	return err
+}
+
+func (r *responder) RespondObject(object messengerapi.Object) (messengerapi.Message, error) {
+	switch obj := object.(type) {
+	case *messengerapi.Location:
+		text := fmt.Sprintf("https://www.google.com/maps?q=%s,%s",
+			strconv.FormatFloat(obj.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(obj.Longitude, 'f', -1, 64),
+		)
+
+		return r.post("chat.postMessage", &postMessageParams{
+			Channel: r.channel,
+			Text:    text,
+		})
+	case *messengerapi.Photo:
+		if obj.File.URL != "" {
+			return r.post("chat.postMessage", &postMessageParams{
+				Channel: r.channel,
+				Text:    obj.Caption,
+				Blocks: []block{
+					{Type: "image", ImageURL: obj.File.URL, AltText: obj.Caption},
+				},
+			})
+		}
+	case *messengerapi.MediaGroup:
+		if err := obj.Validate(); err != nil {
+			return nil, err
+		}
+
+		items := make([]messengerapi.Object, len(obj.Items))
+		for i, item := range obj.Items {
+			items[i] = item
+		}
+
+		return r.share(items...)
+	}
+
+	return r.share(object)
+}
+
+// share uploads files of objects and shares them to channel in one message.
+// Returned message has no id, because Slack does not return id of shared message.
+func (r *responder) share(objects ...messengerapi.Object) (messengerapi.Message, error) {
+	ctx := context.Background()
+
+	files := make([]uploadedFile, 0, len(objects))
+	var caption string
+
+	for _, object := range objects {
+		file, objCaption, err := objectFile(object)
+		if err != nil {
+			return nil, err
+		}
+
+		if caption == "" {
+			caption = objCaption
+		}
+
+		uploaded, err := r.upload(ctx, file)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, uploaded)
+	}
+
+	var resp apiResponse
+
+	params := map[string]any{
+		"files":           files,
+		"channel_id":      r.channel,
+		"initial_comment": caption,
+	}
+	if r.threadTS != "" {
+		params["thread_ts"] = r.threadTS
+	}
+
+	err := r.messenger.api.callJSON(ctx, "files.completeUploadExternal", params, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("complete upload: %w", err)
+	}
+
+	return &message{
+		chatID: r.channel,
+		text:   caption,
+		sender: &messengerapi.Sender{},
+	}, nil
+}
+
+func (r *responder) upload(ctx context.Context, file messengerapi.File) (uploadedFile, error) {
+	if file.Reader == nil {
+		return uploadedFile{}, errors.New("slack supports only files with reader or photo with url")
+	}
+
+	content, err := io.ReadAll(file.Reader)
+	if err != nil {
+		return uploadedFile{}, fmt.Errorf("read file: %w", err)
+	}
+
+	name := file.Name
+	if name == "" {
+		name = "file"
+	}
+
+	var target struct {
+		apiResponse
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+
+	err = r.messenger.api.callForm(ctx, "files.getUploadURLExternal", url.Values{
+		"filename": {name},
+		"length":   {strconv.Itoa(len(content))},
+	}, &target)
+	if err != nil {
+		return uploadedFile{}, fmt.Errorf("get upload url: %w", err)
+	}
+
+	if err = r.messenger.api.upload(ctx, target.UploadURL, content); err != nil {
+		return uploadedFile{}, err
+	}
+
+	return uploadedFile{ID: target.FileID, Title: name}, nil
+}
+
+func (r *responder) post(method string, params *postMessageParams) (messengerapi.Message, error) {
+	var resp postMessageResponse
+
+	if method == "chat.postMessage" {
+		params.ThreadTS = r.threadTS
+	}
+
+	if err := r.messenger.api.callJSON(context.Background(), method, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &message{
+		id:     resp.TS,
+		chatID: r.channel,
+		text:   params.Text,
+		sender: &messengerapi.Sender{},
+	}, nil
+}
+
+// objectFile returns file and caption of object.
+func objectFile(object messengerapi.Object) (messengerapi.File, string, error) {
+	switch obj := object.(type) {
+	case *messengerapi.LocalImage:
+		return messengerapi.File{Reader: obj.Reader, Name: "image.jpg"}, "", nil
+	case *messengerapi.Photo:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Document:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Video:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Audio:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Voice:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Animation:
+		return obj.File, obj.Caption, nil
+	case *messengerapi.Sticker:
+		return obj.File, "", nil
+	default:
+		return messengerapi.File{}, "", fmt.Errorf("unsupported object type %T", object)
+	}
+}
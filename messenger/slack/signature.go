@@ -0,0 +1,56 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	signatureHeader = "X-Slack-Signature"
+	timestampHeader = "X-Slack-Request-Timestamp"
+	signaturePrefix = "v0="
+	signatureScheme = "v0"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid request signature")
+	ErrRequestExpired   = errors.New("request timestamp expired")
+)
+
+// verifySignature checks signature of request body by signing secret,
+// see https://api.slack.com/authentication/verifying-requests-from-slack.
+// Requests older than maxAge are rejected to prevent replay attacks.
+func verifySignature(secret string, header http.Header, body []byte, now time.Time, maxAge time.Duration) error {
+	timestamp := header.Get(timestampHeader)
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: parse timestamp: %w", ErrInvalidSignature, err)
+	}
+
+	if age := now.Sub(time.Unix(unix, 0)); age > maxAge || age < -maxAge {
+		return ErrRequestExpired
+	}
+
+	expected := sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(signatureHeader)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// sign returns signature of request body in format of X-Slack-Signature header.
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signatureScheme + ":" + timestamp + ":"))
+	mac.Write(body)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package telebot
+
+import (
+	"context"
+	"io"
+	"strconv"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"gopkg.in/telebot.v4"
+)
+
+var _ messengerapi.FileFetcher = &fileFetcher{}
+
+// fileFetcher downloads files from telegram servers.
+type fileFetcher struct {
+	bot *telebot.Bot
+}
+
+func (f *fileFetcher) Fetch(_ context.Context, fileID string) (io.ReadCloser, error) {
+	return f.bot.File(&telebot.File{FileID: fileID})
+}
+
+// adaptAttachments maps media, contact and location of telegram message to attachments.
+func (a *messageAdapter) adaptAttachments(msg *telebot.Message) []*messengerapi.Attachment {
+	attachments := make([]*messengerapi.Attachment, 0)
+
+	file := func(kind messengerapi.AttachmentKind, f telebot.File, name, mime string) {
+		attachments = append(attachments, &messengerapi.Attachment{
+			Kind:     kind,
+			FileID:   f.FileID,
+			FileName: name,
+			MIME:     mime,
+			Size:     f.FileSize,
+			Fetcher:  a.fetcher,
+		})
+	}
+
+	if msg.Photo != nil {
+		file(messengerapi.AttachmentPhoto, msg.Photo.File, "", "")
+	}
+	if msg.Document != nil {
+		file(messengerapi.AttachmentDocument, msg.Document.File, msg.Document.FileName, msg.Document.MIME)
+	}
+	if msg.Video != nil {
+		file(messengerapi.AttachmentVideo, msg.Video.File, msg.Video.FileName, msg.Video.MIME)
+	}
+	if msg.Audio != nil {
+		file(messengerapi.AttachmentAudio, msg.Audio.File, msg.Audio.FileName, msg.Audio.MIME)
+	}
+	if msg.Voice != nil {
+		file(messengerapi.AttachmentVoice, msg.Voice.File, "", msg.Voice.MIME)
+	}
+	if msg.Animation != nil {
+		file(messengerapi.AttachmentAnimation, msg.Animation.File, msg.Animation.FileName, msg.Animation.MIME)
+	}
+	if msg.Sticker != nil {
+		file(messengerapi.AttachmentSticker, msg.Sticker.File, "", "")
+	}
+
+	if msg.Contact != nil {
+		contact := &messengerapi.Contact{
+			PhoneNumber: msg.Contact.PhoneNumber,
+			FirstName:   msg.Contact.FirstName,
+			LastName:    msg.Contact.LastName,
+		}
+		if msg.Contact.UserID != 0 {
+			contact.UserID = strconv.FormatInt(msg.Contact.UserID, 10)
+		}
+
+		attachments = append(attachments, &messengerapi.Attachment{
+			Kind:    messengerapi.AttachmentContact,
+			Contact: contact,
+		})
+	}
+
+	if msg.Location != nil {
+		attachments = append(attachments, &messengerapi.Attachment{
+			Kind: messengerapi.AttachmentLocation,
+			Location: &messengerapi.Location{
+				Latitude:  float64(msg.Location.Lat),
+				Longitude: float64(msg.Location.Lng),
+			},
+		})
+	}
+
+	return attachments
+}
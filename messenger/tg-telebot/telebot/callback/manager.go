@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/artarts36/lowbot/logx"
@@ -13,7 +14,10 @@ import (
 	tele "gopkg.in/telebot.v4"
 )
 
-const deletingQueueSize = 1000
+const (
+	deletingQueueSize    = 1000
+	defaultCleanInterval = 1 * time.Minute
+)
 
 type Manager struct {
 	storage Storage
@@ -21,6 +25,12 @@ type Manager struct {
 	deletingQueue chan *deletingQueueMessage
 	logger        logx.Logger
 	cfg           ManagerConfig
+
+	closed      bool
+	closeMu     sync.RWMutex
+	stopCleaner chan struct{}
+	cleanerDone chan struct{}
+	queueDone   chan struct{}
 }
 
 type ManagerConfig struct {
@@ -45,19 +55,27 @@ func NewManager(
 	if cfg.DeletingQueueSize == 0 {
 		cfg.DeletingQueueSize = deletingQueueSize
 	}
+	if cfg.CleanInterval == 0 {
+		cfg.CleanInterval = defaultCleanInterval
+	}
 
 	m := &Manager{
 		storage:       storage,
 		deletingQueue: make(chan *deletingQueueMessage, cfg.DeletingQueueSize),
 		logger:        logger,
 		cfg:           cfg,
+		stopCleaner:   make(chan struct{}),
+		cleanerDone:   make(chan struct{}),
+		queueDone:     make(chan struct{}),
 	}
 
 	go func() {
+		defer close(m.queueDone)
 		m.listenDeletingQueue()
 	}()
 
 	go func() {
+		defer close(m.cleanerDone)
 		m.cleanUnanswered()
 	}()
 
@@ -82,6 +100,20 @@ func (m *Manager) Find(ctx context.Context, id string) (*Callback, error) {
 }
 
 func (m *Manager) Delete(ctx context.Context, id string) {
+	m.closeMu.RLock()
+	defer m.closeMu.RUnlock()
+
+	if m.closed {
+		if err := m.storage.Delete(ctx, id); err != nil {
+			m.logger.ErrorContext(ctx, "[lowbot][callback-manager] failed to delete callback",
+				slogx.Error(err),
+				slog.String("callback.id", id),
+			)
+		}
+
+		return
+	}
+
 	m.deletingQueue <- &deletingQueueMessage{
 		ID: id,
 	}
@@ -122,9 +154,41 @@ func (m *Manager) listenDeletingQueue() {
 }
 
 func (m *Manager) cleanUnanswered() {
-	for range time.Tick(m.cfg.CleanInterval) {
-		m.deletingQueue <- &deletingQueueMessage{
-			Before: time.Now().Add(-m.cfg.TTL),
+	ticker := time.NewTicker(m.cfg.CleanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.deletingQueue <- &deletingQueueMessage{
+				Before: time.Now().Add(-m.cfg.TTL),
+			}
+		case <-m.stopCleaner:
+			return
 		}
 	}
 }
+
+// Close stops cleaning of unanswered callbacks and flushes deleting queue.
+// Waits until deleting queue flushed or ctx done.
+func (m *Manager) Close(ctx context.Context) error {
+	m.closeMu.Lock()
+	if m.closed {
+		m.closeMu.Unlock()
+		return nil
+	}
+
+	close(m.stopCleaner)
+	<-m.cleanerDone
+
+	m.closed = true
+	close(m.deletingQueue)
+	m.closeMu.Unlock()
+
+	select {
+	case <-m.queueDone:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("flush deleting queue: %w", ctx.Err())
+	}
+}
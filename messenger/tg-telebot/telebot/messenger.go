@@ -0,0 +1,180 @@
+package telebot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/artarts36/lowbot/messenger/tg-telebot/telebot/callback"
+	"github.com/prometheus/client_golang/prometheus"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// botMessenger contains common logic of telegram messengers, independent of updates transport.
+type botMessenger struct {
+	name            string
+	bot             *tele.Bot
+	messageAdapter  *messageAdapter
+	logger          logx.Logger
+	callbackManager *callback.Manager
+	sendQueue       *sendQueue
+}
+
+func newBotMessenger(
+	name string,
+	token string,
+	apiURL string,
+	poller tele.Poller,
+	callbackStorage callback.Storage,
+	callbackManagerConfig callback.ManagerConfig,
+	sendQueueConfig SendQueueConfig,
+	logger logx.Logger,
+) (*botMessenger, error) {
+	if token == "" {
+		return nil, errors.New("missing token")
+	}
+
+	if callbackStorage == nil {
+		callbackStorage = callback.NewMemoryStorage()
+	}
+
+	bot, err := tele.NewBot(tele.Settings{
+		URL:    apiURL,
+		Token:  token,
+		Poller: poller,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create telebot: %w", err)
+	}
+
+	callbackManager := callback.NewManager(callbackManagerConfig, callbackStorage, logger)
+
+	return &botMessenger{
+		name:            name,
+		bot:             bot,
+		messageAdapter:  newMessageAdapter(callbackManager, &fileFetcher{bot: bot}, logger),
+		logger:          logger,
+		callbackManager: callbackManager,
+		sendQueue:       newSendQueue(name, sendQueueConfig),
+	}, nil
+}
+
+func (s *botMessenger) CreateResponder(chatID string) messengerapi.Responder {
+	return newResponder(chatID, s.bot, s.callbackManager, s.messageAdapter, s.sendQueue)
+}
+
+// CreateThreadResponder creates Responder, which sends messages to topic of forum chat.
+func (s *botMessenger) CreateThreadResponder(chatID, threadID string) messengerapi.Responder {
+	r := newResponder(chatID, s.bot, s.callbackManager, s.messageAdapter, s.sendQueue)
+	if threadID == "" {
+		return r
+	}
+
+	topicID, err := strconv.Atoi(threadID)
+	if err != nil {
+		s.logger.WarnContext(
+			context.Background(),
+			fmt.Sprintf("[lowbot][%s] invalid thread id, message will be sent to chat", s.name),
+			slog.String("thread.id", threadID),
+		)
+
+		return r
+	}
+
+	r.topic = &tele.Topic{ThreadID: topicID}
+
+	return r
+}
+
+// Describe implements prometheus.Collector for metrics of send queue.
+func (s *botMessenger) Describe(ch chan<- *prometheus.Desc) {
+	s.sendQueue.cfg.Metrics.Describe(ch)
+}
+
+// Collect implements prometheus.Collector for metrics of send queue.
+func (s *botMessenger) Collect(ch chan<- prometheus.Metric) {
+	s.sendQueue.cfg.Metrics.Collect(ch)
+}
+
+// listen polls updates from bot poller and sends adapted messages to ch.
+// Blocks until poller stopped by stop channel.
+func (s *botMessenger) listen(ch chan messengerapi.Message, stop chan struct{}) {
+	ctx := context.Background()
+
+	s.logger.DebugContext(ctx, fmt.Sprintf("[%s] bot starting", s.name))
+
+	updates := make(chan tele.Update)
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case update := <-updates:
+				s.logger.DebugContext(ctx, fmt.Sprintf("[%s] received update", s.name), slog.Int("update.id", update.ID))
+
+				msg, err := s.adaptUpdate(update)
+				if err != nil {
+					s.logger.ErrorContext(ctx, fmt.Sprintf("[%s] failed to adapt update", s.name), slog.Int("update.id", update.ID))
+					continue
+				}
+
+				ch <- msg
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	s.bot.Poller.Poll(s.bot, updates, stop)
+
+	close(stopped)
+	<-done
+
+	s.logger.DebugContext(ctx, fmt.Sprintf("[%s] bot stopped", s.name))
+}
+
+// closeCallbacks flushes callback manager deleting queue until ctx done.
+func (s *botMessenger) closeCallbacks(ctx context.Context) error {
+	if err := s.callbackManager.Close(ctx); err != nil {
+		return fmt.Errorf("close callback manager: %w", err)
+	}
+
+	return nil
+}
+
+func (s *botMessenger) adaptUpdate(update tele.Update) (*message, error) {
+	teleCtx := tele.NewContext(s.bot, update)
+
+	if update.Message != nil {
+		return s.messageAdapter.AdaptMessage(update.Message), nil
+	}
+
+	if update.Callback != nil {
+		defer func() {
+			rerr := teleCtx.Respond()
+			if rerr != nil {
+				s.logger.ErrorContext(context.Background(), fmt.Sprintf("[lowbot][%s] failed to respond to callback", s.name),
+					slog.Int("update.id", update.ID),
+					slog.Any("err", rerr),
+				)
+			}
+
+			s.messageAdapter.callbackManager.Delete(context.Background(), update.Callback.ID)
+		}()
+
+		msg, err := s.messageAdapter.AdaptCallback(update.Callback)
+		if err != nil {
+			return nil, fmt.Errorf("adapt callback: %w", err)
+		}
+		return msg, nil
+	}
+
+	return nil, errors.New("update not supported")
+}
@@ -7,12 +7,16 @@ import (
 )
 
 type message struct {
-	id     string
-	chatID string
-	text   string
-	sender *messengerapi.Sender
-
-	args *messengerapi.Args
+	id       string
+	chatID   string
+	threadID string
+	text     string
+	sender   *messengerapi.Sender
+
+	args        *messengerapi.Args
+	attachments []*messengerapi.Attachment
+	// callback is true, when message created by pressing button.
+	callback bool
 }
 
 func (m *message) GetID() string {
@@ -23,6 +27,10 @@ func (m *message) GetChatID() string {
 	return m.chatID
 }
 
+func (m *message) GetThreadID() string {
+	return m.threadID
+}
+
 func (m *message) GetBody() string {
 	return m.text
 }
@@ -38,6 +46,14 @@ func (m *message) ExtractCommandName() string {
 	return ""
 }
 
+func (m *message) IsCallback() bool {
+	return m.callback
+}
+
 func (m *message) GetArgs() *messengerapi.Args {
 	return m.args
 }
+
+func (m *message) GetAttachments() []*messengerapi.Attachment {
+	return m.attachments
+}
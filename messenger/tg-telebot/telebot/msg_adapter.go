@@ -17,31 +17,37 @@ import (
 
 type messageAdapter struct {
 	callbackManager *callback.Manager
+	fetcher         *fileFetcher
 	logger          logx.Logger
 }
 
-func newMessageAdapter(callbackManager *callback.Manager, logger logx.Logger) *messageAdapter {
+func newMessageAdapter(callbackManager *callback.Manager, fetcher *fileFetcher, logger logx.Logger) *messageAdapter {
 	return &messageAdapter{
 		callbackManager: callbackManager,
+		fetcher:         fetcher,
 		logger:          logger,
 	}
 }
 
 func (a *messageAdapter) AdaptMessage(msg *telebot.Message) *message {
 	return &message{
-		id:     fmt.Sprintf("%d", msg.ID),
-		chatID: strconv.FormatInt(msg.Chat.ID, 10),
-		text:   msg.Text,
-		sender: a.userToSender(msg.Sender),
+		id:          fmt.Sprintf("%d", msg.ID),
+		chatID:      strconv.FormatInt(msg.Chat.ID, 10),
+		threadID:    a.threadID(msg),
+		text:        a.text(msg),
+		sender:      a.userToSender(msg.Sender),
+		attachments: a.adaptAttachments(msg),
 	}
 }
 
 func (a *messageAdapter) AdaptCallback(clb *telebot.Callback) (*message, error) {
 	msg := &message{
-		id:     clb.ID,
-		chatID: strconv.FormatInt(clb.Message.Chat.ID, 10),
-		text:   clb.Message.Text,
-		sender: a.userToSender(clb.Sender),
+		id:       clb.ID,
+		chatID:   strconv.FormatInt(clb.Message.Chat.ID, 10),
+		threadID: a.threadID(clb.Message),
+		text:     clb.Message.Text,
+		sender:   a.userToSender(clb.Sender),
+		callback: true,
 	}
 
 	ctx := context.Background()
@@ -78,6 +84,23 @@ func (a *messageAdapter) cleanCallbackID(value string) string {
 	return strings.TrimPrefix(value, "\f")
 }
 
+// text returns text of message or caption of media.
+func (a *messageAdapter) text(msg *telebot.Message) string {
+	if msg.Text != "" {
+		return msg.Text
+	}
+
+	return msg.Caption
+}
+
+func (a *messageAdapter) threadID(msg *telebot.Message) string {
+	if msg.ThreadID == 0 {
+		return ""
+	}
+
+	return strconv.Itoa(msg.ThreadID)
+}
+
 func (a *messageAdapter) userToSender(user *telebot.User) *messengerapi.Sender {
 	return &messengerapi.Sender{
 		ID:        strconv.FormatInt(user.ID, 10),
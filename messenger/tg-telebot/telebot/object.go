@@ -0,0 +1,114 @@
+package telebot
+
+import (
+	"fmt"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"gopkg.in/telebot.v4"
+)
+
+// adaptObject maps messengerapi.Object to sendable telebot object.
+func adaptObject(object messengerapi.Object) (interface{}, error) {
+	switch o := object.(type) {
+	case *messengerapi.LocalImage:
+		return &telebot.Photo{
+			File: telebot.File{
+				FileReader: o.Reader,
+			},
+		}, nil
+	case *messengerapi.Voice:
+		return &telebot.Voice{
+			File:     adaptFile(o.File),
+			Caption:  o.Caption,
+			MIME:     o.File.MIME,
+			Duration: int(o.Duration.Seconds()),
+		}, nil
+	case *messengerapi.Animation:
+		return &telebot.Animation{
+			File:     adaptFile(o.File),
+			Caption:  o.Caption,
+			MIME:     o.File.MIME,
+			FileName: o.File.Name,
+		}, nil
+	case *messengerapi.Sticker:
+		return &telebot.Sticker{
+			File: adaptFile(o.File),
+		}, nil
+	case *messengerapi.Location:
+		return &telebot.Location{
+			Lat: float32(o.Latitude),
+			Lng: float32(o.Longitude),
+		}, nil
+	case messengerapi.AlbumItem:
+		return adaptAlbumItem(o)
+	default:
+		return nil, fmt.Errorf("unsupported object type: %T", o)
+	}
+}
+
+func adaptAlbumItem(item messengerapi.AlbumItem) (telebot.Inputtable, error) {
+	switch o := item.(type) {
+	case *messengerapi.Photo:
+		return &telebot.Photo{
+			File:    adaptFile(o.File),
+			Caption: o.Caption,
+		}, nil
+	case *messengerapi.Document:
+		return &telebot.Document{
+			File:     adaptFile(o.File),
+			Caption:  o.Caption,
+			MIME:     o.File.MIME,
+			FileName: o.File.Name,
+		}, nil
+	case *messengerapi.Video:
+		return &telebot.Video{
+			File:     adaptFile(o.File),
+			Caption:  o.Caption,
+			MIME:     o.File.MIME,
+			FileName: o.File.Name,
+			Duration: int(o.Duration.Seconds()),
+		}, nil
+	case *messengerapi.Audio:
+		return &telebot.Audio{
+			File:      adaptFile(o.File),
+			Caption:   o.Caption,
+			Title:     o.Title,
+			Performer: o.Performer,
+			MIME:      o.File.MIME,
+			FileName:  o.File.Name,
+			Duration:  int(o.Duration.Seconds()),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported album item type: %T", o)
+	}
+}
+
+func adaptAlbum(group *messengerapi.MediaGroup) (telebot.Album, error) {
+	if err := group.Validate(); err != nil {
+		return nil, err
+	}
+
+	album := make(telebot.Album, 0, len(group.Items))
+
+	for _, item := range group.Items {
+		input, err := adaptAlbumItem(item)
+		if err != nil {
+			return nil, err
+		}
+
+		album = append(album, input)
+	}
+
+	return album, nil
+}
+
+func adaptFile(file messengerapi.File) telebot.File {
+	switch {
+	case file.ID != "":
+		return telebot.File{FileID: file.ID}
+	case file.URL != "":
+		return telebot.FromURL(file.URL)
+	default:
+		return telebot.FromReader(file.Reader)
+	}
+}
@@ -0,0 +1,140 @@
+package telebot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/artarts36/lowbot/logx"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/artarts36/lowbot/messenger/tg-telebot/telebot/callback"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const defaultPollingTimeout = 10 * time.Second
+
+var (
+	_ messengerapi.Messenger       = &PollingMessenger{}
+	_ messengerapi.ThreadMessenger = &PollingMessenger{}
+)
+
+// PollingMessenger receives updates with telegram long polling.
+// Useful for local development and bots without public URL.
+type PollingMessenger struct {
+	*botMessenger
+
+	cfg PollingConfig
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type PollingConfig struct {
+	// Name of messenger in logs and metrics. Default: "polling-messenger".
+	// Set unique name, when application serves several bots.
+	Name string
+
+	Token string
+	// APIURL is URL of Bot API server, e.g. local Bot API server. Default: https://api.telegram.org.
+	APIURL string
+
+	// Timeout of long polling request. Default: 10s.
+	Timeout time.Duration
+
+	// RemoveWebhook removes previously registered webhook before polling,
+	// because telegram does not return updates with active webhook.
+	RemoveWebhook bool
+
+	CallbackStorage callback.Storage
+	CallbackManager callback.ManagerConfig
+
+	// SendQueue limits outgoing messages by telegram limits.
+	SendQueue SendQueueConfig
+}
+
+func NewPollingMessenger(
+	cfg PollingConfig,
+	logger logx.Logger,
+) (*PollingMessenger, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultPollingTimeout
+	}
+
+	if cfg.Name == "" {
+		cfg.Name = "polling-messenger"
+	}
+
+	msngr, err := newBotMessenger(
+		cfg.Name,
+		cfg.Token,
+		cfg.APIURL,
+		&tele.LongPoller{Timeout: cfg.Timeout},
+		cfg.CallbackStorage,
+		cfg.CallbackManager,
+		cfg.SendQueue,
+		logger,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PollingMessenger{
+		botMessenger: msngr,
+		cfg:          cfg,
+		stop:         make(chan struct{}),
+	}, nil
+}
+
+// ServeHTTP always responds 404, because PollingMessenger does not receive updates by HTTP.
+func (s *PollingMessenger) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// Listen polls updates until Close called.
+func (s *PollingMessenger) Listen(ch chan messengerapi.Message) error {
+	if s.cfg.RemoveWebhook {
+		s.logger.DebugContext(context.Background(), "[polling-messenger] removing webhook")
+
+		if err := s.bot.RemoveWebhook(); err != nil {
+			return fmt.Errorf("remove webhook: %w", err)
+		}
+	}
+
+	s.listen(ch, s.stop)
+
+	return nil
+}
+
+// Close stops polling and waiting sends, flushes callbacks and closes bot.
+func (s *PollingMessenger) Close() error {
+	s.sendQueue.stop()
+
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown stops polling, flushes callbacks until ctx done and closes bot.
+// Sends, which wait for rate limits, are stopped, when ctx done.
+// Repeated calls do nothing.
+func (s *PollingMessenger) Shutdown(ctx context.Context) error {
+	errs := make([]error, 0)
+
+	context.AfterFunc(ctx, s.sendQueue.stop)
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
+
+		if err := s.closeCallbacks(ctx); err != nil {
+			errs = append(errs, err)
+		}
+
+		if _, err := s.bot.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	})
+
+	return errors.Join(errs...)
+}
@@ -2,8 +2,11 @@ package telebot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"strconv"
 
 	"github.com/artarts36/lowbot/messenger/messengerapi"
 	"github.com/artarts36/lowbot/messenger/tg-telebot/telebot/callback"
@@ -11,10 +14,15 @@ import (
 )
 
 type responder struct {
-	recipient       *telebotRecipient
+	recipient *telebotRecipient
+	// topic is thread of chat, to which messages are sent, or nil.
+	topic           *telebot.Topic
 	bot             *telebot.Bot
 	callbackManager *callback.Manager
 	msgAdapter      *messageAdapter
+	queue           *sendQueue
+	// ctx stops waiting of send queue, see WithContext.
+	ctx context.Context
 }
 
 type telebotRecipient struct {
@@ -26,19 +34,35 @@ func newResponder(
 	bot *telebot.Bot,
 	callbackManager *callback.Manager,
 	msgAdapter *messageAdapter,
+	queue *sendQueue,
 ) *responder {
 	return &responder{
 		recipient:       &telebotRecipient{chatID: chatID},
 		bot:             bot,
 		callbackManager: callbackManager,
 		msgAdapter:      msgAdapter,
+		queue:           queue,
+		ctx:             context.Background(),
 	}
 }
 
+// WithContext returns copy of responder, which stops waiting in send queue, when ctx done.
+func (r *responder) WithContext(ctx context.Context) messengerapi.Responder {
+	bound := *r
+	bound.ctx = ctx
+
+	return &bound
+}
+
 func (r *telebotRecipient) Recipient() string {
 	return r.chatID
 }
 
+// Fetch downloads file by id, e.g. attachment restored from dialog state.
+func (r *responder) Fetch(ctx context.Context, fileID string) (io.ReadCloser, error) {
+	return r.msgAdapter.fetcher.Fetch(ctx, fileID)
+}
+
 func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message, error) {
 	var what interface{}
 	var opts []interface{}
@@ -59,7 +83,7 @@ func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message,
 		opts = append(opts, r.buildButtonOpt(answer.Buttons))
 	}
 
-	msg, err := r.bot.Send(r.recipient, what, opts...)
+	msg, err := r.send(what, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -68,20 +92,68 @@ func (r *responder) Respond(answer *messengerapi.Answer) (messengerapi.Message,
 }
 
 func (r *responder) RespondObject(object messengerapi.Object) (messengerapi.Message, error) {
-	var what interface{}
+	if group, ok := object.(*messengerapi.MediaGroup); ok {
+		return r.respondAlbum(group)
+	}
 
-	switch o := object.(type) {
-	case *messengerapi.LocalImage:
-		what = &telebot.Photo{
-			File: telebot.File{
-				FileReader: o.Reader,
-			},
-		}
-	default:
-		return nil, fmt.Errorf("unsupported object type: %T", o)
+	what, err := adaptObject(object)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := r.send(what)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.msgAdapter.AdaptMessage(msg), nil
+}
+
+// respondAlbum sends media group and returns first message of album.
+func (r *responder) respondAlbum(group *messengerapi.MediaGroup) (messengerapi.Message, error) {
+	album, err := adaptAlbum(group)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []telebot.Message
+
+	_, err = r.queue.send(r.ctx, r.recipient.chatID, func() (*telebot.Message, error) {
+		var serr error
+		msgs, serr = r.bot.SendAlbum(r.recipient, album, r.topicOpts()...)
+		return nil, serr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(msgs) == 0 {
+		return nil, errors.New("album sent without messages")
+	}
+
+	return r.msgAdapter.AdaptMessage(&msgs[0]), nil
+}
+
+func (r *responder) Edit(msgID string, answer *messengerapi.Answer) (messengerapi.Message, error) {
+	stored, err := r.storedMessage(msgID)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []interface{}
+
+	// telegram allows only inline keyboard in edited message.
+	if answer.Enum.Valid() {
+		opts = append(opts, r.buildEnumOpt(answer.Enum))
+	}
+
+	if len(answer.Buttons) > 0 {
+		opts = append(opts, r.buildButtonOpt(answer.Buttons))
 	}
 
-	msg, err := r.bot.Send(r.recipient, what)
+	msg, err := r.queue.send(r.ctx, r.recipient.chatID, func() (*telebot.Message, error) {
+		return r.bot.Edit(stored, answer.Text, opts...)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +161,65 @@ func (r *responder) RespondObject(object messengerapi.Object) (messengerapi.Mess
 	return r.msgAdapter.AdaptMessage(msg), nil
 }
 
+func (r *responder) Delete(msgID string) error {
+	stored, err := r.storedMessage(msgID)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.queue.send(r.ctx, r.recipient.chatID, func() (*telebot.Message, error) {
+		return nil, r.bot.Delete(stored)
+	})
+
+	return err
+}
+
+func (r *responder) EditButtons(msgID string, buttons []messengerapi.Button) error {
+	stored, err := r.storedMessage(msgID)
+	if err != nil {
+		return err
+	}
+
+	var markup *telebot.ReplyMarkup
+	if len(buttons) > 0 {
+		markup = r.buildButtonOpt(buttons)
+	}
+
+	_, err = r.queue.send(r.ctx, r.recipient.chatID, func() (*telebot.Message, error) {
+		return r.bot.EditReplyMarkup(stored, markup)
+	})
+
+	return err
+}
+
+func (r *responder) storedMessage(msgID string) (*telebot.StoredMessage, error) {
+	chatID, err := strconv.ParseInt(r.recipient.chatID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse chat id: %w", err)
+	}
+
+	return &telebot.StoredMessage{
+		MessageID: msgID,
+		ChatID:    chatID,
+	}, nil
+}
+
+func (r *responder) send(what interface{}, opts ...interface{}) (*telebot.Message, error) {
+	opts = append(opts, r.topicOpts()...)
+
+	return r.queue.send(r.ctx, r.recipient.chatID, func() (*telebot.Message, error) {
+		return r.bot.Send(r.recipient, what, opts...)
+	})
+}
+
+func (r *responder) topicOpts() []interface{} {
+	if r.topic == nil {
+		return nil
+	}
+
+	return []interface{}{r.topic}
+}
+
 func (r *responder) buildMenuOpt(enum []string) *telebot.ReplyMarkup {
 	menu := &telebot.ReplyMarkup{
 		ResizeKeyboard:  true,
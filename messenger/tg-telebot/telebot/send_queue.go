@@ -0,0 +1,259 @@
+package telebot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/artarts36/lowbot/metrics"
+)
+
+const (
+	// defaultGlobalRate is telegram limit of messages per second for all chats.
+	defaultGlobalRate = 30
+	// defaultChatRate is telegram limit of messages per second for one chat.
+	defaultChatRate   = 1
+	defaultMaxRetries = 3
+	defaultMaxWaiting = 1000
+
+	// maxChatBuckets is count of chat buckets, after which idle buckets are cleaned.
+	maxChatBuckets = 10000
+
+	throttleReasonRateLimit  = "rate_limit"
+	throttleReasonRetryAfter = "retry_after"
+	dropReasonQueueFull      = "queue_full"
+	dropReasonRetryExceeded  = "retries_exceeded"
+	dropReasonStopped        = "stopped"
+	dropReasonCanceled       = "canceled"
+)
+
+var (
+	ErrSendQueueFull = errors.New("send queue is full")
+	// ErrSendQueueStopped is returned for message, which waited for sending, when messenger closed.
+	ErrSendQueueStopped = errors.New("send queue is stopped")
+)
+
+type SendQueueConfig struct {
+	// GlobalRate is max count of messages per second for all chats. Default: 30.
+	GlobalRate float64
+	// ChatRate is max count of messages per second for one chat. Default: 1.
+	ChatRate float64
+	// ChatBurst is count of messages, which can be sent to chat without waiting. Default: 1.
+	ChatBurst int
+	// MaxRetries is max count of retries after telegram flood control error. Default: 3.
+	MaxRetries int
+	// MaxWaiting is max count of messages, which wait for sending. Message is dropped, when limit exceeded. Default: 1000.
+	MaxWaiting int
+	// Metrics of send queue. Created by default, available via messenger prometheus.Collector.
+	Metrics *metrics.SendQueue
+}
+
+// sendQueue limits outgoing messages with token buckets per chat and globally
+// and retries sends after telegram flood control errors.
+type sendQueue struct {
+	messenger string
+	cfg       SendQueueConfig
+
+	mu      sync.Mutex
+	global  *tokenBucket
+	chats   map[string]*tokenBucket
+	waiting int
+
+	// ctx is canceled by stop: waiting sends are stopped.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newSendQueue(messenger string, cfg SendQueueConfig) *sendQueue {
+	if cfg.GlobalRate <= 0 {
+		cfg.GlobalRate = defaultGlobalRate
+	}
+	if cfg.ChatRate <= 0 {
+		cfg.ChatRate = defaultChatRate
+	}
+	if cfg.ChatBurst <= 0 {
+		cfg.ChatBurst = 1
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultMaxRetries
+	}
+	if cfg.MaxWaiting <= 0 {
+		cfg.MaxWaiting = defaultMaxWaiting
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.NewSendQueue()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &sendQueue{
+		messenger: messenger,
+		cfg:       cfg,
+		global:    newTokenBucket(cfg.GlobalRate, cfg.GlobalRate),
+		chats:     map[string]*tokenBucket{},
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// send waits for free slot of rate limits and sends message.
+// Send is retried after telegram flood control error.
+// Throws ErrSendQueueStopped, when queue stopped during waiting, and error of ctx, when ctx done.
+func (q *sendQueue) send(ctx context.Context, chatID string, fn func() (*tele.Message, error)) (*tele.Message, error) {
+	if !q.enqueue() {
+		q.cfg.Metrics.IncDropped(q.messenger, dropReasonQueueFull)
+
+		return nil, ErrSendQueueFull
+	}
+	defer q.dequeue()
+
+	q.cfg.Metrics.IncQueued(q.messenger)
+
+	for attempt := 0; ; attempt++ {
+		if delay := q.reserve(chatID); delay > 0 {
+			q.cfg.Metrics.IncThrottled(q.messenger, throttleReasonRateLimit)
+
+			if err := q.wait(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+
+		msg, err := fn()
+		if err == nil {
+			return msg, nil
+		}
+
+		var floodErr tele.FloodError
+		if !errors.As(err, &floodErr) {
+			return nil, err
+		}
+
+		if attempt >= q.cfg.MaxRetries {
+			q.cfg.Metrics.IncDropped(q.messenger, dropReasonRetryExceeded)
+
+			return nil, err
+		}
+
+		q.cfg.Metrics.IncThrottled(q.messenger, throttleReasonRetryAfter)
+
+		if err = q.wait(ctx, time.Duration(floodErr.RetryAfter)*time.Second); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait waits for delay. Throws ErrSendQueueStopped, when queue stopped before, and error of ctx, when ctx done.
+func (q *sendQueue) wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-q.ctx.Done():
+		q.cfg.Metrics.IncDropped(q.messenger, dropReasonStopped)
+
+		return ErrSendQueueStopped
+	case <-ctx.Done():
+		q.cfg.Metrics.IncDropped(q.messenger, dropReasonCanceled)
+
+		return ctx.Err()
+	}
+}
+
+// stop stops waiting sends. Repeated calls do nothing.
+func (q *sendQueue) stop() {
+	q.cancel()
+}
+
+func (q *sendQueue) enqueue() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting >= q.cfg.MaxWaiting {
+		return false
+	}
+
+	q.waiting++
+
+	return true
+}
+
+func (q *sendQueue) dequeue() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.waiting--
+}
+
+// reserve takes tokens of global and chat buckets and returns delay before sending.
+func (q *sendQueue) reserve(chatID string) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	chat, ok := q.chats[chatID]
+	if !ok {
+		if len(q.chats) >= maxChatBuckets {
+			q.cleanChats(now)
+		}
+
+		chat = newTokenBucket(q.cfg.ChatRate, float64(q.cfg.ChatBurst))
+		q.chats[chatID] = chat
+	}
+
+	return max(q.global.reserve(now), chat.reserve(now))
+}
+
+// cleanChats removes buckets of chats, which are full, because they are equal to new buckets.
+func (q *sendQueue) cleanChats(now time.Time) {
+	for chatID, bucket := range q.chats {
+		if bucket.full(now) {
+			delete(q.chats, chatID)
+		}
+	}
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// reserve takes token and returns delay until token available.
+// Tokens can be negative: it means reserved tokens of waiting senders.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.refill(now)
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+
+	return b.tokens >= b.burst
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log/slog"
 	"net/http"
+	"sync"
 
 	"github.com/artarts36/lowbot/logx"
 
@@ -16,59 +16,71 @@ import (
 	tele "gopkg.in/telebot.v4"
 )
 
-var _ messengerapi.Messenger = &WebhookMessenger{}
+var (
+	_ messengerapi.Messenger       = &WebhookMessenger{}
+	_ messengerapi.ThreadMessenger = &WebhookMessenger{}
+)
 
 type WebhookMessenger struct {
-	httpHandler     *tele.Webhook
-	bot             *tele.Bot
-	messageAdapter  *messageAdapter
-	logger          logx.Logger
-	callbackManager *callback.Manager
+	*botMessenger
+
+	httpHandler *tele.Webhook
+	setWebhook  bool
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type WebhookConfig struct {
+	// Name of messenger in logs and metrics. Default: "webhook-messenger".
+	// Set unique name, when application serves several bots.
+	Name string
+
 	WebhookURL string
 	Token      string
+	// APIURL is URL of Bot API server, e.g. local Bot API server. Default: https://api.telegram.org.
+	APIURL string
 
 	CallbackStorage callback.Storage
 	CallbackManager callback.ManagerConfig
+
+	// SendQueue limits outgoing messages by telegram limits.
+	SendQueue SendQueueConfig
 }
 
 func NewWebhookMessenger(
 	cfg WebhookConfig,
 	logger logx.Logger,
 ) (*WebhookMessenger, error) {
-	if cfg.Token == "" {
-		return nil, errors.New("missing token")
-	}
-
-	if cfg.CallbackStorage == nil {
-		cfg.CallbackStorage = callback.NewMemoryStorage()
-	}
-
+	// webhook is registered by Listen, because tele.Webhook closes stop channel, when registration failed.
 	webhook := &tele.Webhook{
 		Endpoint:         &tele.WebhookEndpoint{PublicURL: cfg.WebhookURL},
-		IgnoreSetWebhook: cfg.WebhookURL == "",
+		IgnoreSetWebhook: true,
 	}
 
-	pref := tele.Settings{
-		Token:  cfg.Token,
-		Poller: webhook,
+	if cfg.Name == "" {
+		cfg.Name = "webhook-messenger"
 	}
 
-	bot, err := tele.NewBot(pref)
+	msngr, err := newBotMessenger(
+		cfg.Name,
+		cfg.Token,
+		cfg.APIURL,
+		webhook,
+		cfg.CallbackStorage,
+		cfg.CallbackManager,
+		cfg.SendQueue,
+		logger,
+	)
 	if err != nil {
-		return nil, fmt.Errorf("create telebot: %w", err)
+		return nil, err
 	}
 
-	callbackManager := callback.NewManager(cfg.CallbackManager, cfg.CallbackStorage, logger)
-
 	return &WebhookMessenger{
-		httpHandler:     webhook,
-		bot:             bot,
-		messageAdapter:  newMessageAdapter(callbackManager, logger),
-		logger:          logger,
-		callbackManager: callbackManager,
+		botMessenger: msngr,
+		httpHandler:  webhook,
+		setWebhook:   cfg.WebhookURL != "",
+		stop:         make(chan struct{}),
 	}, nil
 }
 
@@ -76,66 +88,64 @@ func (s *WebhookMessenger) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	s.httpHandler.ServeHTTP(w, req)
 }
 
+// Listen registers webhook, when WebhookURL set, and receives updates until Close called.
 func (s *WebhookMessenger) Listen(ch chan messengerapi.Message) error {
-	ctx := context.Background()
+	select {
+	case <-s.stop:
+		return nil
+	default:
+	}
 
-	s.logger.DebugContext(ctx, "[webhook-messenger] bot starting")
+	if s.setWebhook {
+		if err := s.bot.SetWebhook(s.httpHandler); err != nil {
+			return fmt.Errorf("set webhook: %w", err)
+		}
+	}
 
-	updates := make(chan tele.Update)
+	// tele.Webhook waits signal on own stop channel and closes it by itself,
+	// so signal is sent once, when messenger closed.
+	pollerStop := make(chan struct{})
+	stopped := make(chan struct{})
 	go func() {
-		for update := range updates {
-			s.logger.DebugContext(ctx, "[webhook-messenger] received update", slog.Int("update.id", update.ID))
-
-			msg, err := s.adaptUpdate(update)
-			if err != nil {
-				s.logger.ErrorContext(ctx, "[webhook-messenger] failed to adapt update", slog.Int("update.id", update.ID))
-				continue
-			}
-
-			ch <- msg
+		select {
+		case <-s.stop:
+			pollerStop <- struct{}{}
+		case <-stopped:
 		}
 	}()
 
-	s.bot.Poller.Poll(s.bot, updates, nil)
+	s.listen(ch, pollerStop)
+	close(stopped)
 
 	return nil
 }
 
-func (s *WebhookMessenger) CreateResponder(chatID string) messengerapi.Responder {
-	return newResponder(chatID, s.bot, s.callbackManager, s.messageAdapter)
-}
-
+// Close stops listening and waiting sends, flushes callbacks and closes bot.
 func (s *WebhookMessenger) Close() error {
-	_, err := s.bot.Close()
-	return err
+	s.sendQueue.stop()
+
+	return s.Shutdown(context.Background())
 }
 
-func (s *WebhookMessenger) adaptUpdate(update tele.Update) (*message, error) {
-	teleCtx := tele.NewContext(s.bot, update)
+// Shutdown stops listening, flushes callbacks until ctx done and closes bot.
+// Sends, which wait for rate limits, are stopped, when ctx done.
+// Repeated calls do nothing.
+func (s *WebhookMessenger) Shutdown(ctx context.Context) error {
+	errs := make([]error, 0)
 
-	if update.Message != nil {
-		return s.messageAdapter.AdaptMessage(update.Message), nil
-	}
+	context.AfterFunc(ctx, s.sendQueue.stop)
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
 
-	if update.Callback != nil {
-		defer func() {
-			rerr := teleCtx.Respond()
-			if rerr != nil {
-				s.logger.ErrorContext(context.Background(), "[lowbot][webhook-messenger] failed to respond to callback",
-					slog.Int("update.id", update.ID),
-					slog.Any("err", rerr),
-				)
-			}
-
-			s.messageAdapter.callbackManager.Delete(context.Background(), update.Callback.ID)
-		}()
-
-		msg, err := s.messageAdapter.AdaptCallback(update.Callback)
-		if err != nil {
-			return nil, fmt.Errorf("adapt callback: %w", err)
+		if err := s.closeCallbacks(ctx); err != nil {
+			errs = append(errs, err)
 		}
-		return msg, nil
-	}
 
-	return nil, errors.New("update not supported")
+		if _, err := s.bot.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	})
+
+	return errors.Join(errs...)
 }
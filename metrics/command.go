@@ -17,6 +17,8 @@ type Command struct {
 	interruptions    *prometheus.CounterVec
 	notFound         prometheus.Counter
 	actionHandled    *prometheus.CounterVec
+	stateConflicts   *prometheus.CounterVec
+	timeouts         *prometheus.CounterVec
 }
 
 func newCommand() *Command {
@@ -65,6 +67,18 @@ func newCommand() *Command {
 			Name:      "action_handled_total",
 			Help:      "Count of Command Action Handled",
 		}, []string{"command", "action", "code"}),
+		stateConflicts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemCommand,
+			Name:      "state_conflicts_total",
+			Help:      "Count of concurrent state modifications",
+		}, []string{"retried"}),
+		timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemCommand,
+			Name:      "timeouts_total",
+			Help:      "Count of expired dialogs",
+		}, []string{"command", "state"}),
 	}
 }
 
@@ -76,6 +90,8 @@ func (g *Command) Describe(ch chan<- *prometheus.Desc) {
 	g.interruptions.Describe(ch)
 	g.notFound.Describe(ch)
 	g.actionHandled.Describe(ch)
+	g.stateConflicts.Describe(ch)
+	g.timeouts.Describe(ch)
 }
 
 func (g *Command) Collect(ch chan<- prometheus.Metric) {
@@ -86,6 +102,8 @@ func (g *Command) Collect(ch chan<- prometheus.Metric) {
 	g.interruptions.Collect(ch)
 	g.notFound.Collect(ch)
 	g.actionHandled.Collect(ch)
+	g.stateConflicts.Collect(ch)
+	g.timeouts.Collect(ch)
 }
 
 func (g *Command) IncFinished(command string) {
@@ -115,3 +133,11 @@ func (g *Command) IncNotFound() {
 func (g *Command) IncActionHandled(command, action string, code string) {
 	g.actionHandled.WithLabelValues(command, action, code).Inc()
 }
+
+func (g *Command) IncStateConflict(retried bool) {
+	g.stateConflicts.WithLabelValues(strconv.FormatBool(retried)).Inc()
+}
+
+func (g *Command) IncTimeout(command, state string) {
+	g.timeouts.WithLabelValues(command, state).Inc()
+}
@@ -9,23 +9,27 @@ const namespace = "lowbot"
 type Group struct {
 	command      *Command
 	stateStorage *StateStorage
+	updates      *Updates
 }
 
 func NewGroup() *Group {
 	return &Group{
 		command:      newCommand(),
 		stateStorage: newStateStorage(),
+		updates:      newUpdates(),
 	}
 }
 
 func (g *Group) Describe(ch chan<- *prometheus.Desc) {
 	g.command.Describe(ch)
 	g.stateStorage.Describe(ch)
+	g.updates.Describe(ch)
 }
 
 func (g *Group) Collect(ch chan<- prometheus.Metric) {
 	g.command.Collect(ch)
 	g.stateStorage.Collect(ch)
+	g.updates.Collect(ch)
 }
 
 func (g *Group) Command() *Command {
@@ -35,3 +39,7 @@ func (g *Group) Command() *Command {
 func (g *Group) StateStorage() *StateStorage {
 	return g.stateStorage
 }
+
+func (g *Group) Updates() *Updates {
+	return g.updates
+}
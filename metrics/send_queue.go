@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystemSendQueue = "send_queue"
+
+type SendQueue struct {
+	queued    *prometheus.CounterVec
+	throttled *prometheus.CounterVec
+	dropped   *prometheus.CounterVec
+}
+
+func NewSendQueue() *SendQueue {
+	return &SendQueue{
+		queued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemSendQueue,
+			Name:      "queued_total",
+			Help:      "Count of outgoing messages, which queued for sending",
+		}, []string{"messenger"}),
+		throttled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemSendQueue,
+			Name:      "throttled_total",
+			Help:      "Count of delayed sends of outgoing messages",
+		}, []string{"messenger", "reason"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemSendQueue,
+			Name:      "dropped_total",
+			Help:      "Count of outgoing messages, which were not sent",
+		}, []string{"messenger", "reason"}),
+	}
+}
+
+func (q *SendQueue) IncQueued(messenger string) {
+	q.queued.WithLabelValues(messenger).Inc()
+}
+
+func (q *SendQueue) IncThrottled(messenger, reason string) {
+	q.throttled.WithLabelValues(messenger, reason).Inc()
+}
+
+func (q *SendQueue) IncDropped(messenger, reason string) {
+	q.dropped.WithLabelValues(messenger, reason).Inc()
+}
+
+func (q *SendQueue) Describe(ch chan<- *prometheus.Desc) {
+	q.queued.Describe(ch)
+	q.throttled.Describe(ch)
+	q.dropped.Describe(ch)
+}
+
+func (q *SendQueue) Collect(ch chan<- prometheus.Metric) {
+	q.queued.Collect(ch)
+	q.throttled.Collect(ch)
+	q.dropped.Collect(ch)
+}
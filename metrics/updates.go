@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const subsystemUpdates = "updates"
+
+type Updates struct {
+	received   *prometheus.CounterVec
+	duplicates *prometheus.CounterVec
+}
+
+func newUpdates() *Updates {
+	return &Updates{
+		received: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemUpdates,
+			Name:      "received_total",
+			Help:      "Count of received updates",
+		}, []string{"messenger"}),
+		duplicates: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystemUpdates,
+			Name:      "duplicates_total",
+			Help:      "Count of skipped redelivered updates",
+		}, []string{"messenger"}),
+	}
+}
+
+func (u *Updates) IncReceived(messenger string) {
+	u.received.WithLabelValues(messenger).Inc()
+}
+
+func (u *Updates) IncDuplicate(messenger string) {
+	u.duplicates.WithLabelValues(messenger).Inc()
+}
+
+func (u *Updates) Describe(ch chan<- *prometheus.Desc) {
+	u.received.Describe(ch)
+	u.duplicates.Describe(ch)
+}
+
+func (u *Updates) Collect(ch chan<- prometheus.Metric) {
+	u.received.Collect(ch)
+	u.duplicates.Collect(ch)
+}
@@ -0,0 +1,45 @@
+package redisstatestorage
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/artarts36/lowbot/engine/dedupe"
+)
+
+var _ dedupe.Store = &DedupeStore{}
+
+// DedupeStore stores keys of handled updates in redis with ttl, so duplicates are skipped by all application replicas.
+type DedupeStore struct {
+	client redis.Cmdable
+	config DedupeConfig
+}
+
+type DedupeConfig struct {
+	KeyPrefix string `env:"KEY_PREFIX" envDefault:"lowbot_dedupe_"`
+}
+
+func NewDedupeStore(
+	client redis.Cmdable,
+	cfg DedupeConfig,
+) *DedupeStore {
+	return &DedupeStore{
+		client: client,
+		config: cfg,
+	}
+}
+
+func (s *DedupeStore) Seen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	set, err := s.client.SetNX(ctx, s.config.KeyPrefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return !set, nil
+}
+
+func (s *DedupeStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.config.KeyPrefix+key).Err()
+}
@@ -0,0 +1,158 @@
+package redisstatestorage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/artarts36/lowbot/engine/schedule"
+)
+
+// dueScript returns payloads of due jobs and leases them.
+// KEYS[1] - queue key, KEYS[2] - jobs key, ARGV[1] - now in milliseconds, ARGV[2] - lease end in milliseconds,
+// ARGV[3] - limit.
+var dueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+local payloads = {}
+
+for _, id in ipairs(ids) do
+	local payload = redis.call('HGET', KEYS[2], id)
+	if payload then
+		redis.call('ZADD', KEYS[1], ARGV[2], id)
+		table.insert(payloads, payload)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+
+return payloads
+`)
+
+// cancelScript removes all jobs of dialog state.
+// KEYS[1] - queue key, KEYS[2] - jobs key, KEYS[3] - state jobs key.
+var cancelScript = redis.NewScript(`
+local ids = redis.call('SMEMBERS', KEYS[3])
+
+for _, id in ipairs(ids) do
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('HDEL', KEYS[2], id)
+end
+
+redis.call('DEL', KEYS[3])
+
+return #ids
+`)
+
+var _ schedule.Store = &ScheduleStore{}
+
+// ScheduleStore stores scheduled jobs in redis:
+// sorted set of job ids by delivery time, hash of job payloads and set of job ids per dialog state.
+// Keys share hash tag {schedule}, so scripts run on single slot of Redis Cluster.
+type ScheduleStore struct {
+	client redis.Cmdable
+	config ScheduleConfig
+}
+
+type ScheduleConfig struct {
+	KeyPrefix string `env:"KEY_PREFIX" envDefault:"lowbot_schedule_"`
+}
+
+func NewScheduleStore(
+	client redis.Cmdable,
+	cfg ScheduleConfig,
+) *ScheduleStore {
+	return &ScheduleStore{
+		client: client,
+		config: cfg,
+	}
+}
+
+func (s *ScheduleStore) Add(ctx context.Context, job *schedule.Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshal job to json: %w", err)
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, s.jobsKey(), job.ID, payload)
+		pipe.SAdd(ctx, s.stateJobsKey(job.StateKey), job.ID)
+		pipe.ZAdd(ctx, s.queueKey(), redis.Z{
+			Score:  float64(job.At.UnixMilli()),
+			Member: job.ID,
+		})
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *ScheduleStore) Due(
+	ctx context.Context,
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*schedule.Job, error) {
+	payloads, err := dueScript.Run(
+		ctx,
+		s.client,
+		[]string{s.queueKey(), s.jobsKey()},
+		now.UnixMilli(),
+		now.Add(lease).UnixMilli(),
+		limit,
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]*schedule.Job, 0, len(payloads))
+	for _, payload := range payloads {
+		var job schedule.Job
+
+		if err = json.Unmarshal([]byte(payload), &job); err != nil {
+			return nil, fmt.Errorf("unmarshal job: %w", err)
+		}
+
+		jobs = append(jobs, &job)
+	}
+
+	return jobs, nil
+}
+
+func (s *ScheduleStore) Ack(ctx context.Context, job *schedule.Job) error {
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, s.queueKey(), job.ID)
+		pipe.HDel(ctx, s.jobsKey(), job.ID)
+		pipe.SRem(ctx, s.stateJobsKey(job.StateKey), job.ID)
+
+		return nil
+	})
+
+	return err
+}
+
+func (s *ScheduleStore) Cancel(ctx context.Context, stateKey string) error {
+	return cancelScript.Run(
+		ctx,
+		s.client,
+		[]string{s.queueKey(), s.jobsKey(), s.stateJobsKey(stateKey)},
+	).Err()
+}
+
+// scheduleHashTag places all keys of store to one slot of Redis Cluster.
+const scheduleHashTag = "{schedule}"
+
+func (s *ScheduleStore) queueKey() string {
+	return s.config.KeyPrefix + scheduleHashTag + "queue"
+}
+
+func (s *ScheduleStore) jobsKey() string {
+	return s.config.KeyPrefix + scheduleHashTag + "jobs"
+}
+
+func (s *ScheduleStore) stateJobsKey(stateKey string) string {
+	return s.config.KeyPrefix + scheduleHashTag + "state_" + stateKey
+}
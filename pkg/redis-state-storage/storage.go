@@ -2,9 +2,7 @@ package redisstatestorage
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -12,6 +10,47 @@ import (
 	"github.com/artarts36/lowbot/engine/state"
 )
 
+// putScript sets state only if stored state has expected version, or stored state is absent and expected version is 0.
+// KEYS[1] - state key, ARGV[1] - state payload, ARGV[2] - expected version, ARGV[3] - ttl in milliseconds.
+var putScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local row = cjson.decode(current)
+	if (row['version'] or 0) ~= tonumber(ARGV[2]) then
+		return 0
+	end
+elseif tonumber(ARGV[2]) ~= 0 then
+	return 0
+end
+
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+
+return 1
+`)
+
+// deleteScript deletes state only if stored state has expected version.
+// Returns -1, when state is absent, 0 on version conflict.
+// KEYS[1] - state key, ARGV[1] - expected version.
+var deleteScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+
+local row = cjson.decode(current)
+if (row['version'] or 0) ~= tonumber(ARGV[1]) then
+	return 0
+end
+
+redis.call('DEL', KEYS[1])
+
+return 1
+`)
+
 type Storage struct {
 	client redis.Cmdable
 	config Config
@@ -36,8 +75,8 @@ func (s *Storage) StorageName() string {
 	return "redis"
 }
 
-func (s *Storage) Get(ctx context.Context, chatID string) (*state.State, error) {
-	payload, err := s.client.Get(ctx, s.key(chatID)).Result()
+func (s *Storage) Get(ctx context.Context, key string) (*state.State, error) {
+	payload, err := s.client.Get(ctx, s.key(key)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return nil, state.ErrStateNotFound
@@ -48,44 +87,58 @@ func (s *Storage) Get(ctx context.Context, chatID string) (*state.State, error)
 		return nil, state.ErrStateNotFound
 	}
 
-	var sRow stateRow
+	return state.Decode([]byte(payload))
+}
 
-	if err = json.Unmarshal([]byte(payload), &sRow); err != nil {
-		return nil, err
-	}
+func (s *Storage) Put(ctx context.Context, st *state.State) error {
+	version := st.Version()
 
-	return sRow.state(), nil
-}
+	// payload contains next version of state.
+	st.SetVersion(version + 1)
+	stateJSON, err := state.Encode(st)
+	st.SetVersion(version)
 
-func (s *Storage) Put(ctx context.Context, state *state.State) error {
-	sRow := &stateRow{}
-	sRow.from(state)
+	if err != nil {
+		return err
+	}
 
-	stateJSON, err := json.Marshal(sRow)
+	saved, err := putScript.Run(
+		ctx,
+		s.client,
+		[]string{s.key(st.Key())},
+		stateJSON,
+		version,
+		s.config.TTL.Milliseconds(),
+	).Int()
 	if err != nil {
-		return fmt.Errorf("marshal state to json: %w", err)
+		return err
+	}
+
+	if saved == 0 {
+		return state.ErrStateConflict
 	}
 
-	_, err = s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
-		key := s.key(state.ChatID())
-		return pipe.Set(ctx, key, stateJSON, s.config.TTL).Err()
-	})
-	return err
+	st.SetVersion(version + 1)
+
+	return nil
 }
 
 func (s *Storage) Delete(ctx context.Context, st *state.State) error {
-	result := s.client.Del(ctx, s.key(st.ChatID()))
-	if result.Err() != nil {
-		return result.Err()
+	deleted, err := deleteScript.Run(ctx, s.client, []string{s.key(st.Key())}, st.Version()).Int()
+	if err != nil {
+		return err
 	}
 
-	if result.Val() == 0 {
+	switch deleted {
+	case -1:
 		return state.ErrStateNotFound
+	case 0:
+		return state.ErrStateConflict
 	}
 
 	return nil
 }
 
-func (s *Storage) key(chatID string) string {
-	return s.config.KeyPrefix + chatID
+func (s *Storage) key(key string) string {
+	return s.config.KeyPrefix + key
 }
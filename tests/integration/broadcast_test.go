@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/messenger/broadcast"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errChatBlocked = errors.New("chat blocked")
+
+// broadcastRecorder records sending times of chats, sending to blocked chats fails.
+type broadcastRecorder struct {
+	blocked map[string]bool
+
+	mu   sync.Mutex
+	sent map[string][]time.Time
+}
+
+func newBroadcastRecorder(blocked ...string) *broadcastRecorder {
+	r := &broadcastRecorder{
+		blocked: map[string]bool{},
+		sent:    map[string][]time.Time{},
+	}
+	for _, chatID := range blocked {
+		r.blocked[chatID] = true
+	}
+
+	return r
+}
+
+func (r *broadcastRecorder) createResponder(chatID string) messengerapi.Responder {
+	return &broadcastResponder{recorder: r, chatID: chatID}
+}
+
+func (r *broadcastRecorder) times(chatID string) []time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.sent[chatID]
+}
+
+type broadcastResponder struct {
+	messengerapi.Responder
+
+	recorder *broadcastRecorder
+	chatID   string
+}
+
+func (r *broadcastResponder) Respond(*messengerapi.Answer) (messengerapi.Message, error) {
+	if r.recorder.blocked[r.chatID] {
+		return nil, errChatBlocked
+	}
+
+	r.recorder.mu.Lock()
+	defer r.recorder.mu.Unlock()
+
+	r.recorder.sent[r.chatID] = append(r.recorder.sent[r.chatID], time.Now())
+
+	return nil, nil
+}
+
+func TestBroadcaster(t *testing.T) {
+	answer := &messengerapi.Answer{Text: "News"}
+
+	t.Run("rate: messages spread by global rate", func(t *testing.T) {
+		recorder := newBroadcastRecorder()
+		broadcaster := broadcast.New(broadcast.Config{Rate: 50}, recorder.createResponder)
+
+		startedAt := time.Now()
+
+		require.NoError(t, broadcaster.Send(context.Background(), []string{"1", "2", "3"}, answer))
+
+		assert.GreaterOrEqual(t, time.Since(startedAt), 40*time.Millisecond)
+		for _, chatID := range []string{"1", "2", "3"} {
+			assert.Len(t, recorder.times(chatID), 1)
+		}
+	})
+
+	t.Run("chat interval: messages to one chat spread by interval", func(t *testing.T) {
+		recorder := newBroadcastRecorder()
+		broadcaster := broadcast.New(broadcast.Config{Rate: 1000, ChatInterval: 50 * time.Millisecond}, recorder.createResponder)
+
+		require.NoError(t, broadcaster.Send(context.Background(), []string{"1"}, answer))
+		require.NoError(t, broadcaster.Send(context.Background(), []string{"1", "2"}, answer))
+
+		times := recorder.times("1")
+		require.Len(t, times, 2)
+		assert.GreaterOrEqual(t, times[1].Sub(times[0]), 45*time.Millisecond)
+		assert.Len(t, recorder.times("2"), 1)
+	})
+
+	t.Run("failed: errors of chats returned", func(t *testing.T) {
+		recorder := newBroadcastRecorder("2")
+		broadcaster := broadcast.New(broadcast.Config{Rate: 1000}, recorder.createResponder)
+
+		err := broadcaster.Send(context.Background(), []string{"1", "2", "3"}, answer)
+
+		var sendErr *broadcast.SendError
+		require.ErrorAs(t, err, &sendErr)
+		assert.Len(t, sendErr.Failed, 1)
+		assert.ErrorIs(t, sendErr.Failed["2"], errChatBlocked)
+		assert.Len(t, recorder.times("1"), 1)
+		assert.Len(t, recorder.times("3"), 1)
+	})
+
+	t.Run("canceled: not sent chats failed by ctx", func(t *testing.T) {
+		recorder := newBroadcastRecorder()
+		broadcaster := broadcast.New(broadcast.Config{Rate: 1}, recorder.createResponder)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		err := broadcaster.Send(ctx, []string{"1", "2", "3"}, answer)
+
+		var sendErr *broadcast.SendError
+		require.ErrorAs(t, err, &sendErr)
+		assert.Len(t, recorder.times("1"), 1)
+		assert.ErrorIs(t, sendErr.Failed["2"], context.DeadlineExceeded)
+		assert.ErrorIs(t, sendErr.Failed["3"], context.DeadlineExceeded)
+	})
+}
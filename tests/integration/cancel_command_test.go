@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// stubbornCommand does not allow interruption and records canceled states.
+type stubbornCommand struct {
+	canceled []string
+}
+
+func (*stubbornCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "stubborn",
+		Description: "Command without interruption",
+	}
+}
+
+func (*stubbornCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("ask", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Enter value"})
+		}).
+		Then("save", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Saved " + req.Message.GetBody()})
+		})
+}
+
+func (*stubbornCommand) Interrupt(context.Context, *command.InterruptRequest) (bool, error) {
+	return false, nil
+}
+
+func (c *stubbornCommand) OnCancel(_ context.Context, req *command.CancelRequest) error {
+	c.canceled = append(c.canceled, req.State.Name())
+	return nil
+}
+
+func TestCancelCommand(t *testing.T) {
+	stubborn := &stubbornCommand{}
+
+	bot := lowbottest.New(t, lowbottest.WithCommands(
+		stubborn,
+		&conversationDeleteCommand{},
+		router.NewCancelCommand("cancel"),
+	))
+
+	t.Run("cancel: aborts dialog bypassing interrupt", func(t *testing.T) {
+		bot.Conversation(t, "chat_1").
+			Send("/stubborn").
+			ExpectText("Enter value").
+			Send("/delete").
+			ExpectText("Saved /delete").
+			Send("/stubborn").
+			ExpectText("Enter value").
+			Send("/cancel").
+			ExpectText("Command /stubborn canceled.").
+			ExpectNoReply()
+
+		assert.Equal(t, []string{"save"}, stubborn.canceled)
+
+		_, err := bot.Storage().Get(context.Background(), state.KeyByChat.Key("chat_1", "", ""))
+		require.ErrorIs(t, err, state.ErrStateNotFound)
+	})
+
+	t.Run("cancel: without active dialog", func(t *testing.T) {
+		bot.Conversation(t, "chat_2").
+			Send("/cancel").
+			ExpectText("No active command to cancel.").
+			ExpectNoReply()
+	})
+
+	t.Run("cancel: next message starts new dialog", func(t *testing.T) {
+		bot.Conversation(t, "chat_3").
+			Send("/delete").
+			ExpectText("Delete user?").
+			Send("/cancel").
+			ExpectText("Command /delete canceled.").
+			Send("true").
+			ExpectText("Command not found.")
+	})
+}
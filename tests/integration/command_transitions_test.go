@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errTransitionSwitch = errors.New("switch failed")
+
+type transitionsCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (transitionsCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "pick",
+		Description: "Pick option",
+	}
+}
+
+func (transitionsCommand) Actions() *command.Actions {
+	reply := func(text string) command.ActionCallback {
+		return func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: text})
+		}
+	}
+
+	return command.NewActions().
+		With("picked.a", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(reply("Picked A"))
+		}).
+		With("picked.b", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(reply("Picked B"))
+		}).
+		Then("ask", reply("Pick option")).
+		Branch("ask.dispatch").
+		On("a", "picked.a").
+		Switch(func(_ context.Context, req *command.Request) (string, error) {
+			switch req.Message.GetBody() {
+			case "b", "B":
+				return "picked.b", nil
+			case "undeclared":
+				return "picked.a.undeclared", nil
+			case "fail":
+				return "", errTransitionSwitch
+			}
+
+			return "", nil
+		}, "picked.b").
+		Then("other", reply("Picked other"))
+}
+
+func TestCommandTransitions(t *testing.T) {
+	bot := lowbottest.New(t, lowbottest.WithCommands(&transitionsCommand{}))
+
+	t.Run("on: value routes to state", func(t *testing.T) {
+		bot.Conversation(t, "chat_1").
+			Send("/pick").
+			ExpectText("Pick option").
+			Send("a").
+			ExpectText("Picked A").
+			ExpectNoError().
+			ExpectNoReply()
+	})
+
+	t.Run("switch: predicate routes to state", func(t *testing.T) {
+		bot.Conversation(t, "chat_2").
+			Send("/pick").
+			ExpectText("Pick option").
+			Send("B").
+			ExpectText("Picked B").
+			ExpectNoReply()
+	})
+
+	t.Run("branch: without transition dialog waits for input of next action", func(t *testing.T) {
+		bot.Conversation(t, "chat_3").
+			Send("/pick").
+			ExpectText("Pick option").
+			Send("c").
+			ExpectNoError().
+			ExpectNoReply().
+			Send("d").
+			ExpectText("Picked other").
+			ExpectNoReply()
+	})
+
+	t.Run("switch: error fails handling", func(t *testing.T) {
+		conv := bot.Conversation(t, "chat_4").
+			Send("/pick").
+			ExpectText("Pick option").
+			Send("fail")
+
+		assert.ErrorIs(t, conv.Err(), errTransitionSwitch)
+	})
+
+	t.Run("switch: undeclared state rejected", func(t *testing.T) {
+		conv := bot.Conversation(t, "chat_5").
+			Send("/pick").
+			ExpectText("Pick option").
+			Send("undeclared")
+
+		assert.ErrorContains(t, conv.Err(), `undeclared state "picked.a.undeclared"`)
+	})
+}
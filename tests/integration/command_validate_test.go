@@ -0,0 +1,226 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/lowbottest"
+)
+
+// graphCommand is command with actions built by test.
+type graphCommand struct {
+	command.AlwaysInterruptCommand
+
+	name    string
+	actions *command.Actions
+}
+
+func (c *graphCommand) Definition() *command.Definition {
+	return &command.Definition{Name: c.name}
+}
+
+func (c *graphCommand) Actions() *command.Actions {
+	return c.actions
+}
+
+func noopAction(context.Context, *command.Request) error {
+	return nil
+}
+
+func forwardAction(target string) command.ActionCallback {
+	return func(_ context.Context, req *command.Request) error {
+		req.State.Forward(target)
+		return nil
+	}
+}
+
+func TestCommandValidate(t *testing.T) {
+	cases := []struct {
+		title   string
+		name    string
+		actions *command.Actions
+		errs    []error
+	}{
+		{
+			title:   "valid: chain of actions",
+			name:    "cmd",
+			actions: command.NewActions().Then("a", noopAction).Then("b", noopAction),
+		},
+		{
+			title: "valid: forward target declared",
+			name:  "cmd",
+			actions: command.NewActions().
+				With("retry", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+					build(noopAction)
+				}).
+				Then("a", forwardAction("retry")).
+				ForwardsTo("retry"),
+		},
+		{
+			title: "valid: state reachable by button",
+			name:  "cmd",
+			actions: command.NewActions().
+				With("button", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+					build(noopAction)
+				}).
+				Then("a", noopAction).
+				Reachable("button"),
+		},
+		{
+			title: "valid: return state declared by calls",
+			name:  "cmd",
+			actions: command.NewActions().
+				With("returned", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+					build(noopAction)
+				}).
+				Then("a", noopAction).
+				Calls("sub", "returned"),
+		},
+		{
+			title:   "invalid: empty name",
+			actions: command.NewActions().Then("a", noopAction),
+			errs:    []error{command.ErrEmptyCommandName},
+		},
+		{
+			title:   "invalid: no actions",
+			name:    "cmd",
+			actions: command.NewActions(),
+			errs:    []error{command.ErrNoActions},
+		},
+		{
+			title:   "invalid: duplicate state",
+			name:    "cmd",
+			actions: command.NewActions().Then("a", noopAction).Then("a", noopAction),
+			errs:    []error{command.ErrDuplicateState},
+		},
+		{
+			title:   "invalid: reserved state",
+			name:    "cmd",
+			actions: command.NewActions().Then(state.Passthrough, noopAction),
+			errs:    []error{command.ErrReservedState},
+		},
+		{
+			title:   "invalid: transition to unknown state",
+			name:    "cmd",
+			actions: command.NewActions().Branch("a").On("yes", "missing"),
+			errs:    []error{command.ErrUnknownState},
+		},
+		{
+			title:   "invalid: return to unknown state",
+			name:    "cmd",
+			actions: command.NewActions().Then("a", noopAction).Calls("sub", "missing"),
+			errs:    []error{command.ErrUnknownState},
+		},
+		{
+			title: "valid: state reached only by undeclared forward",
+			name:  "cmd",
+			actions: command.NewActions().
+				With("retry", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+					build(noopAction)
+				}).
+				Then("a", forwardAction("retry")),
+		},
+		{
+			title: "invalid: cycle without input",
+			name:  "cmd",
+			actions: command.NewActions().
+				Branch("a").On("yes", "b").
+				Branch("b").On("yes", "a"),
+			errs: []error{command.ErrInputlessCycle},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.title, func(t *testing.T) {
+			err := command.Validate(&graphCommand{name: tc.name, actions: tc.actions})
+			if len(tc.errs) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			for _, target := range tc.errs {
+				assert.ErrorIs(t, err, target)
+			}
+		})
+	}
+
+	t.Run("warnings: state reached only by undeclared forward is unreachable", func(t *testing.T) {
+		undeclared := command.NewActions().
+			With("retry", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+				build(noopAction)
+			}).
+			Then("a", forwardAction("retry"))
+
+		warnings := command.Warnings(&graphCommand{name: "cmd", actions: undeclared})
+		require.Len(t, warnings, 1)
+		assert.ErrorIs(t, warnings[0], command.ErrUnreachableState)
+
+		declared := command.NewActions().
+			With("retry", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+				build(noopAction)
+			}).
+			Then("a", forwardAction("retry")).
+			ForwardsTo("retry")
+
+		assert.Empty(t, command.Warnings(&graphCommand{name: "cmd", actions: declared}))
+	})
+
+	t.Run("webhookapp: command with unreachable state registered, warning logged", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+
+		app, err := webhookapp.New(lowbottest.NewMessenger(),
+			webhookapp.WithoutWebhook(),
+			webhookapp.WithPrometheus(prometheus.NewRegistry()),
+			webhookapp.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+		)
+		require.NoError(t, err)
+
+		require.NoError(t, app.AddCommand(&graphCommand{
+			name: "cmd",
+			actions: command.NewActions().
+				With("retry", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+					build(noopAction)
+				}).
+				Then("a", forwardAction("retry")),
+		}))
+
+		assert.Contains(t, logs.String(), "unreachable state")
+	})
+
+	t.Run("router: invalid command rejected", func(t *testing.T) {
+		r := router.NewMapStaticRouter()
+
+		err := r.Add(&graphCommand{name: "cmd", actions: command.NewActions()})
+		require.ErrorIs(t, err, router.ErrInvalidCommand)
+		assert.ErrorIs(t, err, command.ErrNoActions)
+
+		_, err = r.Find("cmd")
+		assert.ErrorIs(t, err, router.ErrCommandNotFound)
+	})
+
+	t.Run("router: called command must be registered", func(t *testing.T) {
+		r := router.NewMapStaticRouter()
+
+		caller := &graphCommand{name: "caller", actions: command.NewActions().Then("a", noopAction).Calls("sub", "a")}
+
+		err := r.Add(caller)
+		require.ErrorIs(t, err, router.ErrInvalidCommand)
+		assert.ErrorIs(t, err, command.ErrUnknownCommand)
+
+		require.NoError(t, r.Add(&graphCommand{name: "sub", actions: command.NewActions().Then("a", noopAction)}))
+		require.NoError(t, r.Add(caller))
+
+		recursive := &graphCommand{name: "recursive", actions: command.NewActions().Then("a", noopAction).Calls("recursive", "a")}
+		require.NoError(t, r.Add(recursive))
+	})
+}
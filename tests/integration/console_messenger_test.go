@@ -0,0 +1,169 @@
+package integration
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/messenger/console"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// syncBuffer is output of console messenger, which read by test concurrently.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestConsoleMessenger(t *testing.T) {
+	// start runs messenger and returns function, which types line and returns received message.
+	start := func(t *testing.T) (*console.Messenger, *syncBuffer, func(line string) messengerapi.Message) {
+		t.Helper()
+
+		in, writer := io.Pipe()
+		out := &syncBuffer{}
+
+		msngr := console.NewMessenger(console.Config{In: in, Out: out, FilesDir: t.TempDir()}, slog.Default())
+
+		ch := make(chan messengerapi.Message)
+		done := make(chan error, 1)
+		go func() {
+			done <- msngr.Listen(ch)
+		}()
+		t.Cleanup(func() {
+			require.NoError(t, msngr.Close())
+			require.NoError(t, writer.Close())
+			require.NoError(t, <-done)
+		})
+
+		typeLine := func(line string) messengerapi.Message {
+			t.Helper()
+
+			_, err := io.WriteString(writer, line+"\n")
+			require.NoError(t, err)
+
+			select {
+			case msg := <-ch:
+				return msg
+			case <-time.After(time.Second):
+				t.Fatalf("message %q not received", line)
+				return nil
+			}
+		}
+
+		return msngr, out, typeLine
+	}
+
+	enumAnswer := &messengerapi.Answer{
+		Text: "Delete user?",
+		Enum: messengerapi.Enum{Values: []messengerapi.EnumItem{
+			{Value: "true", Title: "Yes"},
+			{Value: "false", Title: "No"},
+		}},
+	}
+
+	t.Run("choice: enum item picked by number", func(t *testing.T) {
+		msngr, out, typeLine := start(t)
+
+		_, err := msngr.CreateResponder("console").Respond(enumAnswer)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "Delete user?\n  1) Yes\n  2) No")
+
+		msg := typeLine("2")
+		assert.Equal(t, "false", msg.GetBody())
+		assert.True(t, msg.(messengerapi.CallbackMessage).IsCallback())
+	})
+
+	t.Run("choice: cleared by answer without choices", func(t *testing.T) {
+		msngr, _, typeLine := start(t)
+		responder := msngr.CreateResponder("console")
+
+		_, err := responder.Respond(enumAnswer)
+		require.NoError(t, err)
+		_, err = responder.Respond(&messengerapi.Answer{Text: "Enter count"})
+		require.NoError(t, err)
+
+		msg := typeLine("1")
+		assert.Equal(t, "1", msg.GetBody())
+		assert.False(t, msg.(messengerapi.CallbackMessage).IsCallback())
+	})
+
+	t.Run("choice: cleared by edit without choices", func(t *testing.T) {
+		msngr, out, typeLine := start(t)
+		responder := msngr.CreateResponder("console")
+
+		sent, err := responder.Respond(enumAnswer)
+		require.NoError(t, err)
+		_, err = responder.Edit(sent.GetID(), &messengerapi.Answer{Text: "User deleted"})
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "[bot #"+sent.GetID()+" edited] User deleted")
+
+		assert.Equal(t, "1", typeLine("1").GetBody())
+	})
+
+	t.Run("choice: buttons replaced and removed", func(t *testing.T) {
+		msngr, _, typeLine := start(t)
+		responder := msngr.CreateResponder("console")
+
+		sent, err := responder.Respond(enumAnswer)
+		require.NoError(t, err)
+
+		require.NoError(t, responder.EditButtons(sent.GetID(), []messengerapi.Button{
+			&messengerapi.CommandButton{Title: "Delete another", Args: messengerapi.Args{CommandName: "delete"}},
+		}))
+
+		msg := typeLine("1")
+		require.NotNil(t, msg.GetArgs())
+		assert.Equal(t, "delete", msg.GetArgs().CommandName)
+
+		require.NoError(t, responder.EditButtons(sent.GetID(), nil))
+
+		msg = typeLine("1")
+		assert.Equal(t, "1", msg.GetBody())
+		assert.Nil(t, msg.GetArgs())
+	})
+
+	t.Run("attachment: file sent by path", func(t *testing.T) {
+		_, _, typeLine := start(t)
+
+		path := filepath.Join(t.TempDir(), "report.txt")
+		require.NoError(t, os.WriteFile(path, []byte("total: 3"), 0o600))
+
+		msg := typeLine("!document " + path + " monthly report")
+		assert.Equal(t, "monthly report", msg.GetBody())
+
+		attachment := messengerapi.FindAttachment(msg, messengerapi.AttachmentDocument)
+		require.NotNil(t, attachment)
+		assert.Equal(t, "report.txt", attachment.FileName)
+
+		file, err := attachment.Open(t.Context())
+		require.NoError(t, err)
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "total: 3", string(content))
+	})
+}
@@ -0,0 +1,332 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/dispatcher"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errFailedTask = errors.New("task failed")
+
+// doneMessage records error, with which dispatcher rejects message.
+type doneMessage struct {
+	lowbottest.Message
+
+	err chan error
+}
+
+func newDoneMessage(id string) *doneMessage {
+	return &doneMessage{
+		Message: lowbottest.Message{ID: id, ChatID: "chat"},
+		err:     make(chan error, 1),
+	}
+}
+
+func (m *doneMessage) Done(err error) {
+	m.err <- err
+}
+
+// runDispatcher runs dispatcher until test finished.
+func runDispatcher(t *testing.T, cfg dispatcher.Config, handler dispatcher.Handler) *dispatcher.Dispatcher {
+	d := dispatcher.New(cfg, handler, slog.Default())
+	ch := make(chan messengerapi.Message)
+
+	go d.Run(ch)
+
+	t.Cleanup(func() {
+		close(ch)
+		_, _ = d.Wait(context.Background())
+	})
+
+	return d
+}
+
+// chatsOfDifferentWorkers returns two chat ids, which handled by different workers.
+func chatsOfDifferentWorkers(workers int) (string, string) {
+	shard := func(chatID string) uint32 {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(chatID))
+		return h.Sum32() % uint32(workers) //nolint:gosec // workers count is positive
+	}
+
+	for i := 1; ; i++ {
+		chatID := "chat_" + strconv.Itoa(i)
+		if shard(chatID) != shard("chat_0") {
+			return "chat_0", chatID
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	t.Run("order: messages of chat handled in order", func(t *testing.T) {
+		const chats, perChat = 10, 50
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		handled := make(map[string][]string)
+
+		d := runDispatcher(t, dispatcher.Config{Workers: 3}, func(_ context.Context, msg messengerapi.Message) {
+			defer wg.Done()
+
+			mu.Lock()
+			handled[msg.GetChatID()] = append(handled[msg.GetChatID()], msg.GetID())
+			mu.Unlock()
+		})
+
+		wg.Add(chats * perChat)
+		for i := range perChat {
+			for chat := range chats {
+				require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{
+					ID:     strconv.Itoa(i),
+					ChatID: fmt.Sprintf("chat_%d", chat),
+				}))
+			}
+		}
+		wg.Wait()
+
+		for chat := range chats {
+			ids := handled[fmt.Sprintf("chat_%d", chat)]
+			require.Len(t, ids, perChat)
+			for i, id := range ids {
+				assert.Equal(t, strconv.Itoa(i), id)
+			}
+		}
+	})
+
+	t.Run("parallel: slow chat does not block other chats", func(t *testing.T) {
+		slowChat, fastChat := chatsOfDifferentWorkers(2)
+		release := make(chan struct{})
+		fastHandled := make(chan struct{})
+
+		d := runDispatcher(t, dispatcher.Config{Workers: 2}, func(_ context.Context, msg messengerapi.Message) {
+			if msg.GetChatID() == slowChat {
+				<-release
+				return
+			}
+			close(fastHandled)
+		})
+		defer close(release)
+
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "1", ChatID: slowChat}))
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "2", ChatID: fastChat}))
+
+		select {
+		case <-fastHandled:
+		case <-time.After(time.Second):
+			t.Fatal("message of fast chat is not handled")
+		}
+	})
+
+	// fillQueue dispatches message, which blocks worker, and message, which fills queue of size 1.
+	fillQueue := func(t *testing.T, backpressure dispatcher.Backpressure) (*dispatcher.Dispatcher, chan struct{}) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		d := runDispatcher(t, dispatcher.Config{
+			Workers:      1,
+			QueueSize:    1,
+			Backpressure: backpressure,
+		}, func(context.Context, messengerapi.Message) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		})
+
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "1", ChatID: "chat"}))
+		<-started
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "2", ChatID: "chat"}))
+
+		return d, release
+	}
+
+	t.Run("backpressure: drop returns ErrQueueFull", func(t *testing.T) {
+		d, release := fillQueue(t, dispatcher.BackpressureDrop)
+		defer close(release)
+
+		err := d.Dispatch(context.Background(), &lowbottest.Message{ID: "3", ChatID: "chat"})
+		require.ErrorIs(t, err, dispatcher.ErrQueueFull)
+	})
+
+	t.Run("backpressure: drop rejects DoneMessage of channel with ErrQueueFull", func(t *testing.T) {
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+
+		d := dispatcher.New(dispatcher.Config{
+			Workers:      1,
+			QueueSize:    1,
+			Backpressure: dispatcher.BackpressureDrop,
+		}, func(context.Context, messengerapi.Message) {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+			<-release
+		}, slog.Default())
+
+		ch := make(chan messengerapi.Message)
+		go d.Run(ch)
+		defer func() {
+			close(ch)
+			_, _ = d.Wait(context.Background())
+		}()
+		defer close(release)
+
+		ch <- &lowbottest.Message{ID: "1", ChatID: "chat"}
+		<-started
+		ch <- &lowbottest.Message{ID: "2", ChatID: "chat"}
+
+		msg := newDoneMessage("3")
+		ch <- msg
+
+		select {
+		case err := <-msg.err:
+			require.ErrorIs(t, err, dispatcher.ErrQueueFull)
+		case <-time.After(time.Second):
+			t.Fatal("message is not rejected")
+		}
+	})
+
+	t.Run("backpressure: block waits for free place until ctx done", func(t *testing.T) {
+		d, release := fillQueue(t, dispatcher.BackpressureBlock)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := d.Dispatch(ctx, &lowbottest.Message{ID: "3", ChatID: "chat"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "4", ChatID: "chat"}))
+	})
+
+	t.Run("wait: returns nothing, when messages handled", func(t *testing.T) {
+		var handled []string
+
+		d := dispatcher.New(dispatcher.Config{Workers: 2}, func(_ context.Context, msg messengerapi.Message) {
+			handled = append(handled, msg.GetID())
+		}, slog.Default())
+
+		ch := make(chan messengerapi.Message)
+		go d.Run(ch)
+
+		ch <- &lowbottest.Message{ID: "1", ChatID: "chat"}
+		ch <- &lowbottest.Message{ID: "2", ChatID: "chat"}
+		close(ch)
+
+		abandoned, err := d.Wait(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, abandoned)
+		assert.Equal(t, []string{"1", "2"}, handled)
+	})
+
+	t.Run("wait: returns abandoned messages and cancels handlers, when ctx done", func(t *testing.T) {
+		started := make(chan struct{})
+		canceled := make(chan struct{})
+
+		d := runDispatcher(t, dispatcher.Config{Workers: 1}, func(ctx context.Context, msg messengerapi.Message) {
+			if msg.GetID() != "1" {
+				return
+			}
+
+			close(started)
+			<-ctx.Done()
+			close(canceled)
+		})
+
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "1", ChatID: "chat"}))
+		<-started
+		queued := newDoneMessage("2")
+		require.NoError(t, d.Dispatch(context.Background(), queued))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		abandoned, err := d.Wait(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.ErrorIs(t, <-queued.err, dispatcher.ErrAbandoned)
+
+		ids := make([]string, 0, len(abandoned))
+		for _, msg := range abandoned {
+			ids = append(ids, msg.GetID())
+		}
+		assert.ElementsMatch(t, []string{"1", "2"}, ids)
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("context of handler is not canceled")
+		}
+	})
+
+	t.Run("do: task runs after queued messages of chat", func(t *testing.T) {
+		release := make(chan struct{})
+		var handled []string
+
+		d := runDispatcher(t, dispatcher.Config{Workers: 1}, func(_ context.Context, msg messengerapi.Message) {
+			<-release
+			handled = append(handled, msg.GetID())
+		})
+
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "1", ChatID: "chat"}))
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "2", ChatID: "chat"}))
+		close(release)
+
+		err := d.Do(context.Background(), "chat", func(context.Context) error {
+			handled = append(handled, "task")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "task"}, handled)
+	})
+
+	t.Run("do: task of same worker runs immediately from handler", func(t *testing.T) {
+		var d *dispatcher.Dispatcher
+		done := make(chan error, 1)
+
+		d = runDispatcher(t, dispatcher.Config{Workers: 1}, func(ctx context.Context, msg messengerapi.Message) {
+			done <- d.Do(ctx, msg.GetChatID(), func(context.Context) error {
+				return errFailedTask
+			})
+		})
+
+		require.NoError(t, d.Dispatch(context.Background(), &lowbottest.Message{ID: "1", ChatID: "chat"}))
+
+		select {
+		case err := <-done:
+			require.ErrorIs(t, err, errFailedTask)
+		case <-time.After(time.Second):
+			t.Fatal("task is not run")
+		}
+	})
+
+	t.Run("do: returns ErrStopped, when dispatcher stopped", func(t *testing.T) {
+		d := dispatcher.New(dispatcher.Config{Workers: 1}, func(context.Context, messengerapi.Message) {}, slog.Default())
+		ch := make(chan messengerapi.Message)
+
+		go d.Run(ch)
+		close(ch)
+
+		_, err := d.Wait(context.Background())
+		require.NoError(t, err)
+
+		err = d.Do(context.Background(), "chat", func(context.Context) error {
+			return nil
+		})
+		require.ErrorIs(t, err, dispatcher.ErrStopped)
+	})
+}
@@ -0,0 +1,183 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/command/form"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errNameLookup = errors.New("name lookup: connection refused")
+
+type signupForm struct {
+	Name     string
+	Email    string
+	Age      int
+	Plan     string
+	Birthday time.Time
+}
+
+type signupCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (signupCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "signup",
+		Description: "Sign up",
+	}
+}
+
+func (signupCommand) Actions() *command.Actions {
+	return form.New[signupForm]("signup").
+		Text("name", "Enter name", func(f *signupForm, name string) {
+			f.Name = name
+		}, func(name string) error {
+			if name == "broken" {
+				return errNameLookup
+			}
+
+			if len(name) < 3 {
+				return command.NewInvalidArgumentError("Name is too short.")
+			}
+
+			return nil
+		}).
+		Email("email", "Enter email", func(f *signupForm, email string) {
+			f.Email = email
+		}).
+		Int("age", "Enter age", func(f *signupForm, age int) {
+			f.Age = age
+		}).
+		Enum("plan", "Select plan", messengerapi.EnumFromList([]string{"free", "pro"}), func(f *signupForm, plan string) {
+			f.Plan = plan
+		}).
+		Date("birthday", "Enter birthday", func(f *signupForm, birthday time.Time) {
+			f.Birthday = birthday
+		}).
+		Submit(func(_ context.Context, req *command.Request, f *signupForm) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: fmt.Sprintf("%s <%s>, %d, %s, %s", f.Name, f.Email, f.Age, f.Plan, f.Birthday.Format(time.DateOnly)),
+			})
+		}).
+		Actions()
+}
+
+type transferForm struct {
+	Account string
+}
+
+// transferCommand collects accounts by two forms and counts validations.
+type transferCommand struct {
+	command.AlwaysInterruptCommand
+
+	validations int
+}
+
+func (*transferCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "transfer",
+		Description: "Transfer money",
+	}
+}
+
+func (c *transferCommand) Actions() *command.Actions {
+	validate := func(string) error {
+		c.validations++
+		return nil
+	}
+
+	source := form.New[transferForm]("source").
+		Text("account", "Enter source account", func(f *transferForm, account string) {
+			f.Account = account
+		}, validate).
+		Submit(func(_ context.Context, req *command.Request, f *transferForm) error {
+			req.State.Set("source", f.Account)
+			return req.State.Passthrough()
+		})
+
+	return form.New[transferForm]("target").
+		Text("account", "Enter target account", func(f *transferForm, account string) {
+			f.Account = account
+		}, validate).
+		Submit(func(_ context.Context, req *command.Request, f *transferForm) error {
+			return req.Respond(&messengerapi.Answer{Text: req.State.Get("source") + " -> " + f.Account})
+		}).
+		AppendTo(source.Actions())
+}
+
+func TestForm(t *testing.T) {
+	bot := lowbottest.New(t, lowbottest.WithCommands(&signupCommand{}))
+
+	t.Run("fields: invalid values asked again", func(t *testing.T) {
+		bot.Conversation(t, "chat_1").
+			Send("/signup").
+			ExpectText("Enter name").
+			Send(" ").
+			ExpectText("Value must not be empty.\nEnter name").
+			Send("al").
+			ExpectText("Name is too short.\nEnter name").
+			Send("alice").
+			ExpectText("Enter email").
+			Send("alice").
+			ExpectText("Invalid email.\nEnter email").
+			Send("Alice <alice@example.com>").
+			ExpectText("Enter age").
+			Send("old").
+			ExpectText("Value must be integer.\nEnter age").
+			Send("30").
+			ExpectText("Select plan").
+			ExpectButtons("free", "pro").
+			Send("enterprise").
+			ExpectText("Select one of values.\nSelect plan").
+			ExpectButtons("free", "pro").
+			Press("pro").
+			ExpectText("Enter birthday").
+			Send("01.02.1995").
+			ExpectText("Date must be in format 2006-01-02.\nEnter birthday").
+			Send("1995-02-01").
+			ExpectText("alice <alice@example.com>, 30, pro, 1995-02-01").
+			ExpectNoReply()
+	})
+
+	t.Run("validator: error without text not sent to user", func(t *testing.T) {
+		conv := bot.Conversation(t, "chat_2").
+			Send("/signup").
+			ExpectText("Enter name").
+			Send("broken")
+
+		require.ErrorIs(t, conv.Err(), errNameLookup)
+
+		for _, reply := range bot.Messenger().Replies("chat_2") {
+			assert.False(t, strings.Contains(reply.Answer.Text, errNameLookup.Error()))
+		}
+
+		conv.Send("alice").ExpectText("Enter email")
+	})
+
+	t.Run("forms: command contains several forms, values validated once", func(t *testing.T) {
+		transfer := &transferCommand{}
+
+		lowbottest.New(t, lowbottest.WithCommands(transfer)).
+			Conversation(t, "chat_3").
+			Send("/transfer").
+			ExpectText("Enter source account").
+			Send("A1").
+			ExpectText("Enter target account").
+			Send("B2").
+			ExpectText("A1 -> B2").
+			ExpectNoError()
+
+		assert.Equal(t, 2, transfer.validations)
+	})
+}
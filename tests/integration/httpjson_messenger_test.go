@@ -0,0 +1,345 @@
+package integration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/dispatcher"
+	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/messenger/httpjson"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const httpJSONToken = "widget-secret"
+
+func TestHTTPJSONMessenger(t *testing.T) {
+	msngr, err := httpjson.NewMessenger(httpjson.Config{
+		Token:       httpJSONToken,
+		PollTimeout: time.Second,
+	}, slog.Default())
+	require.NoError(t, err)
+
+	app, err := webhookapp.New(msngr,
+		webhookapp.WithoutWebhook(),
+		webhookapp.WithPrometheus(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+	require.NoError(t, app.AddCommand(&conversationAddCommand{}))
+	require.NoError(t, app.AddCommand(&conversationDeleteCommand{}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, app.Close())
+		require.NoError(t, <-done)
+	})
+
+	server := httptest.NewServer(msngr)
+	t.Cleanup(server.Close)
+
+	request := func(method, query string, body any) *http.Response {
+		var reader io.Reader
+		if body != nil {
+			encoded, err := json.Marshal(body)
+			require.NoError(t, err)
+			reader = strings.NewReader(string(encoded))
+		}
+
+		req, err := http.NewRequest(method, server.URL+query, reader)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+httpJSONToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			resp.Body.Close()
+		})
+
+		return resp
+	}
+
+	send := func(in httpjson.IncomingMessage) httpjson.PostResponse {
+		in.Wait = true
+
+		var resp httpjson.PostResponse
+		require.Eventually(t, func() bool {
+			httpResp := request(http.MethodPost, "/", in)
+			if httpResp.StatusCode != http.StatusOK {
+				return false
+			}
+			require.NoError(t, json.NewDecoder(httpResp.Body).Decode(&resp))
+			return true
+		}, time.Second, 10*time.Millisecond, "messenger is not listening")
+
+		return resp
+	}
+
+	poll := func(query string) httpjson.PollResponse {
+		httpResp := request(http.MethodGet, query, nil)
+		require.Equal(t, http.StatusOK, httpResp.StatusCode)
+
+		var resp httpjson.PollResponse
+		require.NoError(t, json.NewDecoder(httpResp.Body).Decode(&resp))
+
+		return resp
+	}
+
+	t.Run("auth: request without token rejected", func(t *testing.T) {
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(`{"chat_id":"c1","text":"/add"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("sync: replies in response, enum picked by callback", func(t *testing.T) {
+		resp := send(httpjson.IncomingMessage{ChatID: "c1", Text: "/add"})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, httpjson.EventMessage, resp.Events[0].Type)
+		assert.Equal(t, "Enter user name", resp.Events[0].Answer.Text)
+
+		resp = send(httpjson.IncomingMessage{ChatID: "c1", Text: "bob"})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Select user type", resp.Events[0].Answer.Text)
+		assert.Equal(t, []httpjson.EnumItem{
+			{Value: "internal", Title: "internal"},
+			{Value: "external", Title: "external"},
+		}, resp.Events[0].Answer.Enum)
+
+		resp = send(httpjson.IncomingMessage{ChatID: "c1", Text: "external", Callback: true})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Send avatar", resp.Events[0].Answer.Text)
+	})
+
+	t.Run("sync: replaced prompt delivered as edit", func(t *testing.T) {
+		resp := send(httpjson.IncomingMessage{ChatID: "c2", Text: "/delete"})
+		require.Len(t, resp.Events, 1)
+		prompt := resp.Events[0]
+
+		resp = send(httpjson.IncomingMessage{ChatID: "c2", Text: "true", Callback: true})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, httpjson.EventEdit, resp.Events[0].Type)
+		assert.Equal(t, prompt.MessageID, resp.Events[0].MessageID)
+		assert.Equal(t, "User deleted", resp.Events[0].Answer.Text)
+
+		require.Len(t, resp.Events[0].Answer.Buttons, 1)
+		button := resp.Events[0].Answer.Buttons[0]
+		assert.NotEmpty(t, button.ID)
+
+		resp = send(httpjson.IncomingMessage{ChatID: "c2", Button: button.ID})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Delete user?", resp.Events[0].Answer.Text)
+
+		// button can be pressed once.
+		httpResp := request(http.MethodPost, "/", httpjson.IncomingMessage{ChatID: "c2", Button: button.ID, Wait: true})
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+	})
+
+	t.Run("buttons: args of client rejected", func(t *testing.T) {
+		httpResp := request(http.MethodPost, "/", map[string]any{
+			"chat_id":  "c5",
+			"args":     map[string]any{"command": "delete", "state": "deleting"},
+			"callback": true,
+		})
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+
+		httpResp = request(http.MethodPost, "/", httpjson.IncomingMessage{ChatID: "c5", Button: "forged"})
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+	})
+
+	t.Run("buttons: button of other chat rejected", func(t *testing.T) {
+		resp := send(httpjson.IncomingMessage{ChatID: "c6", Text: "/delete"})
+		require.Len(t, resp.Events, 1)
+
+		resp = send(httpjson.IncomingMessage{ChatID: "c6", Text: "true", Callback: true})
+		require.Len(t, resp.Events, 1)
+		require.Len(t, resp.Events[0].Answer.Buttons, 1)
+
+		httpResp := request(http.MethodPost, "/", httpjson.IncomingMessage{
+			ChatID: "c7",
+			Button: resp.Events[0].Answer.Buttons[0].ID,
+		})
+		assert.Equal(t, http.StatusBadRequest, httpResp.StatusCode)
+	})
+
+	t.Run("sync: response contains only replies to message", func(t *testing.T) {
+		require.NoError(t, app.Send(context.Background(), "c8", &messengerapi.Answer{Text: "Hello"}))
+
+		resp := send(httpjson.IncomingMessage{ChatID: "c8", Text: "/add"})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Enter user name", resp.Events[0].Answer.Text)
+		assert.Equal(t, resp.ID, resp.Events[0].ReplyTo)
+
+		events := poll("/?chat_id=c8&timeout=0").Events
+		require.Len(t, events, 1)
+		assert.Equal(t, "Hello", events[0].Answer.Text)
+	})
+
+	t.Run("sync: replies are not taken by concurrent poll", func(t *testing.T) {
+		polled := make(chan []httpjson.Event, 1)
+		go func() {
+			polled <- poll("/?chat_id=c9&timeout=1").Events
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+
+		resp := send(httpjson.IncomingMessage{ChatID: "c9", Text: "/add"})
+		require.Len(t, resp.Events, 1)
+		assert.Equal(t, "Enter user name", resp.Events[0].Answer.Text)
+
+		assert.Empty(t, <-polled)
+	})
+
+	t.Run("pull: events sent out of dialog", func(t *testing.T) {
+		assert.Empty(t, poll("/?chat_id=c3&timeout=0").Events)
+
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			_ = app.Send(context.Background(), "c3", &messengerapi.Answer{Text: "Hello"})
+		}()
+
+		events := poll("/?chat_id=c3&timeout=1").Events
+		require.Len(t, events, 1)
+		assert.Equal(t, "Hello", events[0].Answer.Text)
+		assert.Equal(t, "c3", events[0].ChatID)
+	})
+
+	t.Run("stream: events sent as server-sent events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/?chat_id=c4", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+httpJSONToken)
+		req.Header.Set("Accept", "text/event-stream")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		require.NoError(t, app.Send(context.Background(), "c4", &messengerapi.Answer{Text: "Hello"}))
+		require.NoError(t, app.StartDialog(context.Background(), machine.DialogRef{ChatID: "c4"}, "delete", nil))
+
+		reader := bufio.NewReader(resp.Body)
+		events := make([]httpjson.Event, 0, 2)
+
+		for len(events) < 2 {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+
+			if data, ok := strings.CutPrefix(line, "data: "); ok {
+				var event httpjson.Event
+				require.NoError(t, json.Unmarshal([]byte(data), &event))
+				events = append(events, event)
+			}
+		}
+
+		assert.Equal(t, "Hello", events[0].Answer.Text)
+		assert.Equal(t, "Delete user?", events[1].Answer.Text)
+	})
+}
+
+func TestHTTPJSONMessengerConfig(t *testing.T) {
+	// listen runs messenger without application and returns channel of received messages.
+	listen := func(t *testing.T, cfg httpjson.Config) (*httptest.Server, chan messengerapi.Message) {
+		msngr, err := httpjson.NewMessenger(cfg, slog.Default())
+		require.NoError(t, err)
+
+		ch := make(chan messengerapi.Message)
+		done := make(chan error, 1)
+		go func() {
+			done <- msngr.Listen(ch)
+		}()
+
+		server := httptest.NewServer(msngr)
+		t.Cleanup(func() {
+			server.Close()
+			require.NoError(t, msngr.Close())
+			require.NoError(t, <-done)
+		})
+
+		return server, ch
+	}
+
+	post := func(t *testing.T, server *httptest.Server, token, body string) *http.Response {
+		var resp *http.Response
+		require.Eventually(t, func() bool {
+			req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(body))
+			require.NoError(t, err)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+
+			resp, err = http.DefaultClient.Do(req)
+			require.NoError(t, err)
+
+			if resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("Content-Type") == "" {
+				resp.Body.Close()
+				return false
+			}
+
+			return true
+		}, time.Second, 10*time.Millisecond, "messenger is not listening")
+		t.Cleanup(func() {
+			resp.Body.Close()
+		})
+
+		return resp
+	}
+
+	t.Run("token: required without opt-out", func(t *testing.T) {
+		_, err := httpjson.NewMessenger(httpjson.Config{}, slog.Default())
+		require.Error(t, err)
+	})
+
+	t.Run("token: request without token accepted, when unauthenticated allowed", func(t *testing.T) {
+		server, ch := listen(t, httpjson.Config{AllowUnauthenticated: true})
+
+		received := make(chan messengerapi.Message, 1)
+		go func() {
+			received <- <-ch
+		}()
+
+		resp := post(t, server, "", `{"chat_id":"c1","text":"/add"}`)
+		assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+		select {
+		case msg := <-received:
+			assert.Equal(t, "/add", msg.GetBody())
+		case <-time.After(time.Second):
+			t.Fatal("message is not received")
+		}
+	})
+
+	t.Run("wait: responds 503, when message is not handled", func(t *testing.T) {
+		server, ch := listen(t, httpjson.Config{Token: httpJSONToken})
+
+		go func() {
+			msg := <-ch
+
+			done, ok := msg.(messengerapi.DoneMessage)
+			if ok {
+				done.Done(dispatcher.ErrQueueFull)
+			}
+		}()
+
+		resp := post(t, server, httpJSONToken, `{"chat_id":"c1","text":"/add","wait":true}`)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+}
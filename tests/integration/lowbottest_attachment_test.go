@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/command/form"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// inspectCommand describes attachments of received message.
+type inspectCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (inspectCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "inspect",
+		Description: "Inspect attachment",
+	}
+}
+
+func (inspectCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("ask", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Send attachment"})
+		}).
+		Then("inspect", func(ctx context.Context, req *command.Request) error {
+			attachments := req.Message.GetAttachments()
+			if len(attachments) == 0 {
+				return req.Respond(&messengerapi.Answer{Text: "No attachment"})
+			}
+
+			attachment := attachments[0]
+
+			switch attachment.Kind {
+			case messengerapi.AttachmentLocation:
+				return req.Respond(&messengerapi.Answer{
+					Text: fmt.Sprintf("location %.2f, %.2f", attachment.Location.Latitude, attachment.Location.Longitude),
+				})
+			case messengerapi.AttachmentContact:
+				return req.Respond(&messengerapi.Answer{
+					Text: fmt.Sprintf("contact %s %s", attachment.Contact.FirstName, attachment.Contact.PhoneNumber),
+				})
+			}
+
+			file, err := attachment.Open(ctx)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			content, err := io.ReadAll(file)
+			if err != nil {
+				return err
+			}
+
+			return req.Respond(&messengerapi.Answer{
+				Text: fmt.Sprintf("%s %s (%d bytes): %s", attachment.Kind, attachment.FileName, attachment.Size, content),
+			})
+		})
+}
+
+// resumeUploadCommand collects document by form with replaced prompts.
+type resumeUploadCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (resumeUploadCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:           "resume",
+		Description:    "Upload resume",
+		ReplacePrompts: true,
+	}
+}
+
+func (resumeUploadCommand) Actions() *command.Actions {
+	type upload struct {
+		Resume *messengerapi.Attachment
+	}
+
+	return form.New[upload]("upload").
+		Attachment("resume", "Send resume", messengerapi.AttachmentDocument, func(u *upload, resume *messengerapi.Attachment) {
+			u.Resume = resume
+		}).
+		Submit(func(ctx context.Context, req *command.Request, u *upload) error {
+			file, err := u.Resume.Open(ctx)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			content, err := io.ReadAll(file)
+			if err != nil {
+				return err
+			}
+
+			return req.Respond(&messengerapi.Answer{Text: "Resume: " + string(content)})
+		}).
+		Actions()
+}
+
+func TestLowbottestAttachments(t *testing.T) {
+	bot := lowbottest.New(t, lowbottest.WithCommands(&inspectCommand{}, &resumeUploadCommand{}))
+
+	t.Run("file: uploaded file opened by action", func(t *testing.T) {
+		document := bot.Messenger().Upload(messengerapi.AttachmentDocument, "report.txt", []byte("total: 3"))
+
+		bot.Conversation(t, "chat_1").
+			Send("/inspect").
+			ExpectText("Send attachment").
+			SendAttachment(document, "report").
+			ExpectText("document report.txt (8 bytes): total: 3").
+			ExpectNoError()
+	})
+
+	t.Run("location: received without file", func(t *testing.T) {
+		bot.Conversation(t, "chat_2").
+			Send("/inspect").
+			ExpectText("Send attachment").
+			SendAttachment(&messengerapi.Attachment{
+				Kind:     messengerapi.AttachmentLocation,
+				Location: &messengerapi.Location{Latitude: 55.75, Longitude: 37.62},
+			}, "").
+			ExpectText("location 55.75, 37.62")
+	})
+
+	t.Run("contact: received without file", func(t *testing.T) {
+		bot.Conversation(t, "chat_3").
+			Send("/inspect").
+			ExpectText("Send attachment").
+			SendAttachment(&messengerapi.Attachment{
+				Kind:    messengerapi.AttachmentContact,
+				Contact: &messengerapi.Contact{FirstName: "Bob", PhoneNumber: "+10000000000"},
+			}, "").
+			ExpectText("contact Bob +10000000000")
+	})
+
+	t.Run("file: not uploaded file can not be opened", func(t *testing.T) {
+		conv := bot.Conversation(t, "chat_4").
+			Send("/inspect").
+			ExpectText("Send attachment").
+			SendAttachment(&messengerapi.Attachment{
+				Kind:    messengerapi.AttachmentDocument,
+				FileID:  "missing",
+				Fetcher: bot.Messenger(),
+			}, "")
+
+		require.ErrorIs(t, conv.Err(), messengerapi.ErrNoFile)
+	})
+
+	t.Run("form: attachment restored from state opened with replaced prompts", func(t *testing.T) {
+		document := bot.Messenger().Upload(messengerapi.AttachmentDocument, "resume.txt", []byte("go developer"))
+
+		bot.Conversation(t, "chat_5").
+			Send("/resume").
+			ExpectText("Send resume").
+			Send("no file").
+			ExpectTextContains("Send document.").
+			SendAttachment(document, "").
+			ExpectText("Resume: go developer").
+			ExpectNoError()
+	})
+
+	t.Run("open: attachment without file", func(t *testing.T) {
+		_, err := (&messengerapi.Attachment{Kind: messengerapi.AttachmentLocation}).Open(context.Background())
+		require.ErrorIs(t, err, messengerapi.ErrNoFile)
+
+		_, err = (&messengerapi.Attachment{Kind: messengerapi.AttachmentDocument, FileID: "1"}).Open(context.Background())
+		require.ErrorIs(t, err, messengerapi.ErrFetcherMissing)
+	})
+
+	t.Run("find: first attachment of kind", func(t *testing.T) {
+		photo := &messengerapi.Attachment{Kind: messengerapi.AttachmentPhoto, FileID: "1"}
+		msg := &lowbottest.Message{Attachments: []*messengerapi.Attachment{
+			{Kind: messengerapi.AttachmentDocument, FileID: "2"},
+			photo,
+		}}
+
+		assert.Same(t, photo, messengerapi.FindAttachment(msg, messengerapi.AttachmentPhoto))
+		assert.Nil(t, messengerapi.FindAttachment(msg, messengerapi.AttachmentVideo))
+	})
+}
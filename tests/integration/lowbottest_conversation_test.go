@@ -0,0 +1,172 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/command/form"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type conversationUser struct {
+	Name   string
+	Type   string
+	Avatar *messengerapi.Attachment
+}
+
+type conversationAddCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (conversationAddCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "add",
+		Description: "Add user",
+	}
+}
+
+func (conversationAddCommand) Actions() *command.Actions {
+	return form.New[conversationUser]("user").
+		Text("name", "Enter user name", func(u *conversationUser, name string) {
+			u.Name = name
+		}).
+		Enum("type", "Select user type", messengerapi.EnumFromList([]string{"internal", "external"}),
+			func(u *conversationUser, typ string) {
+				u.Type = typ
+			}).
+		Attachment("avatar", "Send avatar", messengerapi.AttachmentPhoto,
+			func(u *conversationUser, avatar *messengerapi.Attachment) {
+				u.Avatar = avatar
+			}).
+		Submit(func(ctx context.Context, req *command.Request, u *conversationUser) error {
+			avatar, err := u.Avatar.Open(ctx)
+			if err != nil {
+				return err
+			}
+			defer avatar.Close()
+
+			content, err := io.ReadAll(avatar)
+			if err != nil {
+				return err
+			}
+
+			return req.Respond(&messengerapi.Answer{
+				Text: fmt.Sprintf("%s (%s), avatar: %s", u.Name, u.Type, content),
+			})
+		}).
+		Actions()
+}
+
+type conversationDeleteCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (conversationDeleteCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:           "delete",
+		Description:    "Delete user",
+		ReplacePrompts: true,
+	}
+}
+
+func (conversationDeleteCommand) Actions() *command.Actions {
+	return command.NewActions().
+		With("confirmed", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(func(_ context.Context, req *command.Request) error {
+				return req.Respond(&messengerapi.Answer{
+					Text: "User deleted",
+					Buttons: []messengerapi.Button{
+						&messengerapi.CommandButton{
+							Title: "Delete another",
+							Args: messengerapi.Args{
+								CommandName: "delete",
+								StateName:   "start",
+							},
+						},
+					},
+				})
+			})
+		}).
+		With("canceled", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(func(_ context.Context, req *command.Request) error {
+				return req.Respond(&messengerapi.Answer{
+					Text: "Deletion canceled",
+				})
+			})
+		}).
+		Then("start", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: "Delete user?",
+				Enum: messengerapi.Enum{
+					Values: []messengerapi.EnumItem{
+						{Value: "true", Title: "Yes"},
+						{Value: "false", Title: "No"},
+					},
+				},
+			})
+		}).
+		Branch("start.dispatch").
+		On("true", "confirmed").
+		On("false", "canceled")
+}
+
+func TestLowbottestConversation(t *testing.T) {
+	bot := lowbottest.New(t, lowbottest.WithCommands(
+		&conversationAddCommand{},
+		&conversationDeleteCommand{},
+	))
+
+	t.Run("form: text, enum and attachment", func(t *testing.T) {
+		avatar := bot.Messenger().Upload(messengerapi.AttachmentPhoto, "avatar.jpg", []byte("jpeg"))
+
+		bot.Conversation(t, "chat_1").
+			Send("/add").
+			ExpectText("Enter user name").
+			Send("bob").
+			ExpectText("Select user type").
+			ExpectButtons("internal", "external").
+			Press("external").
+			ExpectText("Send avatar").
+			Send("no photo").
+			ExpectTextContains("Send photo.").
+			SendAttachment(avatar, "").
+			ExpectText("bob (external), avatar: jpeg").
+			ExpectNoError().
+			ExpectNoReply()
+	})
+
+	t.Run("enum replaces prompt, command button starts dialog", func(t *testing.T) {
+		conv := bot.Conversation(t, "chat_2").
+			Send("/delete").
+			ExpectText("Delete user?").
+			ExpectButtons("Yes", "No").
+			Press("Yes").
+			ExpectReply(func(reply *lowbottest.Reply) {
+				assert.True(t, reply.Edited)
+				assert.Equal(t, "User deleted", reply.Answer.Text)
+			}).
+			Press("Delete another").
+			ExpectText("Delete user?").
+			Press("No").
+			ExpectText("Deletion canceled").
+			ExpectNoReply()
+
+		require.NoError(t, conv.Err())
+		assert.Len(t, bot.Messenger().Replies("chat_2"), 4)
+	})
+
+	t.Run("unknown command", func(t *testing.T) {
+		bot.Conversation(t, "chat_3").
+			Send("/unknown").
+			ExpectReply(func(reply *lowbottest.Reply) {
+				require.NotNil(t, reply.Answer)
+			})
+	})
+}
@@ -0,0 +1,205 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// pickUserCommand is sub-dialog, which stores selected user in state data.
+type pickUserCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (pickUserCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "pick_user",
+		Description: "Select user",
+	}
+}
+
+func (pickUserCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("ask", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Select user"})
+		}).
+		Then("save", func(_ context.Context, req *command.Request) error {
+			req.State.Set("user", req.Message.GetBody())
+
+			return req.Respond(&messengerapi.Answer{Text: "Selected " + req.Message.GetBody()})
+		})
+}
+
+// renameCommand calls pickUserCommand and schedules reminder before call.
+type renameCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (renameCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "rename",
+		Description: "Rename user",
+	}
+}
+
+func (renameCommand) Actions() *command.Actions {
+	askName := func(_ context.Context, req *command.Request) error {
+		text := "Enter new name for " + req.State.Get("user")
+		if body := req.Message.GetBody(); body != "" {
+			text += " (input: " + body + ")"
+		}
+
+		return req.Respond(&messengerapi.Answer{Text: text})
+	}
+
+	return command.NewActions().
+		With("remind", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(func(_ context.Context, req *command.Request) error {
+				req.State.Forward("ask_name")
+
+				return req.Respond(&messengerapi.Answer{Text: "Reminder"})
+			}).ForwardsTo("ask_name")
+		}).
+		Then("start", func(_ context.Context, req *command.Request) error {
+			req.Schedule(time.Now(), "remind", nil)
+
+			req.State.Call("pick_user", "ask_name")
+
+			return nil
+		}).
+		Calls("pick_user", "ask_name").
+		Then("ask_name", askName).
+		Then("save", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: "Renamed " + req.State.Get("user") + " to " + req.Message.GetBody(),
+			})
+		}).
+		Reachable("remind")
+}
+
+// pickColorCommand is sub-dialog, which stores color selected by button in state data.
+type pickColorCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (pickColorCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "pick_color",
+		Description: "Select color",
+	}
+}
+
+func (pickColorCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("ask", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{
+				Text: "Select color",
+				Buttons: []messengerapi.Button{
+					&messengerapi.CommandButton{
+						Title: "Red",
+						Args: messengerapi.Args{
+							CommandName: "pick_color",
+							StateName:   "save",
+							Data:        map[string]string{"color": "red"},
+						},
+					},
+				},
+			})
+		}).
+		Then("save", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Selected " + req.State.Get("color")})
+		})
+}
+
+// paintCommand calls pickColorCommand.
+type paintCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (paintCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "paint",
+		Description: "Paint wall",
+	}
+}
+
+func (paintCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("start", func(_ context.Context, req *command.Request) error {
+			req.State.Call("pick_color", "paint")
+
+			return nil
+		}).
+		Calls("pick_color", "paint").
+		Then("paint", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Painted " + req.State.Get("color")})
+		})
+}
+
+func TestMachineCall(t *testing.T) {
+	newBot := func(opts ...lowbottest.Option) *lowbottest.Bot {
+		opts = append(opts, lowbottest.WithCommands(&pickUserCommand{}, &renameCommand{}, &pickColorCommand{}, &paintCommand{}))
+
+		return lowbottest.New(t, opts...)
+	}
+
+	t.Run("return: return state runs without message of sub-dialog", func(t *testing.T) {
+		newBot().Conversation(t, "chat_1").
+			Send("/rename").
+			ExpectText("Select user").
+			Send("bob").
+			ExpectText("Selected bob").
+			ExpectText("Enter new name for bob").
+			ExpectNoReply().
+			Send("alice").
+			ExpectText("Renamed bob to alice").
+			ExpectNoError()
+	})
+
+	t.Run("schedule: action of parent postponed until sub-dialog finished", func(t *testing.T) {
+		store := schedule.NewMemoryStore()
+		bot := newBot(lowbottest.WithScheduleStore(store))
+
+		conv := bot.Conversation(t, "chat_2").
+			Send("/rename").
+			ExpectText("Select user")
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply()
+
+		// postponed action is not due again, while sub-dialog runs.
+		jobs, err := store.Due(context.Background(), time.Now().Add(time.Hour), time.Minute, 100)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		conv.Send("bob").
+			ExpectText("Selected bob").
+			ExpectText("Enter new name for bob")
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectText("Reminder").
+			ExpectText("Enter new name for bob").
+			ExpectNoReply().
+			Send("alice").
+			ExpectText("Renamed bob to alice").
+			ExpectNoError()
+	})
+
+	t.Run("return: button of sub-dialog keeps parent dialog", func(t *testing.T) {
+		newBot().Conversation(t, "chat_3").
+			Send("/paint").
+			ExpectText("Select color").
+			Press("Red").
+			ExpectText("Selected red").
+			ExpectText("Painted red").
+			ExpectNoError()
+	})
+}
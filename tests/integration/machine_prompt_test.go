@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errEditUnavailable = errors.New("edit unavailable")
+
+// editFailingResponder fails editing of messages.
+type editFailingResponder struct {
+	messengerapi.Responder
+}
+
+func (*editFailingResponder) Edit(string, *messengerapi.Answer) (messengerapi.Message, error) {
+	return nil, errEditUnavailable
+}
+
+func TestMachinePromptReplace(t *testing.T) {
+	t.Run("press: prompt replaced", func(t *testing.T) {
+		bot := lowbottest.New(t, lowbottest.WithCommands(&conversationDeleteCommand{}))
+
+		bot.Conversation(t, "chat_1").
+			Send("/delete").
+			ExpectText("Delete user?").
+			Press("No").
+			ExpectReply(func(reply *lowbottest.Reply) {
+				assert.True(t, reply.Edited)
+				assert.Equal(t, "Deletion canceled", reply.Answer.Text)
+			}).
+			ExpectNoReply()
+	})
+
+	t.Run("text: prompt not replaced", func(t *testing.T) {
+		bot := lowbottest.New(t, lowbottest.WithCommands(&conversationDeleteCommand{}))
+
+		bot.Conversation(t, "chat_2").
+			Send("/delete").
+			ExpectText("Delete user?").
+			Send("false").
+			ExpectReply(func(reply *lowbottest.Reply) {
+				assert.False(t, reply.Edited)
+				assert.Equal(t, "Deletion canceled", reply.Answer.Text)
+			}).
+			ExpectNoReply()
+	})
+
+	t.Run("edit failed: answer sent as new message", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		bot := lowbottest.New(t,
+			lowbottest.WithCommands(&conversationDeleteCommand{}),
+			lowbottest.WithLogger(slog.New(slog.NewTextHandler(logs, nil))),
+		)
+
+		conv := bot.Conversation(t, "chat_3").
+			Send("/delete").
+			ExpectText("Delete user?")
+
+		require.NoError(t, bot.Machine().Handle(context.Background(), &machine.Request{
+			Message: &lowbottest.Message{
+				ID:       "press",
+				ChatID:   "chat_3",
+				Text:     "false",
+				Sender:   &messengerapi.Sender{ID: "chat_3"},
+				Callback: true,
+			},
+			Responder: &editFailingResponder{Responder: bot.Messenger().CreateResponder("chat_3")},
+		}))
+
+		conv.ExpectReply(func(reply *lowbottest.Reply) {
+			assert.False(t, reply.Edited)
+			assert.Equal(t, "Deletion canceled", reply.Answer.Text)
+		}).
+			ExpectNoReply()
+
+		assert.Contains(t, logs.String(), "failed to replace prompt")
+		assert.Contains(t, logs.String(), errEditUnavailable.Error())
+	})
+}
@@ -0,0 +1,157 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// remindCommand schedules reminder with text of user and waits for confirmation.
+type remindCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (remindCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "remind",
+		Description: "Remind later",
+	}
+}
+
+func (remindCommand) Actions() *command.Actions {
+	notify := func(_ context.Context, req *command.Request) error {
+		return req.Respond(&messengerapi.Answer{Text: "Reminder: " + req.State.Get("text")})
+	}
+
+	return command.NewActions().
+		Then("ask", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Enter text"})
+		}).
+		Then("schedule", func(_ context.Context, req *command.Request) error {
+			req.Schedule(time.Now(), "notify", map[string]string{"text": req.Message.GetBody()})
+
+			return req.Respond(&messengerapi.Answer{Text: "Scheduled"})
+		}).
+		Then("wait", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Done"})
+		}).
+		With("notify", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(notify)
+		}).
+		Reachable("notify")
+}
+
+// noteCommand schedules reminder by last action, so reminder is discarded with finished dialog.
+type noteCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (noteCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "note",
+		Description: "Save note",
+	}
+}
+
+func (noteCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("save", func(_ context.Context, req *command.Request) error {
+			req.Schedule(time.Now(), "notify", nil)
+
+			return req.Respond(&messengerapi.Answer{Text: "Saved"})
+		}).
+		With("notify", func(build func(callback command.ActionCallback) *command.ActionBuilder) {
+			build(func(_ context.Context, req *command.Request) error {
+				return req.Respond(&messengerapi.Answer{Text: "Note reminder"})
+			})
+		}).
+		Reachable("notify")
+}
+
+func TestMachineSchedule(t *testing.T) {
+	newBot := func(opts ...lowbottest.Option) *lowbottest.Bot {
+		return lowbottest.New(t, append([]lowbottest.Option{
+			lowbottest.WithCommands(&remindCommand{}, &noteCommand{}, &quizCommand{}),
+		}, opts...)...)
+	}
+
+	t.Run("scheduled: action runs with data without user input", func(t *testing.T) {
+		bot := newBot()
+
+		conv := bot.Conversation(t, "chat_1").
+			Send("/remind").
+			ExpectText("Enter text").
+			Send("buy milk").
+			ExpectText("Scheduled")
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectText("Reminder: buy milk").ExpectNoReply()
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply().ExpectNoError()
+	})
+
+	t.Run("scheduled: action canceled, when dialog interrupted", func(t *testing.T) {
+		bot := newBot()
+
+		conv := bot.Conversation(t, "chat_2").
+			Send("/remind").
+			ExpectText("Enter text").
+			Send("buy milk").
+			ExpectText("Scheduled").
+			Send("/quiz").
+			ExpectText("First question?")
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply()
+	})
+
+	t.Run("scheduled: action canceled, when dialog replaced by button of other command", func(t *testing.T) {
+		store := schedule.NewMemoryStore()
+		bot := newBot(lowbottest.WithScheduleStore(store))
+
+		bot.Conversation(t, "chat_4").
+			Send("/remind").
+			ExpectText("Enter text").
+			Send("buy milk").
+			ExpectText("Scheduled").
+			SendMessage(&lowbottest.Message{
+				Text:     "Quiz",
+				Args:     &messengerapi.Args{CommandName: "quiz", StateName: "first"},
+				Callback: true,
+			}).
+			ExpectText("First question?").
+			ExpectNoError()
+
+		jobs, err := store.Due(context.Background(), time.Now().Add(time.Hour), time.Minute, 100)
+		require.NoError(t, err)
+
+		for _, job := range jobs {
+			assert.NotEqual(t, "remind", job.CommandName)
+		}
+	})
+
+	t.Run("scheduled: action of last action discarded", func(t *testing.T) {
+		logs := &bytes.Buffer{}
+		bot := newBot(lowbottest.WithLogger(slog.New(slog.NewTextHandler(logs, nil))))
+
+		conv := bot.Conversation(t, "chat_3").
+			Send("/note").
+			ExpectText("Saved")
+
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply().ExpectNoError()
+
+		assert.Contains(t, logs.String(), "scheduled action discarded, dialog finished")
+	})
+}
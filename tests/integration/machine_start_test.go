@@ -0,0 +1,76 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+func TestMachineStartDialog(t *testing.T) {
+	newBot := func(strategy state.KeyStrategy) *lowbottest.Bot {
+		return lowbottest.New(t,
+			lowbottest.WithCommands(&quizCommand{}),
+			lowbottest.WithDialogKey(strategy),
+		)
+	}
+
+	start := func(bot *lowbottest.Bot, ref machine.DialogRef) error {
+		return bot.Machine().StartDialog(context.Background(), ref, "quiz", nil, bot.Messenger().CreateThreadResponder)
+	}
+
+	t.Run("chat: dialog continued by user", func(t *testing.T) {
+		bot := newBot(state.KeyByChat)
+
+		require.NoError(t, start(bot, machine.DialogRef{ChatID: "chat_1"}))
+
+		bot.Conversation(t, "chat_1").
+			ExpectText("First question?").
+			Send("1").
+			ExpectText("Second question?").
+			ExpectNoError()
+	})
+
+	t.Run("chat sender: dialog of member started", func(t *testing.T) {
+		bot := newBot(state.KeyByChatSender)
+
+		require.NoError(t, start(bot, machine.DialogRef{ChatID: "group_1", SenderID: "alice"}))
+
+		alice := &messengerapi.Sender{ID: "alice"}
+		bob := &messengerapi.Sender{ID: "bob"}
+
+		bot.Conversation(t, "group_1").
+			ExpectText("First question?").
+			As(bob).
+			Send("1").
+			ExpectText("Command not found.").
+			As(alice).
+			Send("1").
+			ExpectText("Second question?")
+	})
+
+	t.Run("chat sender: sender required", func(t *testing.T) {
+		bot := newBot(state.KeyByChatSender)
+
+		require.ErrorIs(t, start(bot, machine.DialogRef{ChatID: "group_2"}), machine.ErrSenderRequired)
+
+		bot.Conversation(t, "group_2").ExpectNoReply()
+	})
+
+	t.Run("chat thread: dialog replies to thread", func(t *testing.T) {
+		bot := newBot(state.KeyByChatThread)
+
+		require.NoError(t, start(bot, machine.DialogRef{ChatID: "forum", ThreadID: "1"}))
+
+		bot.Conversation(t, "forum").InThread("2").ExpectNoReply()
+		bot.Conversation(t, "forum").InThread("1").
+			ExpectText("First question?").
+			Send("1").
+			ExpectText("Second question?")
+	})
+}
@@ -0,0 +1,182 @@
+package integration
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/machine"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// profileCommand offers to resume dialog, when interrupted.
+type profileCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (profileCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "profile",
+		Description: "Fill profile",
+	}
+}
+
+func (profileCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("ask_name", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Enter name"})
+		}).
+		Then("ask_age", func(_ context.Context, req *command.Request) error {
+			req.State.Set("name", req.Message.GetBody())
+
+			return req.Respond(&messengerapi.Answer{Text: "Enter age"})
+		}).
+		Then("save", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Saved " + req.State.Get("name") + ", " + req.Message.GetBody()})
+		})
+}
+
+func (profileCommand) SuspendOnInterrupt(context.Context, *command.InterruptRequest) (command.ResumeMode, error) {
+	return command.ResumeOffer, nil
+}
+
+type helpCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (helpCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "help",
+		Description: "Show help",
+	}
+}
+
+func (helpCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("help", func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: "Help"})
+		})
+}
+
+func TestMachineSuspend(t *testing.T) {
+	newBot := func(opts ...lowbottest.Option) *lowbottest.Bot {
+		return lowbottest.New(t, append([]lowbottest.Option{
+			lowbottest.WithCommands(&profileCommand{}, &helpCommand{}, &stubbornCommand{}),
+		}, opts...)...)
+	}
+
+	// offer starts profile dialog, interrupts it by help command and returns arguments of resume button.
+	offer := func(conv *lowbottest.Conversation) *messengerapi.Args {
+		var args *messengerapi.Args
+
+		conv.Send("/profile").
+			ExpectText("Enter name").
+			Send("bob").
+			ExpectText("Enter age").
+			Send("/help").
+			ExpectText("Help").
+			ExpectReply(func(reply *lowbottest.Reply) {
+				require.Equal(t, "Command /profile is not finished.", reply.Answer.Text)
+				require.Len(t, reply.Answer.Buttons, 1)
+
+				button, ok := reply.Answer.Buttons[0].(*messengerapi.CommandButton)
+				require.True(t, ok)
+				require.Equal(t, "Continue", button.Title)
+				require.NotContains(t, button.Args.Data, "name")
+
+				args = &messengerapi.Args{
+					CommandName: button.Args.CommandName,
+					StateName:   button.Args.StateName,
+					Data:        maps.Clone(button.Args.Data),
+				}
+			})
+
+		return args
+	}
+
+	t.Run("offer: dialog resumed by button", func(t *testing.T) {
+		conv := newBot().Conversation(t, "chat_1")
+
+		offer(conv)
+
+		conv.Press("Continue").
+			ExpectText("Command /profile resumed.").
+			Send("30").
+			ExpectText("Saved bob, 30").
+			ExpectNoError()
+	})
+
+	t.Run("offer: repeated press does not replace current dialog", func(t *testing.T) {
+		conv := newBot().Conversation(t, "chat_2")
+
+		args := offer(conv)
+
+		conv.Press("Continue").
+			ExpectText("Command /profile resumed.").
+			SendMessage(&lowbottest.Message{Text: "Continue", Args: args, Callback: true}).
+			ExpectText("Command /profile can not be resumed.").
+			Send("30").
+			ExpectText("Saved bob, 30").
+			ExpectNoError()
+	})
+
+	t.Run("offer: forged button rejected", func(t *testing.T) {
+		conv := newBot().Conversation(t, "chat_3")
+
+		offer(conv)
+
+		conv.SendMessage(&lowbottest.Message{
+			Text: "Continue",
+			Args: &messengerapi.Args{
+				CommandName: "profile",
+				StateName:   state.Resume,
+				Data:        map[string]string{state.ResumeIDKey: "forged", "name": "mallory"},
+			},
+			Callback: true,
+		}).
+			ExpectText("Command /profile can not be resumed.").
+			Press("Continue").
+			ExpectText("Command /profile resumed.").
+			Send("30").
+			ExpectText("Saved bob, 30")
+	})
+
+	t.Run("offer: current dialog, which does not allow interruption, handles press", func(t *testing.T) {
+		conv := newBot().Conversation(t, "chat_4")
+
+		offer(conv)
+
+		conv.Send("/stubborn").
+			ExpectText("Enter value").
+			Press("Continue").
+			ExpectText("Saved Command /profile is not finished.").
+			Press("Continue").
+			ExpectText("Command /profile resumed.").
+			Send("30").
+			ExpectText("Saved bob, 30").
+			ExpectNoError()
+	})
+
+	t.Run("texts: configured", func(t *testing.T) {
+		bot := newBot(lowbottest.WithResumeTexts(machine.ResumeTexts{
+			Offer:    "Finish /%s?",
+			Continue: "Yes",
+			Resumed:  "Back to /%s.",
+			Expired:  "Too late for /%s.",
+		}))
+
+		bot.Conversation(t, "chat_5").
+			Send("/profile").
+			ExpectText("Enter name").
+			Send("/help").
+			ExpectText("Help").
+			ExpectText("Finish /profile?").
+			Press("Yes").
+			ExpectText("Back to /profile.")
+	})
+}
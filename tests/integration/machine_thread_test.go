@@ -0,0 +1,34 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+)
+
+func TestMachineThreads(t *testing.T) {
+	bot := lowbottest.New(t,
+		lowbottest.WithCommands(&quizCommand{}),
+		lowbottest.WithDialogKey(state.KeyByChatThread),
+	)
+
+	general := bot.Conversation(t, "forum")
+	first := bot.Conversation(t, "forum").InThread("1")
+	second := bot.Conversation(t, "forum").InThread("2")
+
+	first.Send("/quiz").ExpectText("First question?")
+	second.Send("/quiz").ExpectText("First question?")
+
+	first.Send("1").ExpectText("Second question?")
+	second.ExpectNoReply()
+
+	time.Sleep(quizIdleTimeout + 20*time.Millisecond)
+	bot.RunScheduled(context.Background())
+
+	first.ExpectText("Quiz expired").ExpectNoReply()
+	second.ExpectText("Quiz expired").ExpectNoReply()
+	general.ExpectNoReply()
+}
@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/router"
+	"github.com/artarts36/lowbot/engine/schedule"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+const quizIdleTimeout = 100 * time.Millisecond
+
+type quizCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (quizCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "quiz",
+		Description: "Answer questions",
+		IdleTimeout: quizIdleTimeout,
+	}
+}
+
+func (quizCommand) Actions() *command.Actions {
+	ask := func(text string) command.ActionCallback {
+		return func(_ context.Context, req *command.Request) error {
+			return req.Respond(&messengerapi.Answer{Text: text})
+		}
+	}
+
+	return command.NewActions().
+		Then("first", ask("First question?")).
+		Then("second", ask("Second question?")).
+		Then("done", ask("Quiz passed"))
+}
+
+func (quizCommand) OnTimeout(_ context.Context, req *command.TimeoutRequest) error {
+	_, err := req.Responder.Respond(&messengerapi.Answer{Text: "Quiz expired"})
+	return err
+}
+
+func TestMachineTimeouts(t *testing.T) {
+	newBot := func(opts ...lowbottest.Option) *lowbottest.Bot {
+		return lowbottest.New(t, append([]lowbottest.Option{
+			lowbottest.WithCommands(&quizCommand{}, router.NewCancelCommand("cancel")),
+		}, opts...)...)
+	}
+
+	expectNoState := func(t *testing.T, bot *lowbottest.Bot, chatID string) {
+		t.Helper()
+
+		_, err := bot.Storage().Get(context.Background(), state.KeyByChat.Key(chatID, "", ""))
+		require.ErrorIs(t, err, state.ErrStateNotFound)
+	}
+
+	t.Run("expired: timeout handler called and state deleted", func(t *testing.T) {
+		bot := newBot()
+
+		conv := bot.Conversation(t, "chat_1").
+			Send("/quiz").
+			ExpectText("First question?")
+
+		time.Sleep(quizIdleTimeout + 20*time.Millisecond)
+		bot.RunScheduled(context.Background())
+
+		conv.ExpectText("Quiz expired").ExpectNoReply()
+		expectNoState(t, bot, "chat_1")
+	})
+
+	t.Run("continued: deadline moved by answer", func(t *testing.T) {
+		bot := newBot()
+
+		conv := bot.Conversation(t, "chat_2").
+			Send("/quiz").
+			ExpectText("First question?")
+
+		time.Sleep(quizIdleTimeout / 2)
+		conv.Send("1").ExpectText("Second question?")
+
+		time.Sleep(quizIdleTimeout/2 + 20*time.Millisecond)
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply()
+
+		time.Sleep(quizIdleTimeout / 2)
+		bot.RunScheduled(context.Background())
+		conv.ExpectText("Quiz expired").ExpectNoReply()
+	})
+
+	t.Run("expired: message without command handled as message without dialog", func(t *testing.T) {
+		bot := newBot()
+
+		conv := bot.Conversation(t, "chat_3").
+			Send("/quiz").
+			ExpectText("First question?")
+
+		time.Sleep(quizIdleTimeout + 20*time.Millisecond)
+
+		conv.Send("1").
+			ExpectText("Quiz expired").
+			ExpectText("Command not found.").
+			ExpectNoReply()
+		expectNoState(t, bot, "chat_3")
+	})
+
+	t.Run("restart: dialog expired by another machine with shared stores", func(t *testing.T) {
+		storage := state.NewMemoryStorage()
+		schedules := schedule.NewMemoryStore()
+
+		newBot(lowbottest.WithStateStorage(storage), lowbottest.WithScheduleStore(schedules)).
+			Conversation(t, "chat_4").
+			Send("/quiz").
+			ExpectText("First question?")
+
+		restarted := newBot(lowbottest.WithStateStorage(storage), lowbottest.WithScheduleStore(schedules))
+
+		time.Sleep(quizIdleTimeout + 20*time.Millisecond)
+		restarted.RunScheduled(context.Background())
+
+		restarted.Conversation(t, "chat_4").ExpectText("Quiz expired")
+		expectNoState(t, restarted, "chat_4")
+	})
+
+	t.Run("canceled: dialog does not expire", func(t *testing.T) {
+		schedules := schedule.NewMemoryStore()
+		bot := newBot(lowbottest.WithScheduleStore(schedules))
+
+		conv := bot.Conversation(t, "chat_5").
+			Send("/quiz").
+			ExpectText("First question?").
+			Send("/cancel").
+			ExpectText("Command /quiz canceled.")
+
+		time.Sleep(quizIdleTimeout + 20*time.Millisecond)
+		bot.RunScheduled(context.Background())
+		conv.ExpectNoReply()
+
+		jobs, err := schedules.Due(context.Background(), time.Now(), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+}
@@ -0,0 +1,60 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/dedupe"
+)
+
+func TestMemoryDedupeStore(t *testing.T) {
+	t.Run("seen: second call is duplicate", func(t *testing.T) {
+		ctx := context.Background()
+		store := dedupe.NewMemoryStore()
+
+		seen, err := store.Seen(ctx, "chat_1:1", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		seen, err = store.Seen(ctx, "chat_1:1", time.Minute)
+		require.NoError(t, err)
+		assert.True(t, seen)
+
+		seen, err = store.Seen(ctx, "chat_1:2", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("seen: key expires after ttl", func(t *testing.T) {
+		ctx := context.Background()
+		store := dedupe.NewMemoryStore()
+
+		seen, err := store.Seen(ctx, "chat_1:1", 20*time.Millisecond)
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		time.Sleep(30 * time.Millisecond)
+
+		seen, err = store.Seen(ctx, "chat_1:1", 20*time.Millisecond)
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("release: released key is not seen", func(t *testing.T) {
+		ctx := context.Background()
+		store := dedupe.NewMemoryStore()
+
+		_, err := store.Seen(ctx, "chat_1:1", time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Release(ctx, "chat_1:1"))
+
+		seen, err := store.Seen(ctx, "chat_1:1", time.Minute)
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+}
@@ -0,0 +1,96 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/schedule"
+)
+
+func TestMemoryScheduleStore(t *testing.T) {
+	now := time.Date(2025, time.September, 27, 23, 0, 0, 0, time.UTC)
+
+	t.Run("due: lease and redelivery until ack", func(t *testing.T) {
+		ctx := context.Background()
+		store := schedule.NewMemoryStore()
+		job := &schedule.Job{ID: "1", StateKey: "chat_1", At: now}
+
+		require.NoError(t, store.Add(ctx, job))
+
+		jobs, err := store.Due(ctx, now.Add(-time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		jobs, err = store.Due(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, job, jobs[0])
+
+		jobs, err = store.Due(ctx, now.Add(time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		jobs, err = store.Due(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		require.NoError(t, store.Ack(ctx, job))
+
+		jobs, err = store.Due(ctx, now.Add(time.Hour), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("due: ordered by time and limited", func(t *testing.T) {
+		ctx := context.Background()
+		store := schedule.NewMemoryStore()
+
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "late", At: now.Add(time.Second)}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "early", At: now}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "latest", At: now.Add(2 * time.Second)}))
+
+		jobs, err := store.Due(ctx, now.Add(time.Hour), time.Minute, 2)
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+		assert.Equal(t, "early", jobs[0].ID)
+		assert.Equal(t, "late", jobs[1].ID)
+	})
+
+	t.Run("add: leased job replaced and returned at its time", func(t *testing.T) {
+		ctx := context.Background()
+		store := schedule.NewMemoryStore()
+
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "1", At: now, StateName: "first"}))
+
+		jobs, err := store.Due(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "1", At: now.Add(time.Second), StateName: "second"}))
+
+		jobs, err = store.Due(ctx, now.Add(time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "second", jobs[0].StateName)
+	})
+
+	t.Run("cancel: jobs of dialog state", func(t *testing.T) {
+		ctx := context.Background()
+		store := schedule.NewMemoryStore()
+
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "1", StateKey: "chat_2", At: now}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "2", StateKey: "chat_2", At: now}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "3", StateKey: "chat_3", At: now}))
+
+		require.NoError(t, store.Cancel(ctx, "chat_2"))
+
+		jobs, err := store.Due(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "3", jobs[0].ID)
+	})
+}
@@ -0,0 +1,62 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/state"
+)
+
+func TestMemoryStateStorage(t *testing.T) {
+	storage := state.NewMemoryStorage()
+	ctx := context.Background()
+
+	t.Run("put: conflict with stale version", func(t *testing.T) {
+		first := state.NewState("chat_1", "start")
+		second := state.NewState("chat_1", "start")
+
+		require.NoError(t, storage.Put(ctx, first))
+		assert.Equal(t, int64(1), first.Version())
+
+		assert.Equal(t, state.ErrStateConflict, storage.Put(ctx, second))
+
+		require.NoError(t, storage.Put(ctx, first))
+		assert.Equal(t, int64(2), first.Version())
+	})
+
+	t.Run("put: stale state after delete rejected", func(t *testing.T) {
+		require.NoError(t, storage.Put(ctx, state.NewState("chat_2", "add")))
+
+		finished, err := storage.Get(ctx, "chat_2")
+		require.NoError(t, err)
+		slow, err := storage.Get(ctx, "chat_2")
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Delete(ctx, finished))
+
+		assert.Equal(t, state.ErrStateConflict, storage.Put(ctx, slow))
+
+		_, err = storage.Get(ctx, "chat_2")
+		assert.Equal(t, state.ErrStateNotFound, err)
+	})
+
+	t.Run("delete: conflict with stale version", func(t *testing.T) {
+		st := state.NewState("chat_3", "add")
+		require.NoError(t, storage.Put(ctx, st))
+
+		stale, err := storage.Get(ctx, "chat_3")
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Put(ctx, st))
+
+		assert.Equal(t, state.ErrStateConflict, storage.Delete(ctx, stale))
+		require.NoError(t, storage.Delete(ctx, st))
+	})
+
+	t.Run("delete not exists state", func(t *testing.T) {
+		assert.Equal(t, state.ErrStateNotFound, storage.Delete(ctx, state.NewState("chat_4", "start")))
+	})
+}
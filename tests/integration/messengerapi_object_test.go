@@ -0,0 +1,52 @@
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+func TestMediaGroupValidate(t *testing.T) {
+	photo := &messengerapi.Photo{File: messengerapi.FileFromID("photo")}
+	video := &messengerapi.Video{File: messengerapi.FileFromID("video")}
+	document := &messengerapi.Document{File: messengerapi.FileFromID("document")}
+	audio := &messengerapi.Audio{File: messengerapi.FileFromID("audio")}
+
+	items := func(count int) []messengerapi.AlbumItem {
+		result := make([]messengerapi.AlbumItem, count)
+		for i := range result {
+			result[i] = photo
+		}
+
+		return result
+	}
+
+	cases := []struct {
+		name  string
+		items []messengerapi.AlbumItem
+		valid bool
+	}{
+		{name: "photos and videos mixed", items: []messengerapi.AlbumItem{photo, video}, valid: true},
+		{name: "documents", items: []messengerapi.AlbumItem{document, document}, valid: true},
+		{name: "audios", items: []messengerapi.AlbumItem{audio, audio}, valid: true},
+		{name: "max items", items: items(messengerapi.MaxMediaGroupItems), valid: true},
+		{name: "one item", items: items(1)},
+		{name: "too many items", items: items(messengerapi.MaxMediaGroupItems + 1)},
+		{name: "photo with document", items: []messengerapi.AlbumItem{photo, document}},
+		{name: "document with audio", items: []messengerapi.AlbumItem{document, audio}},
+		{name: "nil item", items: []messengerapi.AlbumItem{photo, nil}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&messengerapi.MediaGroup{Items: tc.items}).Validate()
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, messengerapi.ErrInvalidMediaGroup)
+			}
+		})
+	}
+}
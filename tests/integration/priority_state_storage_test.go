@@ -0,0 +1,91 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+)
+
+func TestPriorityStateStorage(t *testing.T) {
+	ctx := context.Background()
+
+	newStorage := func() (state.Storage, state.Storage, state.Storage) {
+		priority, fallback := state.NewMemoryStorage(), state.NewMemoryStorage()
+
+		return state.NewPriorityStorage([]string{"profile"}, priority, fallback), priority, fallback
+	}
+
+	t.Run("put: state, which replaces dialog of other storage, moved", func(t *testing.T) {
+		storage, priority, fallback := newStorage()
+
+		profile := state.NewState("chat_1", "profile")
+		require.NoError(t, storage.Put(ctx, profile))
+		require.NoError(t, storage.Put(ctx, profile))
+
+		help := state.NewState("chat_1", "help")
+		help.SetVersion(profile.Version())
+		require.NoError(t, storage.Put(ctx, help))
+		assert.Equal(t, int64(1), help.Version())
+
+		_, err := priority.Get(ctx, "chat_1")
+		assert.Equal(t, state.ErrStateNotFound, err)
+
+		stored, err := fallback.Get(ctx, "chat_1")
+		require.NoError(t, err)
+		assert.Equal(t, "help", stored.CommandName())
+
+		back := state.NewState("chat_1", "profile")
+		back.SetVersion(help.Version())
+		require.NoError(t, storage.Put(ctx, back))
+
+		stored, err = storage.Get(ctx, "chat_1")
+		require.NoError(t, err)
+		assert.Equal(t, "profile", stored.CommandName())
+	})
+
+	t.Run("put: stale state, which replaces dialog of other storage, rejected", func(t *testing.T) {
+		storage, priority, _ := newStorage()
+
+		profile := state.NewState("chat_2", "profile")
+		require.NoError(t, storage.Put(ctx, profile))
+
+		help := state.NewState("chat_2", "help")
+		help.SetVersion(profile.Version())
+
+		require.NoError(t, storage.Put(ctx, profile))
+
+		assert.Equal(t, state.ErrStateConflict, storage.Put(ctx, help))
+
+		stored, err := priority.Get(ctx, "chat_2")
+		require.NoError(t, err)
+		assert.Equal(t, "profile", stored.CommandName())
+	})
+
+	t.Run("machine: dialog interrupted by command of other storage", func(t *testing.T) {
+		storage, _, _ := newStorage()
+
+		lowbottest.New(t,
+			lowbottest.WithCommands(&profileCommand{}, &pickUserCommand{}),
+			lowbottest.WithStateStorage(storage),
+		).Conversation(t, "chat_3").
+			Send("/profile").
+			ExpectText("Enter name").
+			Send("bob").
+			ExpectText("Enter age").
+			Send("/pick_user").
+			ExpectText("Select user").
+			Send("alice").
+			ExpectText("Selected alice").
+			ExpectText("Command /profile is not finished.").
+			Press("Continue").
+			ExpectText("Command /profile resumed.").
+			Send("30").
+			ExpectText("Saved bob, 30").
+			ExpectNoError()
+	})
+}
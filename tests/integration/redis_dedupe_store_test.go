@@ -0,0 +1,50 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	redisstatestorage "github.com/artarts36/lowbot/pkg/redis-state-storage"
+)
+
+func TestRedisDedupeStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	store := redisstatestorage.NewDedupeStore(client, redisstatestorage.DedupeConfig{
+		KeyPrefix: "dedupe_test_",
+	})
+
+	t.Run("seen: second call is duplicate", func(t *testing.T) {
+		ctx := context.Background()
+		key := "c5e1a9d2-7f3b-4b8e-a0c6-2d4f6e8a1b3c:1"
+
+		seen, err := store.Seen(ctx, key, 10*time.Second)
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		seen, err = store.Seen(ctx, key, 10*time.Second)
+		require.NoError(t, err)
+		assert.True(t, seen)
+	})
+
+	t.Run("release: released key is not duplicate", func(t *testing.T) {
+		ctx := context.Background()
+		key := "8d2b4f6a-1c3e-4a5b-9d7f-0e2c4a6b8d1f:1"
+
+		seen, err := store.Seen(ctx, key, 10*time.Second)
+		require.NoError(t, err)
+		assert.False(t, seen)
+
+		require.NoError(t, store.Release(ctx, key))
+
+		seen, err = store.Seen(ctx, key, 10*time.Second)
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+}
@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/schedule"
+	redisstatestorage "github.com/artarts36/lowbot/pkg/redis-state-storage"
+)
+
+func TestRedisScheduleStore(t *testing.T) {
+	client := redis.NewClient(&redis.Options{
+		Addr: "localhost:6379",
+	})
+	store := redisstatestorage.NewScheduleStore(client, redisstatestorage.ScheduleConfig{
+		KeyPrefix: "schedule_test_",
+	})
+	now := time.Date(2025, time.September, 27, 23, 0, 0, 0, time.UTC)
+
+	t.Run("due: lease and redelivery until ack", func(t *testing.T) {
+		ctx := context.Background()
+		job := &schedule.Job{
+			ID:              "5b0f3c1e-8a2d-4e6f-9c7b-1d3e5f7a9b2c",
+			StateKey:        "chat_1",
+			ChatID:          "chat_1",
+			CommandName:     "remind",
+			DialogStartedAt: now,
+			StateName:       "notify",
+			Data:            map[string]string{"text": "hello"},
+			At:              now,
+		}
+
+		require.NoError(t, store.Add(ctx, job))
+
+		jobs, err := store.Due(ctx, now.Add(-time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		jobs, err = store.Due(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, job, jobs[0])
+
+		jobs, err = store.Due(ctx, now.Add(time.Second), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+
+		jobs, err = store.Due(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		require.NoError(t, store.Ack(ctx, job))
+
+		jobs, err = store.Due(ctx, now.Add(time.Hour), time.Minute, 10)
+		require.NoError(t, err)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("cancel: jobs of dialog state", func(t *testing.T) {
+		ctx := context.Background()
+
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "1", StateKey: "chat_2", At: now}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "2", StateKey: "chat_2", At: now}))
+		require.NoError(t, store.Add(ctx, &schedule.Job{ID: "3", StateKey: "chat_3", At: now}))
+
+		require.NoError(t, store.Cancel(ctx, "chat_2"))
+
+		jobs, err := store.Due(ctx, now, time.Minute, 10)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, "3", jobs[0].ID)
+
+		require.NoError(t, store.Ack(ctx, jobs[0]))
+	})
+}
@@ -14,9 +14,10 @@ import (
 )
 
 func TestRedisStateStorage(t *testing.T) {
-	storage := redisstatestorage.NewStorage(redis.NewClient(&redis.Options{
+	client := redis.NewClient(&redis.Options{
 		Addr: "localhost:6379",
-	}), redisstatestorage.Config{
+	})
+	storage := redisstatestorage.NewStorage(client, redisstatestorage.Config{
 		KeyPrefix: "chat_",
 		TTL:       10 * time.Second,
 	})
@@ -32,7 +33,7 @@ func TestRedisStateStorage(t *testing.T) {
 		ctx := context.Background()
 		currTime := time.Date(2025, time.September, 27, 23, 0, 0, 0, time.UTC)
 
-		stateObj := state.NewFullState(chatID, "start", "start", map[string]string{}, currTime)
+		stateObj := state.NewFullState(chatID, "start", "start", map[string]string{}, currTime, currTime, 0)
 
 		err := storage.Put(ctx, stateObj)
 		require.NoError(t, err)
@@ -43,6 +44,96 @@ func TestRedisStateStorage(t *testing.T) {
 		assert.Equal(t, stateObj, got)
 	})
 
+	t.Run("get: state saved before schema versioning", func(t *testing.T) {
+		ctx := context.Background()
+		legacyChatID := "0b6f7c3e-5d0a-4a3b-8c52-1f0e9d8a7b64"
+
+		err := client.Set(ctx, "chat_"+legacyChatID, `{"chat_id":"`+legacyChatID+`","name":"email",`+
+			`"command_name":"add","data":{"user.name":"bob"},"started_at":"2025-09-27T23:00:00Z"}`, 10*time.Second).Err()
+		require.NoError(t, err)
+
+		got, err := storage.Get(ctx, legacyChatID)
+		require.NoError(t, err)
+
+		assert.Equal(t, "email", got.Name())
+		assert.Equal(t, "bob", got.Get("user.name"))
+		assert.Equal(t, got.StartedAt(), got.UpdatedAt())
+		assert.Equal(t, int64(0), got.Version())
+	})
+
+	t.Run("put: conflict with stale version", func(t *testing.T) {
+		ctx := context.Background()
+		conflictChatID := "4d1c8a57-2b0e-4c8b-9f3e-6f1b5a9e2c11"
+
+		first := state.NewState(conflictChatID, "start")
+		second := state.NewState(conflictChatID, "start")
+
+		require.NoError(t, storage.Put(ctx, first))
+		assert.Equal(t, int64(1), first.Version())
+
+		err := storage.Put(ctx, second)
+		require.Error(t, err)
+		assert.Equal(t, state.ErrStateConflict, err)
+
+		require.NoError(t, storage.Put(ctx, first))
+		assert.Equal(t, int64(2), first.Version())
+	})
+
+	t.Run("put: stale state after delete rejected", func(t *testing.T) {
+		ctx := context.Background()
+		deletedChatID := "9a3e5c71-6b2d-4f08-a1c4-7e8d2b5f9c36"
+
+		st := state.NewState(deletedChatID, "add")
+		require.NoError(t, storage.Put(ctx, st))
+
+		slow, err := storage.Get(ctx, deletedChatID)
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Delete(ctx, st))
+		assert.Equal(t, state.ErrStateConflict, storage.Put(ctx, slow))
+	})
+
+	t.Run("delete: conflict with stale version", func(t *testing.T) {
+		ctx := context.Background()
+		staleChatID := "2f7b9d14-8c3a-4e61-b5d0-9a1c6e4f7b28"
+
+		st := state.NewState(staleChatID, "add")
+		require.NoError(t, storage.Put(ctx, st))
+
+		stale, err := storage.Get(ctx, staleChatID)
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Put(ctx, st))
+
+		assert.Equal(t, state.ErrStateConflict, storage.Delete(ctx, stale))
+		require.NoError(t, storage.Delete(ctx, st))
+	})
+
+	t.Run("put/get: independent states of chat members", func(t *testing.T) {
+		ctx := context.Background()
+		groupChatID := "-7e2b4f19-3c6d-4a8e-b1f0-5d9c2a7e8b43"
+
+		first := state.NewState(groupChatID, "add")
+		first.SetKey(state.KeyByChatSender.Key(groupChatID, "1", ""))
+		second := state.NewState(groupChatID, "delete")
+		second.SetKey(state.KeyByChatSender.Key(groupChatID, "2", ""))
+
+		require.NoError(t, storage.Put(ctx, first))
+		require.NoError(t, storage.Put(ctx, second))
+
+		got, err := storage.Get(ctx, first.Key())
+		require.NoError(t, err)
+		assert.Equal(t, "add", got.CommandName())
+		assert.Equal(t, first.Key(), got.Key())
+
+		got, err = storage.Get(ctx, second.Key())
+		require.NoError(t, err)
+		assert.Equal(t, "delete", got.CommandName())
+
+		_, err = storage.Get(ctx, groupChatID)
+		assert.Equal(t, state.ErrStateNotFound, err)
+	})
+
 	t.Run("delete not exists state", func(t *testing.T) {
 		err := storage.Delete(context.Background(), state.NewState("109c71b7-7be7-427f-aeae-5352093eff3e", "start"))
 		require.Error(t, err)
@@ -0,0 +1,276 @@
+package integration
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/artarts36/lowbot/messenger/slack"
+)
+
+const slackSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// slackAPI is local stand-in of Slack Web API, which records calls.
+type slackAPI struct {
+	mu    sync.Mutex
+	calls map[string][]map[string]any
+}
+
+func newSlackAPI(t *testing.T) (*slackAPI, *httptest.Server) {
+	api := &slackAPI{calls: make(map[string][]map[string]any)}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer xoxb-test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if req.URL.Path == "/download/F1" {
+			_, _ = w.Write([]byte("avatar"))
+			return
+		}
+
+		method := strings.TrimPrefix(req.URL.Path, "/api/")
+		params := make(map[string]any)
+
+		body, _ := io.ReadAll(req.Body)
+		if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			require.NoError(t, json.Unmarshal(body, &params))
+		} else {
+			form, _ := url.ParseQuery(string(body))
+			for k := range form {
+				params[k] = form.Get(k)
+			}
+		}
+
+		api.mu.Lock()
+		api.calls[method] = append(api.calls[method], params)
+		api.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch method {
+		case "chat.postMessage", "chat.update":
+			_, _ = w.Write([]byte(`{"ok":true,"channel":"C1","ts":"1700000000.000100"}`))
+		case "files.info":
+			_, _ = w.Write([]byte(`{"ok":true,"file":{"url_private_download":"` + server.URL + `/download/F1"}}`))
+		case "chat.delete":
+			_, _ = w.Write([]byte(`{"ok":false,"error":"message_not_found"}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return api, server
+}
+
+func (a *slackAPI) lastCall(method string) map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	calls := a.calls[method]
+	if len(calls) == 0 {
+		return nil
+	}
+
+	return calls[len(calls)-1]
+}
+
+func signedSlackRequest(body, contentType string, at time.Time) *http.Request {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(slackSigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/slack", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", timestamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+
+	return req
+}
+
+func interactionBody(t *testing.T, payload map[string]any) string {
+	encoded, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	return url.Values{"payload": {string(encoded)}}.Encode()
+}
+
+func TestSlackMessenger(t *testing.T) {
+	api, server := newSlackAPI(t)
+
+	msngr, err := slack.NewMessenger(slack.Config{
+		SigningSecret: slackSigningSecret,
+		BotToken:      "xoxb-test",
+		APIURL:        server.URL + "/api",
+	}, slog.Default())
+	require.NoError(t, err)
+
+	ch := make(chan messengerapi.Message, 1)
+	go func() {
+		_ = msngr.Listen(ch)
+	}()
+	t.Cleanup(func() {
+		_ = msngr.Close()
+	})
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		msngr.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Eventually(t, func() bool {
+		rec := serve(signedSlackRequest(`{"type":"event_callback","event":{"type":"message","user":"U1","channel":"C1","ts":"1.1"}}`,
+			"application/json", time.Now()))
+		if rec.Code != http.StatusOK {
+			return false
+		}
+		<-ch
+		return true
+	}, time.Second, 10*time.Millisecond, "messenger is not listening")
+
+	t.Run("signature: url verification", func(t *testing.T) {
+		rec := serve(signedSlackRequest(`{"type":"url_verification","challenge":"3eZbrw1a"}`, "application/json", time.Now()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "3eZbrw1a", rec.Body.String())
+	})
+
+	t.Run("signature: invalid and expired requests rejected", func(t *testing.T) {
+		req := signedSlackRequest(`{"type":"url_verification","challenge":"x"}`, "application/json", time.Now())
+		req.Header.Set("X-Slack-Signature", "v0=deadbeef")
+		assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+
+		req = signedSlackRequest(`{"type":"url_verification","challenge":"x"}`, "application/json", time.Now().Add(-10*time.Minute))
+		assert.Equal(t, http.StatusUnauthorized, serve(req).Code)
+	})
+
+	t.Run("event: mention with file", func(t *testing.T) {
+		rec := serve(signedSlackRequest(`{"type":"event_callback","event":{
+			"type":"app_mention","user":"U2","channel":"C2","text":"<@U0BOT> /add","ts":"1.2","thread_ts":"1.0",
+			"files":[{"id":"F1","name":"avatar.png","mimetype":"image/png","size":6}]
+		}}`, "application/json", time.Now()))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		msg := <-ch
+		assert.Equal(t, "1.2", msg.GetID())
+		assert.Equal(t, "C2", msg.GetChatID())
+		assert.Equal(t, "add", msg.ExtractCommandName())
+		assert.Equal(t, "U2", msg.GetSender().ID)
+		assert.Equal(t, "1.0", msg.(messengerapi.ThreadMessage).GetThreadID())
+
+		attachment := messengerapi.FindAttachment(msg, messengerapi.AttachmentPhoto)
+		require.NotNil(t, attachment)
+
+		file, err := attachment.Open(t.Context())
+		require.NoError(t, err)
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		require.NoError(t, err)
+		assert.Equal(t, "avatar", string(content))
+		assert.Equal(t, "F1", api.lastCall("files.info")["file"])
+	})
+
+	t.Run("event: bot messages skipped", func(t *testing.T) {
+		rec := serve(signedSlackRequest(`{"type":"event_callback","event":{"type":"message","bot_id":"B1","channel":"C1","text":"hi","ts":"1.3"}}`,
+			"application/json", time.Now()))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, ch)
+	})
+
+	t.Run("respond: answer rendered as blocks, buttons pressed", func(t *testing.T) {
+		responder := msngr.CreateResponder("C1")
+
+		sent, err := responder.Respond(&messengerapi.Answer{
+			Text: "Delete user?",
+			Enum: messengerapi.EnumFromList([]string{"yes"}),
+			Buttons: []messengerapi.Button{
+				&messengerapi.CommandButton{
+					Title: "Later",
+					Args: messengerapi.Args{
+						CommandName: "delete",
+						StateName:   "postponed",
+						Data:        map[string]string{"user.id": "42"},
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "1700000000.000100", sent.GetID())
+
+		call := api.lastCall("chat.postMessage")
+		require.NotNil(t, call)
+		assert.Equal(t, "C1", call["channel"])
+		assert.Equal(t, "Delete user?", call["text"])
+
+		blocks := call["blocks"].([]any)
+		require.Len(t, blocks, 2)
+		elements := blocks[1].(map[string]any)["elements"].([]any)
+		require.Len(t, elements, 2)
+
+		press := func(element any) messengerapi.Message {
+			button := element.(map[string]any)
+
+			rec := serve(signedSlackRequest(interactionBody(t, map[string]any{
+				"type":    "block_actions",
+				"user":    map[string]any{"id": "U1", "username": "bob"},
+				"channel": map[string]any{"id": "C1"},
+				"message": map[string]any{"text": "Delete user?", "ts": sent.GetID()},
+				"actions": []any{map[string]any{
+					"action_id": button["action_id"],
+					"value":     button["value"],
+					"action_ts": "1.4",
+				}},
+			}), "application/x-www-form-urlencoded", time.Now()))
+			require.Equal(t, http.StatusOK, rec.Code)
+
+			return <-ch
+		}
+
+		enumMsg := press(elements[0])
+		assert.Equal(t, "yes", enumMsg.GetBody())
+		assert.Nil(t, enumMsg.GetArgs())
+		assert.True(t, enumMsg.(messengerapi.CallbackMessage).IsCallback())
+
+		buttonMsg := press(elements[1])
+		assert.Equal(t, &messengerapi.Args{
+			CommandName: "delete",
+			StateName:   "postponed",
+			Data:        map[string]string{"user.id": "42"},
+		}, buttonMsg.GetArgs())
+		assert.Equal(t, "bob", buttonMsg.GetSender().Username)
+	})
+
+	t.Run("respond: edit and delete", func(t *testing.T) {
+		responder := msngr.CreateResponder("C1")
+
+		_, err := responder.Edit("1.5", &messengerapi.Answer{Text: "Deleted"})
+		require.NoError(t, err)
+		assert.Equal(t, "1.5", api.lastCall("chat.update")["ts"])
+
+		var apiErr *slack.APIError
+		require.ErrorAs(t, responder.Delete("1.5"), &apiErr)
+		assert.Equal(t, "message_not_found", apiErr.Code)
+	})
+}
@@ -0,0 +1,236 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/engine/state"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+type cartBag struct {
+	Items []string `json:"items"`
+}
+
+var cart = state.NewBag[cartBag]("cart")
+
+type cartCommand struct {
+	command.AlwaysInterruptCommand
+}
+
+func (cartCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "cart",
+		Description: "Fill cart",
+	}
+}
+
+func (cartCommand) Actions() *command.Actions {
+	add := func(next string) command.ActionCallback {
+		return func(_ context.Context, req *command.Request) error {
+			if body := req.Message.GetBody(); body != "/cart" {
+				err := cart.Update(req.State, func(value *cartBag) {
+					value.Items = append(value.Items, body)
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			steps, err := req.State.GetInt("steps")
+			if err != nil && !errors.Is(err, state.ErrDataKeyNotFound) {
+				return err
+			}
+
+			req.State.SetInt("steps", steps+1)
+
+			return req.Respond(&messengerapi.Answer{Text: next})
+		}
+	}
+
+	return command.NewActions().
+		Then("first", add("Add item")).
+		Then("second", add("Add one more item")).
+		Then("checkout", func(_ context.Context, req *command.Request) error {
+			if err := cart.Update(req.State, func(value *cartBag) {
+				value.Items = append(value.Items, req.Message.GetBody())
+			}); err != nil {
+				return err
+			}
+
+			value, err := cart.Get(req.State)
+			if err != nil {
+				return err
+			}
+
+			steps, err := req.State.GetInt("steps")
+			if err != nil {
+				return err
+			}
+
+			return req.Respond(&messengerapi.Answer{Text: fmt.Sprintf("Cart: %v, steps: %d", value.Items, steps)})
+		})
+}
+
+func TestStateData(t *testing.T) {
+	t.Run("int: stored as string", func(t *testing.T) {
+		st := state.NewState("chat", "cmd")
+		st.SetInt("count", 42)
+
+		value, err := st.GetInt("count")
+		require.NoError(t, err)
+		assert.Equal(t, 42, value)
+		assert.Equal(t, "42", st.Get("count"))
+	})
+
+	t.Run("int: invalid value", func(t *testing.T) {
+		st := state.NewState("chat", "cmd")
+		st.Set("count", "many")
+
+		_, err := st.GetInt("count")
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, state.ErrDataKeyNotFound)
+	})
+
+	t.Run("time: keeps nanoseconds and zone", func(t *testing.T) {
+		at := time.Date(2025, 3, 4, 5, 6, 7, 89, time.FixedZone("UTC+3", 3*60*60))
+
+		st := state.NewState("chat", "cmd")
+		st.SetTime("at", at)
+
+		value, err := st.GetTime("at")
+		require.NoError(t, err)
+		assert.True(t, at.Equal(value))
+		assert.Equal(t, at.Format(time.RFC3339Nano), value.Format(time.RFC3339Nano))
+	})
+
+	t.Run("json: decoded to type", func(t *testing.T) {
+		st := state.NewState("chat", "cmd")
+		require.NoError(t, st.SetJSON("cart", cartBag{Items: []string{"apple"}}))
+
+		value, err := state.GetJSON[cartBag](st, "cart")
+		require.NoError(t, err)
+		assert.Equal(t, cartBag{Items: []string{"apple"}}, value)
+
+		_, err = state.GetJSON[int](st, "cart")
+		require.Error(t, err)
+	})
+
+	t.Run("missing key: ErrDataKeyNotFound", func(t *testing.T) {
+		st := state.NewState("chat", "cmd")
+
+		_, err := st.GetInt("missing")
+		require.ErrorIs(t, err, state.ErrDataKeyNotFound)
+
+		_, err = st.GetTime("missing")
+		require.ErrorIs(t, err, state.ErrDataKeyNotFound)
+
+		_, err = state.GetJSON[cartBag](st, "missing")
+		require.ErrorIs(t, err, state.ErrDataKeyNotFound)
+	})
+
+	t.Run("bag: zero value when not stored, invalid value returned as error", func(t *testing.T) {
+		st := state.NewState("chat", "cmd")
+
+		value, err := cart.Get(st)
+		require.NoError(t, err)
+		assert.Equal(t, cartBag{}, value)
+
+		st.Set("cart", "not json")
+
+		_, err = cart.Get(st)
+		require.Error(t, err)
+		require.Error(t, cart.Update(st, func(*cartBag) {}))
+	})
+
+	t.Run("bag: kept between steps of dialog", func(t *testing.T) {
+		bot := lowbottest.New(t, lowbottest.WithCommands(&cartCommand{}))
+
+		bot.Conversation(t, "chat_1").
+			Send("/cart").
+			ExpectText("Add item").
+			Send("apple").
+			ExpectText("Add one more item").
+			Send("pear").
+			ExpectText("Cart: [apple pear], steps: 2").
+			ExpectNoError()
+	})
+}
+
+func TestStateEncoding(t *testing.T) {
+	t.Run("encode: dialog stack and data decoded", func(t *testing.T) {
+		startedAt := time.Date(2025, 9, 27, 23, 0, 0, 0, time.UTC)
+
+		parent := state.NewFullState("chat_1", "ask_name", "rename", map[string]string{"user": "bob"}, startedAt, startedAt, 3)
+		child := state.NewChildState(parent, "pick_user")
+		child.SetKey("chat_1:user_1")
+		child.SetThreadID("5")
+		require.NoError(t, child.SetJSON("cart", cartBag{Items: []string{"apple"}}))
+		child.Suspend(state.NewFullState("chat_1", "ask_age", "profile", nil, startedAt, startedAt, 1), "offer")
+
+		raw, err := state.Encode(child)
+		require.NoError(t, err)
+
+		got, err := state.Decode(raw)
+		require.NoError(t, err)
+
+		assert.Equal(t, "chat_1:user_1", got.Key())
+		assert.Equal(t, "5", got.ThreadID())
+		assert.Equal(t, "pick_user", got.CommandName())
+		assert.Equal(t, int64(3), got.Version())
+
+		bag, err := cart.Get(got)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apple"}, bag.Items)
+
+		require.NotNil(t, got.Parent())
+		assert.Equal(t, "bob", got.Parent().Get("user"))
+		assert.Equal(t, "ask_name", got.Parent().Name())
+
+		require.NotNil(t, got.Suspended())
+		assert.Equal(t, "profile", got.Suspended().CommandName())
+		assert.Equal(t, "offer", got.ResumeMode())
+	})
+
+	t.Run("decode: state encoded before versioning", func(t *testing.T) {
+		got, err := state.Decode([]byte(`{"chat_id":"chat_2","name":"email",` +
+			`"command_name":"add","data":{"user.name":"bob"},"started_at":"2025-09-27T23:00:00Z"}`))
+		require.NoError(t, err)
+
+		assert.Equal(t, "chat_2", got.Key())
+		assert.Equal(t, "email", got.Name())
+		assert.Equal(t, "bob", got.Get("user.name"))
+		assert.Equal(t, got.StartedAt(), got.UpdatedAt())
+		assert.Equal(t, int64(0), got.Version())
+	})
+
+	t.Run("decode: newer schema rejected", func(t *testing.T) {
+		_, err := state.Decode([]byte(`{"schema":2,"chat_id":"chat_3","name":"email","command_name":"add"}`))
+		require.ErrorIs(t, err, state.ErrUnsupportedSchema)
+	})
+
+	t.Run("memory storage: stored state is not shared with caller", func(t *testing.T) {
+		storage := state.NewMemoryStorage()
+
+		st := state.NewState("chat_4", "cart")
+		st.SetInt("steps", 1)
+		require.NoError(t, storage.Put(context.Background(), st))
+
+		st.SetInt("steps", 2)
+
+		got, err := storage.Get(context.Background(), "chat_4")
+		require.NoError(t, err)
+
+		steps, err := got.GetInt("steps")
+		require.NoError(t, err)
+		assert.Equal(t, 1, steps)
+	})
+}
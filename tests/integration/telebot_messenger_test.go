@@ -0,0 +1,493 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	tele "gopkg.in/telebot.v4"
+
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+	"github.com/artarts36/lowbot/messenger/tg-telebot/telebot"
+)
+
+const telegramToken = "123:telegram-test"
+
+// telegramAPI is local stand-in of Telegram Bot API, which records calls.
+type telegramAPI struct {
+	mu    sync.Mutex
+	calls map[string][]map[string]any
+	// floods is count of next sendMessage calls, which fail with flood control error.
+	floods     int
+	retryAfter int
+}
+
+func newTelegramAPI(t *testing.T) (*telegramAPI, *httptest.Server) {
+	api := &telegramAPI{calls: make(map[string][]map[string]any)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method, ok := strings.CutPrefix(req.URL.Path, "/bot"+telegramToken+"/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		params := make(map[string]any)
+
+		body, _ := io.ReadAll(req.Body)
+		if len(body) > 0 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+			require.NoError(t, json.Unmarshal(body, &params))
+		}
+
+		api.mu.Lock()
+		api.calls[method] = append(api.calls[method], params)
+		flood := method == "sendMessage" && api.floods > 0
+		if flood {
+			api.floods--
+		}
+		retryAfter := api.retryAfter
+		api.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if flood {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = fmt.Fprintf(w,
+				`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d","parameters":{"retry_after":%d}}`,
+				retryAfter, retryAfter,
+			)
+
+			return
+		}
+
+		switch method {
+		case "getMe":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot","username":"bot"}}`))
+		case "sendMediaGroup":
+			_, _ = w.Write([]byte(`{"ok":true,"result":[` +
+				`{"message_id":11,"date":1700000000,"from":{"id":1,"is_bot":true,"first_name":"bot"},"chat":{"id":1,"type":"private"}},` +
+				`{"message_id":12,"date":1700000000,"from":{"id":1,"is_bot":true,"first_name":"bot"},"chat":{"id":1,"type":"private"}}]}`))
+		case "getUpdates":
+			_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+		case "sendMessage", "sendLocation", "editMessageText", "editMessageReplyMarkup":
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":10,"date":1700000000,"from":{"id":1,"is_bot":true,"first_name":"bot"},"chat":{"id":1,"type":"private"}}}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return api, server
+}
+
+// Flood fails next {count} sendMessage calls with flood control error, which asks to retry after {retryAfter} seconds.
+func (a *telegramAPI) Flood(count, retryAfter int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.floods = count
+	a.retryAfter = retryAfter
+}
+
+func (a *telegramAPI) Calls(method string) []map[string]any {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.calls[method]
+}
+
+func newTelebotWebhookMessenger(t *testing.T, cfg telebot.WebhookConfig) (*telebot.WebhookMessenger, *telegramAPI) {
+	api, server := newTelegramAPI(t)
+
+	cfg.Token = telegramToken
+	cfg.APIURL = server.URL
+
+	msngr, err := telebot.NewWebhookMessenger(cfg, slog.Default())
+	require.NoError(t, err)
+
+	return msngr, api
+}
+
+func TestTelebotWebhookMessenger(t *testing.T) {
+	listen := func(msngr messengerapi.Messenger) chan error {
+		done := make(chan error, 1)
+		go func() {
+			done <- msngr.Listen(make(chan messengerapi.Message))
+		}()
+
+		return done
+	}
+
+	waitListen := func(t *testing.T, done chan error) {
+		t.Helper()
+
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("listen is not stopped")
+		}
+	}
+
+	t.Run("close: stops listening and can be called twice", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		done := listen(msngr)
+
+		require.NoError(t, msngr.Close())
+		require.NoError(t, msngr.Close())
+
+		waitListen(t, done)
+		assert.Len(t, api.Calls("close"), 1)
+	})
+
+	t.Run("close: before listen stops listening", func(t *testing.T) {
+		msngr, _ := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		require.NoError(t, msngr.Close())
+
+		waitListen(t, listen(msngr))
+	})
+
+	t.Run("listen: registers webhook", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			WebhookURL: "https://bot.example.com/webhook",
+		})
+
+		done := listen(msngr)
+
+		require.Eventually(t, func() bool {
+			return len(api.Calls("setWebhook")) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, msngr.Close())
+		waitListen(t, done)
+	})
+
+	t.Run("responder: thread responder sends to topic", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		_, err := msngr.CreateThreadResponder("1", "5").Respond(&messengerapi.Answer{Text: "In topic"})
+		require.NoError(t, err)
+
+		_, err = msngr.CreateResponder("1").Respond(&messengerapi.Answer{Text: "In chat"})
+		require.NoError(t, err)
+
+		calls := api.Calls("sendMessage")
+		require.Len(t, calls, 2)
+		assert.Equal(t, "5", calls[0]["message_thread_id"])
+		assert.NotContains(t, calls[1], "message_thread_id")
+	})
+}
+
+func TestTelebotPollingMessenger(t *testing.T) {
+	t.Run("close: stops polling, closes bot once", func(t *testing.T) {
+		api, server := newTelegramAPI(t)
+
+		msngr, err := telebot.NewPollingMessenger(telebot.PollingConfig{
+			Token:   telegramToken,
+			APIURL:  server.URL,
+			Timeout: 10 * time.Millisecond,
+		}, slog.Default())
+		require.NoError(t, err)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- msngr.Listen(make(chan messengerapi.Message))
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(api.Calls("getUpdates")) > 0
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, msngr.Close())
+		require.NoError(t, msngr.Close())
+
+		select {
+		case err = <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("listen is not stopped")
+		}
+
+		assert.Len(t, api.Calls("close"), 1)
+	})
+}
+
+func TestTelebotResponder(t *testing.T) {
+	t.Run("edit: text and buttons of message replaced", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		msg, err := msngr.CreateResponder("1").Edit("10", &messengerapi.Answer{
+			Text: "Edited",
+			Buttons: []messengerapi.Button{
+				&messengerapi.CommandButton{Title: "Retry", Args: messengerapi.Args{CommandName: "retry"}},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "10", msg.GetID())
+
+		calls := api.Calls("editMessageText")
+		require.Len(t, calls, 1)
+		assert.Equal(t, "1", calls[0]["chat_id"])
+		assert.Equal(t, "10", calls[0]["message_id"])
+		assert.Equal(t, "Edited", calls[0]["text"])
+		assert.Contains(t, calls[0]["reply_markup"], "Retry")
+	})
+
+	t.Run("delete: message deleted", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		require.NoError(t, msngr.CreateResponder("1").Delete("10"))
+
+		calls := api.Calls("deleteMessage")
+		require.Len(t, calls, 1)
+		assert.Equal(t, "1", calls[0]["chat_id"])
+		assert.Equal(t, "10", calls[0]["message_id"])
+	})
+
+	t.Run("edit buttons: keyboard replaced and removed", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{ChatRate: 100},
+		})
+		responder := msngr.CreateResponder("1")
+
+		require.NoError(t, responder.EditButtons("10", []messengerapi.Button{
+			&messengerapi.CommandButton{Title: "Retry", Args: messengerapi.Args{CommandName: "retry"}},
+		}))
+		require.NoError(t, responder.EditButtons("10", nil))
+
+		calls := api.Calls("editMessageReplyMarkup")
+		require.Len(t, calls, 2)
+		assert.Equal(t, "10", calls[0]["message_id"])
+		assert.Contains(t, calls[0]["reply_markup"], "Retry")
+		assert.NotContains(t, fmt.Sprint(calls[1]["reply_markup"]), "Retry")
+	})
+
+	t.Run("edit: invalid chat id rejected", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		_, err := msngr.CreateResponder("chat").Edit("10", &messengerapi.Answer{Text: "Edited"})
+		require.Error(t, err)
+		assert.Empty(t, api.Calls("editMessageText"))
+	})
+}
+
+func TestTelebotResponderObjects(t *testing.T) {
+	t.Run("album: sent as media group", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		msg, err := msngr.CreateResponder("1").RespondObject(&messengerapi.MediaGroup{
+			Items: []messengerapi.AlbumItem{
+				&messengerapi.Photo{File: messengerapi.FileFromURL("https://example.com/1.jpg"), Caption: "Trip"},
+				&messengerapi.Video{File: messengerapi.FileFromID("video-1")},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "11", msg.GetID())
+
+		calls := api.Calls("sendMediaGroup")
+		require.Len(t, calls, 1)
+		assert.Contains(t, calls[0]["media"], "https://example.com/1.jpg")
+		assert.Contains(t, calls[0]["media"], "video-1")
+	})
+
+	t.Run("album: invalid media group rejected", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		_, err := msngr.CreateResponder("1").RespondObject(&messengerapi.MediaGroup{
+			Items: []messengerapi.AlbumItem{
+				&messengerapi.Photo{File: messengerapi.FileFromID("photo-1")},
+				&messengerapi.Document{File: messengerapi.FileFromID("document-1")},
+			},
+		})
+		require.ErrorIs(t, err, messengerapi.ErrInvalidMediaGroup)
+		assert.Empty(t, api.Calls("sendMediaGroup"))
+	})
+
+	t.Run("location: sent", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		_, err := msngr.CreateResponder("1").RespondObject(&messengerapi.Location{Latitude: 55.5, Longitude: 37.25})
+		require.NoError(t, err)
+
+		calls := api.Calls("sendLocation")
+		require.Len(t, calls, 1)
+		assert.Contains(t, calls[0]["latitude"], "55.5")
+		assert.Contains(t, calls[0]["longitude"], "37.25")
+	})
+}
+
+func TestTelebotSendQueue(t *testing.T) {
+	answer := &messengerapi.Answer{Text: "Hello"}
+
+	t.Run("chat rate: messages to chat delayed", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{ChatRate: 20},
+		})
+
+		startedAt := time.Now()
+
+		for range 2 {
+			_, err := msngr.CreateResponder("1").Respond(answer)
+			require.NoError(t, err)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(startedAt), 45*time.Millisecond)
+		assert.Len(t, api.Calls("sendMessage"), 2)
+	})
+
+	t.Run("chat burst: messages to chat sent without waiting", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{ChatRate: 1, ChatBurst: 3},
+		})
+
+		startedAt := time.Now()
+
+		for range 3 {
+			_, err := msngr.CreateResponder("1").Respond(answer)
+			require.NoError(t, err)
+		}
+
+		assert.Less(t, time.Since(startedAt), 500*time.Millisecond)
+		assert.Len(t, api.Calls("sendMessage"), 3)
+	})
+
+	t.Run("global rate: messages to different chats delayed", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{GlobalRate: 10},
+		})
+
+		startedAt := time.Now()
+
+		for chatID := range 11 {
+			_, err := msngr.CreateResponder(fmt.Sprint(chatID + 1)).Respond(answer)
+			require.NoError(t, err)
+		}
+
+		assert.GreaterOrEqual(t, time.Since(startedAt), 95*time.Millisecond)
+		assert.Len(t, api.Calls("sendMessage"), 11)
+	})
+
+	t.Run("retry: message sent after flood control error", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{ChatRate: 100},
+		})
+
+		api.Flood(2, 0)
+
+		_, err := msngr.CreateResponder("1").Respond(answer)
+		require.NoError(t, err)
+		assert.Len(t, api.Calls("sendMessage"), 3)
+	})
+
+	t.Run("retry: flood control error returned after max retries", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{
+			SendQueue: telebot.SendQueueConfig{ChatRate: 100, MaxRetries: 1},
+		})
+
+		api.Flood(3, 0)
+
+		_, err := msngr.CreateResponder("1").Respond(answer)
+
+		var floodErr tele.FloodError
+		require.ErrorAs(t, err, &floodErr)
+		assert.Len(t, api.Calls("sendMessage"), 2)
+	})
+
+	t.Run("close: waiting send stopped", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		api.Flood(1, 60)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := msngr.CreateResponder("1").Respond(answer)
+			done <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(api.Calls("sendMessage")) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		require.NoError(t, msngr.Close())
+
+		select {
+		case err := <-done:
+			require.ErrorIs(t, err, telebot.ErrSendQueueStopped)
+		case <-time.After(time.Second):
+			t.Fatal("send is not stopped")
+		}
+	})
+
+	t.Run("context: waiting send of application stopped, when ctx done", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		app, err := webhookapp.New(msngr, webhookapp.WithoutWebhook(), webhookapp.WithPrometheus(prometheus.NewRegistry()))
+		require.NoError(t, err)
+
+		api.Flood(1, 60)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- app.Send(ctx, "1", answer)
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(api.Calls("sendMessage")) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+
+		select {
+		case err = <-done:
+			require.ErrorIs(t, err, context.Canceled)
+		case <-time.After(time.Second):
+			t.Fatal("send is not stopped")
+		}
+	})
+
+	t.Run("shutdown: waiting send stopped, when ctx done", func(t *testing.T) {
+		msngr, api := newTelebotWebhookMessenger(t, telebot.WebhookConfig{})
+
+		api.Flood(1, 60)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := msngr.CreateResponder("1").Respond(answer)
+			done <- err
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(api.Calls("sendMessage")) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, msngr.Shutdown(ctx))
+
+		select {
+		case err := <-done:
+			require.ErrorIs(t, err, telebot.ErrSendQueueStopped)
+		case <-time.After(time.Second):
+			t.Fatal("send is not stopped")
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+var errPingUnavailable = errors.New("ping unavailable")
+
+// pingCommand fails first run.
+type pingCommand struct {
+	command.AlwaysInterruptCommand
+
+	runs atomic.Int32
+}
+
+func (*pingCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "ping",
+		Description: "Ping bot",
+	}
+}
+
+func (c *pingCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("pong", func(_ context.Context, req *command.Request) error {
+			if c.runs.Add(1) == 1 {
+				return errPingUnavailable
+			}
+
+			return req.Respond(&messengerapi.Answer{Text: "Pong"})
+		})
+}
+
+func TestWebhookAppDedupe(t *testing.T) {
+	msngr := lowbottest.NewMessenger()
+	ping := &pingCommand{}
+
+	app, err := webhookapp.New(msngr,
+		webhookapp.WithoutWebhook(),
+		webhookapp.WithPrometheus(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+	require.NoError(t, app.AddCommand(ping))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, app.Close())
+		require.NoError(t, <-done)
+	})
+
+	push := func(id string) {
+		require.Eventually(t, func() bool {
+			return msngr.Push(&lowbottest.Message{ID: id, ChatID: "1", Text: "/ping"}) == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	push("1")
+	require.Eventually(t, func() bool {
+		return ping.runs.Load() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// redelivered message, which handling failed, is handled again.
+	push("1")
+	require.Eventually(t, func() bool {
+		return len(msngr.Replies("1")) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// handled message is skipped.
+	push("1")
+	push("2")
+	require.Eventually(t, func() bool {
+		return ping.runs.Load() == 3
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Len(t, msngr.Replies("1"), 2)
+}
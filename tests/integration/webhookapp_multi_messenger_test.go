@@ -0,0 +1,93 @@
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+func TestWebhookAppMultiMessenger(t *testing.T) {
+	staff := lowbottest.NewMessenger()
+	clients := lowbottest.NewMessenger()
+
+	app, err := webhookapp.NewMulti([]webhookapp.NamedMessenger{
+		{Name: "staff", Path: "/staff", Messenger: staff},
+		{Name: "clients", Path: "/clients", Messenger: clients},
+	},
+		webhookapp.WithoutWebhook(),
+		webhookapp.WithPrometheus(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+	require.NoError(t, app.AddCommand(&conversationAddCommand{}))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, app.Close())
+		require.NoError(t, <-done)
+	})
+
+	push := func(msngr *lowbottest.Messenger, text string) {
+		require.Eventually(t, func() bool {
+			return msngr.Push(&lowbottest.Message{ChatID: "1", Text: text}) == nil
+		}, time.Second, 10*time.Millisecond)
+	}
+
+	lastText := func(msngr *lowbottest.Messenger) func() string {
+		return func() string {
+			replies := msngr.Replies("1")
+			if len(replies) == 0 || replies[len(replies)-1].Answer == nil {
+				return ""
+			}
+
+			return replies[len(replies)-1].Answer.Text
+		}
+	}
+
+	t.Run("same chat id in different messengers has independent dialogs", func(t *testing.T) {
+		push(staff, "/add")
+		require.Eventually(t, func() bool {
+			return lastText(staff)() == "Enter user name"
+		}, time.Second, 10*time.Millisecond)
+
+		push(clients, "bob")
+		require.Eventually(t, func() bool {
+			return len(clients.Replies("1")) == 1
+		}, time.Second, 10*time.Millisecond)
+		assert.NotEqual(t, "Select user type", lastText(clients)())
+
+		push(staff, "alice")
+		require.Eventually(t, func() bool {
+			return lastText(staff)() == "Select user type"
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("send routes qualified chat id to messenger", func(t *testing.T) {
+		chatID := app.ChatID("clients", "1")
+		assert.Equal(t, "clients:1", chatID)
+
+		require.NoError(t, app.Send(context.Background(), chatID, &messengerapi.Answer{Text: "Hello"}))
+		assert.Equal(t, "Hello", lastText(clients)())
+
+		err := app.Send(context.Background(), "unknown:1", &messengerapi.Answer{Text: "Hello"})
+		require.ErrorIs(t, err, webhookapp.ErrUnknownMessenger)
+	})
+
+	t.Run("invalid messengers rejected", func(t *testing.T) {
+		_, err := webhookapp.NewMulti([]webhookapp.NamedMessenger{
+			{Name: "staff", Path: "/a", Messenger: staff},
+			{Name: "staff", Path: "/b", Messenger: clients},
+		}, webhookapp.WithPrometheus(prometheus.NewRegistry()))
+		require.Error(t, err)
+	})
+}
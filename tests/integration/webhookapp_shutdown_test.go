@@ -0,0 +1,109 @@
+package integration
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/artarts36/lowbot/engine/command"
+	"github.com/artarts36/lowbot/entrypoint/webhookapp"
+	"github.com/artarts36/lowbot/lowbottest"
+	"github.com/artarts36/lowbot/messenger/messengerapi"
+)
+
+// closeRecordingMessenger records, whether messenger is closed.
+type closeRecordingMessenger struct {
+	*lowbottest.Messenger
+
+	closed atomic.Bool
+}
+
+func (m *closeRecordingMessenger) Close() error {
+	m.closed.Store(true)
+
+	return m.Messenger.Close()
+}
+
+// slowCommand responds after release, reporting whether messenger was closed before response.
+type slowCommand struct {
+	command.AlwaysInterruptCommand
+
+	messenger *closeRecordingMessenger
+	started   chan struct{}
+	release   chan struct{}
+	// closedBeforeRespond is true, when action responded through closed messenger.
+	closedBeforeRespond atomic.Bool
+}
+
+func (*slowCommand) Definition() *command.Definition {
+	return &command.Definition{
+		Name:        "slow",
+		Description: "Respond slowly",
+	}
+}
+
+func (c *slowCommand) Actions() *command.Actions {
+	return command.NewActions().
+		Then("respond", func(_ context.Context, req *command.Request) error {
+			close(c.started)
+			<-c.release
+
+			c.closedBeforeRespond.Store(c.messenger.closed.Load())
+
+			return req.Respond(&messengerapi.Answer{Text: "Done"})
+		})
+}
+
+func TestWebhookAppShutdown(t *testing.T) {
+	msngr := &closeRecordingMessenger{Messenger: lowbottest.NewMessenger()}
+	slow := &slowCommand{
+		messenger: msngr,
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+
+	app, err := webhookapp.New(msngr,
+		webhookapp.WithoutWebhook(),
+		webhookapp.WithPrometheus(prometheus.NewRegistry()),
+	)
+	require.NoError(t, err)
+	require.NoError(t, app.AddCommand(slow))
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Run()
+	}()
+
+	require.Eventually(t, func() bool {
+		return msngr.Push(&lowbottest.Message{ID: "1", ChatID: "1", Text: "/slow"}) == nil
+	}, time.Second, 10*time.Millisecond)
+	<-slow.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- app.Shutdown(ctx)
+	}()
+
+	// messenger is open, while message in handling.
+	assert.Never(t, msngr.closed.Load, 100*time.Millisecond, 10*time.Millisecond)
+
+	close(slow.release)
+
+	require.NoError(t, <-shutdown)
+	require.NoError(t, <-done)
+
+	assert.False(t, slow.closedBeforeRespond.Load())
+	assert.True(t, msngr.closed.Load())
+
+	replies := msngr.Replies("1")
+	require.Len(t, replies, 1)
+	assert.Equal(t, "Done", replies[0].Answer.Text)
+}